@@ -0,0 +1,134 @@
+// mailpreview renders the email templates with sample data for designers.
+//
+//	mailpreview                                      lists templates and locales
+//	mailpreview -template password-reset -locale ru  prints the HTML part
+//	mailpreview -template new-sign-in -format eml    prints the whole message
+//	mailpreview -serve :8025                         serves every template and locale
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ibgl/microservice-users/internal/app/email"
+)
+
+func main() {
+	dir := flag.String("dir", "", "templates directory, the shipped templates when empty")
+	name := flag.String("template", "", "template name")
+	locale := flag.String("locale", email.DefaultLocale, "locale")
+	format := flag.String("format", "html", "html, text or eml")
+	dataFile := flag.String("data", "", "JSON file with the template data, the shipped sample when empty")
+	serve := flag.String("serve", "", "address to serve previews on, e.g. :8025")
+	flag.Parse()
+
+	var templates fs.FS = email.DefaultTemplates()
+	if *dir != "" {
+		templates = os.DirFS(*dir)
+	}
+
+	renderer, err := email.NewRenderer(templates, email.DefaultLocale)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *serve != "" {
+		log.Printf("serving previews on %s", *serve)
+		log.Fatal(http.ListenAndServe(*serve, previewHandler(renderer)))
+	}
+
+	if *name == "" {
+		fmt.Printf("templates: %s\nlocales: %s\n", strings.Join(renderer.Names(), ", "), strings.Join(renderer.Locales(), ", "))
+		return
+	}
+
+	data, err := templateData(*name, *dataFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	msg, err := renderer.Render(*name, *locale, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch *format {
+	case "html":
+		fmt.Print(msg.HTML)
+	case "text":
+		fmt.Printf("Subject: %s\n\n%s", msg.Subject, msg.Text)
+	case "eml":
+		msg.From = "Preview <preview@example.com>"
+		msg.To = "recipient@example.com"
+
+		content, err := msg.Bytes()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(content)
+	default:
+		log.Fatalf("unknown format %s", *format)
+	}
+}
+
+func templateData(name, file string) (map[string]interface{}, error) {
+	if file == "" {
+		return email.Sample(name)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	return data, json.Unmarshal(content, &data)
+}
+
+// previewHandler lists the templates at / and renders /{locale}/{name} with the sample data,
+// ?format=text shows the text part
+func previewHandler(renderer *email.Renderer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 2 {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<ul>")
+			for _, name := range renderer.Names() {
+				for _, locale := range renderer.Locales() {
+					fmt.Fprintf(w, `<li><a href="/%[1]s/%[2]s">%[2]s (%[1]s)</a> <a href="/%[1]s/%[2]s?format=text">text</a></li>`,
+						html.EscapeString(locale), html.EscapeString(name))
+				}
+			}
+			fmt.Fprint(w, "</ul>")
+			return
+		}
+
+		data, err := email.Sample(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		msg, err := renderer.Render(parts[1], parts[0], data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		if r.URL.Query().Get("format") == "text" || msg.HTML == "" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprintf(w, "Subject: %s\n\n%s", msg.Subject, msg.Text)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, msg.HTML)
+	})
+}
@@ -5,9 +5,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/ibgl/microservice-users/internal/adapters"
 	"github.com/ibgl/microservice-users/internal/app"
+	"github.com/ibgl/microservice-users/internal/app/email"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/password"
+	"github.com/ibgl/microservice-users/internal/app/webhook"
 	"github.com/ibgl/microservice-users/internal/ports"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -16,8 +25,47 @@ import (
 )
 
 func main() {
-	ctx := context.Background()
+	// cancelled on SIGINT and SIGTERM, the servers and the background workers stop with it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	viper.AutomaticEnv()
+	viper.SetDefault("PASSWORD_MIN_LENGTH", password.DefaultPolicy.MinLength)
+	viper.SetDefault("PASSWORD_MIN_CLASSES", password.DefaultPolicy.MinClasses)
+	viper.SetDefault("PASSWORD_MIN_SCORE", password.DefaultPolicy.MinScore)
+	viper.SetDefault("BREACHED_PASSWORDS_MIN_COUNT", 1)
+	viper.SetDefault("PASSWORD_HASHER", password.AlgorithmArgon2id)
+	viper.SetDefault("BCRYPT_COST", password.DefaultBcryptCost)
+	viper.SetDefault("ARGON2_MEMORY", password.DefaultArgon2Params.Memory)
+	viper.SetDefault("ARGON2_ITERATIONS", password.DefaultArgon2Params.Iterations)
+	viper.SetDefault("ARGON2_PARALLELISM", password.DefaultArgon2Params.Parallelism)
+	viper.SetDefault("HASHING_WORKERS", runtime.NumCPU())
+	viper.SetDefault("HASHING_QUEUE", 100)
+	viper.SetDefault("HASHING_MAX_WAIT", 2000)
+	viper.SetDefault("CAPTCHA_SIGN_IN_AFTER", 3)
+	viper.SetDefault("SESSION_COOKIE_PATH", "/users/api/v1/refresh")
+	viper.SetDefault("SESSION_COOKIE_SAME_SITE", "strict")
+	viper.SetDefault("CORS_ALLOWED_METHODS", strings.Join(ports.DefaultCorsMethods, ","))
+	viper.SetDefault("CORS_ALLOWED_HEADERS", strings.Join(ports.DefaultCorsHeaders, ","))
+	viper.SetDefault("CORS_MAX_AGE", ports.DefaultCorsMaxAge)
+	viper.SetDefault("MAIL_DEFAULT_LOCALE", email.DefaultLocale)
+	viper.SetDefault("MAIL_TRANSPORT", app.MailTransportSMTP)
+	viper.SetDefault("MAIL_FILE_DIR", "logs/mail")
+	viper.SetDefault("MAIL_SINK_ADDR", "127.0.0.1:2525")
+	viper.SetDefault("MAIL_SMTP_POOL_SIZE", email.DefaultSMTPPoolSize)
+	viper.SetDefault("MAIL_SENDMAIL_PATH", email.DefaultSendmailPath)
+	viper.SetDefault("MAIL_HTTP_AUTH_HEADER", email.DefaultHTTPAuthHeader)
+	viper.SetDefault("EVENTS_PUBLISHER", app.EventsPublisherNone)
+	viper.SetDefault("EVENTS_RELAY_INTERVAL", event.DefaultRelayPolicy.Interval.Milliseconds())
+	viper.SetDefault("EVENTS_SOURCE", event.DefaultSource)
+	viper.SetDefault("EVENTS_NATS_URL", "nats://127.0.0.1:4222")
+	viper.SetDefault("EVENTS_NATS_SUBJECT", adapters.DefaultNatsEventSubject)
+	viper.SetDefault("EVENTS_KAFKA_BROKERS", "127.0.0.1:9092")
+	viper.SetDefault("EVENTS_KAFKA_TOPIC", adapters.DefaultKafkaEventTopic)
+	viper.SetDefault("WEBHOOKS_DISPATCH_INTERVAL", webhook.DefaultDeliveryPolicy.Interval.Milliseconds())
+	viper.SetDefault("WEBHOOKS_MAX_ATTEMPTS", webhook.DefaultDeliveryPolicy.MaxAttempts)
+	viper.SetDefault("MAIL_OUTBOX_INTERVAL", email.DefaultDispatchPolicy.Interval.Milliseconds())
+	viper.SetDefault("MAIL_OUTBOX_MAX_ATTEMPTS", email.DefaultDispatchPolicy.MaxAttempts)
 
 	//init logger
 	f, err := os.OpenFile("logs/app-log.json", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
@@ -87,6 +135,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	ittl := viper.GetInt("JWT_IMPERSONATION_TTL")
+	if ittl == 0 {
+		ittl = 900
+	}
+
 	googleKey := viper.GetString("GOOGLE_KEY")
 	if googleKey == "" {
 		logger.Errorf("GOOGLE_KEY configuration must be provided %v", err)
@@ -99,23 +152,132 @@ func main() {
 		os.Exit(1)
 	}
 
+	mailHost := viper.GetString("MAIL_HOST")
+	if mailHost == "" && viper.GetString("MAIL_TRANSPORT") == app.MailTransportSMTP {
+		logger.Errorf("MAIL_HOST configuration must be provided for the smtp mail transport, MAIL_TRANSPORT=file writes emails to disk instead")
+		os.Exit(1)
+	}
+
+	mailPort := viper.GetString("MAIL_PORT")
+	if mailPort == "" {
+		mailPort = "587"
+	}
+
 	//init application
 	appConfig := app.Config{
-		JwtSecret:       jwtSecret,
-		JwtAccessTTL:    attl,
-		JwtRefreshTTL:   rttl,
-		MaxUserSessions: maxSessions,
-		GoogleKey:       googleKey,
+		JwtSecret:           jwtSecret,
+		JwtAccessTTL:        attl,
+		JwtRefreshTTL:       rttl,
+		JwtImpersonationTTL: ittl,
+		MaxUserSessions:     maxSessions,
+		GoogleKey:           googleKey,
+		FrontendUrl:         viper.GetString("FRONTEND_URL"),
+		MailSender:          viper.GetString("MAIL_SENDER"),
+		MailHost:            mailHost,
+		MailPort:            mailPort,
+		MailPassword:        viper.GetString("MAIL_PASSWORD"),
+		MailFromName:        viper.GetString("MAIL_FROM_NAME"),
+		MailReplyTo:         viper.GetString("MAIL_REPLY_TO"),
+		MailTemplatesDir:    viper.GetString("MAIL_TEMPLATES_DIR"),
+		MailDefaultLocale:   viper.GetString("MAIL_DEFAULT_LOCALE"),
+		MailTransport:       viper.GetString("MAIL_TRANSPORT"),
+		MailFileDir:         viper.GetString("MAIL_FILE_DIR"),
+		MailSinkAddr:        viper.GetString("MAIL_SINK_ADDR"),
+		LoginAttemptsStore:  viper.GetString("LOGIN_ATTEMPTS_STORE"),
+		SignUpMode:          viper.GetString("SIGN_UP_MODE"),
+		RateLimitStore:      viper.GetString("RATE_LIMIT_STORE"),
+		RateLimits:          viper.GetString("RATE_LIMITS"),
+		RedisUrl:            viper.GetString("REDIS_URL"),
+
+		MailOutboxInterval:    viper.GetInt("MAIL_OUTBOX_INTERVAL"),
+		MailOutboxMaxAttempts: viper.GetInt("MAIL_OUTBOX_MAX_ATTEMPTS"),
+
+		MailSmtpSecurity:   viper.GetString("MAIL_SMTP_SECURITY"),
+		MailSmtpPoolSize:   viper.GetInt("MAIL_SMTP_POOL_SIZE"),
+		MailSendmailPath:   viper.GetString("MAIL_SENDMAIL_PATH"),
+		MailHttpEndpoint:   viper.GetString("MAIL_HTTP_ENDPOINT"),
+		MailHttpApiKey:     viper.GetString("MAIL_HTTP_API_KEY"),
+		MailHttpAuthHeader: viper.GetString("MAIL_HTTP_AUTH_HEADER"),
+
+		EventsPublisher:     viper.GetString("EVENTS_PUBLISHER"),
+		EventsRelayInterval: viper.GetInt("EVENTS_RELAY_INTERVAL"),
+		EventsSource:        viper.GetString("EVENTS_SOURCE"),
+		EventsNatsUrl:       viper.GetString("EVENTS_NATS_URL"),
+		EventsNatsSubject:   viper.GetString("EVENTS_NATS_SUBJECT"),
+		EventsNatsStream:    viper.GetString("EVENTS_NATS_STREAM"),
+		EventsKafkaBrokers:  viper.GetString("EVENTS_KAFKA_BROKERS"),
+		EventsKafkaTopic:    viper.GetString("EVENTS_KAFKA_TOPIC"),
+
+		WebhooksAllowHttp:        viper.GetBool("WEBHOOKS_ALLOW_HTTP"),
+		WebhooksDispatchInterval: viper.GetInt("WEBHOOKS_DISPATCH_INTERVAL"),
+		WebhooksMaxAttempts:      viper.GetInt("WEBHOOKS_MAX_ATTEMPTS"),
+
+		PasswordMinLength:         viper.GetInt("PASSWORD_MIN_LENGTH"),
+		PasswordMinClasses:        viper.GetInt("PASSWORD_MIN_CLASSES"),
+		PasswordMinScore:          viper.GetInt("PASSWORD_MIN_SCORE"),
+		BreachedPasswordsFile:     viper.GetString("BREACHED_PASSWORDS_FILE"),
+		BreachedPasswordsMinCount: viper.GetInt("BREACHED_PASSWORDS_MIN_COUNT"),
+
+		PasswordHasher:    viper.GetString("PASSWORD_HASHER"),
+		BcryptCost:        viper.GetInt("BCRYPT_COST"),
+		Argon2Memory:      viper.GetInt("ARGON2_MEMORY"),
+		Argon2Iterations:  viper.GetInt("ARGON2_ITERATIONS"),
+		Argon2Parallelism: viper.GetInt("ARGON2_PARALLELISM"),
+
+		HashingWorkers: viper.GetInt("HASHING_WORKERS"),
+		HashingQueue:   viper.GetInt("HASHING_QUEUE"),
+		HashingMaxWait: viper.GetInt("HASHING_MAX_WAIT"),
+
+		CaptchaProvider:    viper.GetString("CAPTCHA_PROVIDER"),
+		CaptchaSecret:      viper.GetString("CAPTCHA_SECRET"),
+		CaptchaVerifyUrl:   viper.GetString("CAPTCHA_VERIFY_URL"),
+		CaptchaSignInAfter: viper.GetInt("CAPTCHA_SIGN_IN_AFTER"),
+
+		EmailDomainsAllow:     viper.GetString("EMAIL_DOMAINS_ALLOW"),
+		EmailDomainsDeny:      viper.GetString("EMAIL_DOMAINS_DENY"),
+		DisposableDomainsFile: viper.GetString("DISPOSABLE_DOMAINS_FILE"),
+
+		SessionMode:           viper.GetString("SESSION_MODE"),
+		SessionCookieDomain:   viper.GetString("SESSION_COOKIE_DOMAIN"),
+		SessionCookiePath:     viper.GetString("SESSION_COOKIE_PATH"),
+		SessionCookieSameSite: viper.GetString("SESSION_COOKIE_SAME_SITE"),
+		SessionCookieInsecure: viper.GetBool("SESSION_COOKIE_INSECURE"),
+
+		TrustedProxies: viper.GetString("TRUSTED_PROXIES"),
+
+		CorsAllowedOrigins:   viper.GetString(environmentKey("CORS_ALLOWED_ORIGINS")),
+		CorsAllowedMethods:   viper.GetString(environmentKey("CORS_ALLOWED_METHODS")),
+		CorsAllowedHeaders:   viper.GetString(environmentKey("CORS_ALLOWED_HEADERS")),
+		CorsAllowCredentials: viper.GetBool(environmentKey("CORS_ALLOW_CREDENTIALS")),
+		CorsMaxAge:           viper.GetInt(environmentKey("CORS_MAX_AGE")),
 	}
 
-	app, err := app.NewApplication(&appConfig, logger, pool)
+	app, err := app.NewApplication(ctx, &appConfig, logger, pool)
 	if err != nil {
 		logger.Errorf("Application init error %v", err)
 		os.Exit(1)
 	}
 
+	go ports.NewGrpcServer(app).Start(ctx)
+
 	server := ports.NewHttpServer(app)
-	server.Start()
+	server.Start(ctx)
+}
+
+// environmentKey prefers the key suffixed with APP_ENV when it is set, e.g. CORS_ALLOWED_ORIGINS_PRODUCTION
+// over CORS_ALLOWED_ORIGINS for APP_ENV=production
+func environmentKey(key string) string {
+	environment := viper.GetString("APP_ENV")
+	if environment == "" {
+		return key
+	}
+
+	specific := key + "_" + strings.ToUpper(environment)
+	if viper.IsSet(specific) {
+		return specific
+	}
+
+	return key
 }
 
 type QueryTracer struct {
@@ -0,0 +1 @@
+ALTER TABLE public.users ADD "role" varchar(32) NOT NULL DEFAULT 'user';
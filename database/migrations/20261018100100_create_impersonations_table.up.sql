@@ -0,0 +1,12 @@
+CREATE TABLE public.impersonations (
+	uuid uuid NOT NULL,
+	actor_uuid uuid NOT NULL,
+	target_uuid uuid NOT NULL,
+	reason varchar(500) NOT NULL,
+	expires_at timestamp NOT NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT impersonations_pk PRIMARY KEY (uuid)
+);
+
+CREATE INDEX impersonations_target_uuid_idx ON public.impersonations (target_uuid);
+CREATE INDEX impersonations_actor_uuid_idx ON public.impersonations (actor_uuid);
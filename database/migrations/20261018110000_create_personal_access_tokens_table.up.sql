@@ -0,0 +1,15 @@
+CREATE TABLE public.personal_access_tokens (
+	uuid uuid NOT NULL,
+	user_uuid uuid NOT NULL,
+	name varchar(100) NOT NULL,
+	hash varchar(64) NOT NULL,
+	scopes varchar[] NOT NULL,
+	expires_at timestamp NOT NULL,
+	last_used_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT personal_access_tokens_pk PRIMARY KEY (uuid),
+	CONSTRAINT personal_access_tokens_fk FOREIGN KEY (user_uuid) REFERENCES public.users(uuid) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX personal_access_tokens_hash_idx ON public.personal_access_tokens (hash);
+CREATE INDEX personal_access_tokens_user_uuid_idx ON public.personal_access_tokens (user_uuid);
@@ -0,0 +1,9 @@
+CREATE TABLE public.oauth_clients (
+	client_id varchar(64) NOT NULL,
+	name varchar(200) NOT NULL,
+	secret_hash varchar(256) NOT NULL,
+	scopes varchar[] NOT NULL,
+	created_by uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT oauth_clients_pk PRIMARY KEY (client_id)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE public.users DROP CONSTRAINT IF EXISTS users_active_household_fk;
+ALTER TABLE public.users DROP COLUMN IF EXISTS active_household_uuid;
+DROP TABLE IF EXISTS public.household_invitations;
+DROP TABLE IF EXISTS public.household_members;
+DROP TABLE IF EXISTS public.households;
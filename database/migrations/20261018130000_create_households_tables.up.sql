@@ -0,0 +1,39 @@
+CREATE TABLE public.households (
+	uuid uuid NOT NULL,
+	name varchar(200) NOT NULL,
+	owner_uuid uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT households_pk PRIMARY KEY (uuid),
+	CONSTRAINT households_owner_fk FOREIGN KEY (owner_uuid) REFERENCES public.users(uuid) ON DELETE CASCADE
+);
+
+CREATE TABLE public.household_members (
+	household_uuid uuid NOT NULL,
+	user_uuid uuid NOT NULL,
+	"role" varchar(32) NOT NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT household_members_pk PRIMARY KEY (household_uuid, user_uuid),
+	CONSTRAINT household_members_household_fk FOREIGN KEY (household_uuid) REFERENCES public.households(uuid) ON DELETE CASCADE,
+	CONSTRAINT household_members_user_fk FOREIGN KEY (user_uuid) REFERENCES public.users(uuid) ON DELETE CASCADE
+);
+
+CREATE INDEX household_members_user_uuid_idx ON public.household_members (user_uuid);
+
+CREATE TABLE public.household_invitations (
+	uuid uuid NOT NULL,
+	household_uuid uuid NOT NULL,
+	email varchar(320) NOT NULL,
+	inviter_uuid uuid NOT NULL,
+	status varchar(32) NOT NULL,
+	expires_at timestamp NOT NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT household_invitations_pk PRIMARY KEY (uuid),
+	CONSTRAINT household_invitations_household_fk FOREIGN KEY (household_uuid) REFERENCES public.households(uuid) ON DELETE CASCADE
+);
+
+CREATE INDEX household_invitations_email_idx ON public.household_invitations (email);
+
+ALTER TABLE public.users ADD active_household_uuid uuid NULL;
+ALTER TABLE public.users ADD CONSTRAINT users_active_household_fk FOREIGN KEY (active_household_uuid) REFERENCES public.households(uuid) ON DELETE SET NULL;
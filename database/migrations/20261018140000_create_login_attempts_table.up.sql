@@ -0,0 +1,9 @@
+CREATE TABLE public.login_attempts (
+	"key" varchar(400) NOT NULL,
+	failures int4 NOT NULL,
+	last_failure_at timestamp NOT NULL,
+	locked_until timestamp NOT NULL,
+	CONSTRAINT login_attempts_pk PRIMARY KEY ("key")
+);
+
+CREATE INDEX login_attempts_last_failure_at_idx ON public.login_attempts (last_failure_at);
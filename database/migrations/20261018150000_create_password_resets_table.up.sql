@@ -0,0 +1,12 @@
+CREATE TABLE public.password_resets (
+	uuid uuid NOT NULL,
+	user_uuid uuid NOT NULL,
+	hash varchar(64) NOT NULL,
+	expires_at timestamp NOT NULL,
+	used_at timestamp NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT password_resets_pk PRIMARY KEY (uuid),
+	CONSTRAINT password_resets_fk FOREIGN KEY (user_uuid) REFERENCES public.users(uuid) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX password_resets_hash_idx ON public.password_resets (hash);
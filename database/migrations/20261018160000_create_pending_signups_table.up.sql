@@ -0,0 +1,13 @@
+CREATE TABLE public.pending_signups (
+	uuid uuid NOT NULL,
+	email varchar(320) NOT NULL,
+	"name" varchar(200) NOT NULL,
+	hash varchar(256) NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	expires_at timestamp NOT NULL,
+	created_at timestamp NOT NULL,
+	CONSTRAINT pending_signups_pk PRIMARY KEY (uuid)
+);
+
+CREATE UNIQUE INDEX pending_signups_token_hash_idx ON public.pending_signups (token_hash);
+CREATE INDEX pending_signups_email_idx ON public.pending_signups (email);
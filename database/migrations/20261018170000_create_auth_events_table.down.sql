@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.auth_events;
+DROP FUNCTION IF EXISTS public.auth_events_append_only();
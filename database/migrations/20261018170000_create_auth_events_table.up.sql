@@ -0,0 +1,26 @@
+CREATE TABLE public.auth_events (
+	uuid uuid NOT NULL,
+	"type" varchar(64) NOT NULL,
+	user_uuid uuid NULL,
+	actor_uuid uuid NULL,
+	email varchar(320) NOT NULL DEFAULT '',
+	ip varchar(64) NOT NULL DEFAULT '',
+	user_agent varchar(512) NOT NULL DEFAULT '',
+	request_id varchar(128) NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL,
+	CONSTRAINT auth_events_pk PRIMARY KEY (uuid)
+);
+
+CREATE INDEX auth_events_user_uuid_created_at_idx ON public.auth_events (user_uuid, created_at DESC);
+CREATE INDEX auth_events_created_at_idx ON public.auth_events (created_at DESC);
+
+-- the audit trail outlives deleted users and can not be rewritten
+CREATE FUNCTION public.auth_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'auth_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER auth_events_append_only
+	BEFORE UPDATE OR DELETE ON public.auth_events
+	FOR EACH ROW EXECUTE FUNCTION public.auth_events_append_only();
@@ -0,0 +1,3 @@
+DROP INDEX public.refresh_tokens_user_uuid_device_fingerprint_idx;
+
+ALTER TABLE public.refresh_tokens DROP COLUMN device_fingerprint;
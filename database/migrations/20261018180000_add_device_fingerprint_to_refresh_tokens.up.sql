@@ -0,0 +1,3 @@
+ALTER TABLE public.refresh_tokens ADD device_fingerprint varchar(64) NOT NULL DEFAULT '';
+
+CREATE INDEX refresh_tokens_user_uuid_device_fingerprint_idx ON public.refresh_tokens (user_uuid, device_fingerprint);
@@ -0,0 +1,18 @@
+CREATE TABLE public.email_outbox (
+	uuid uuid NOT NULL,
+	status varchar(16) NOT NULL DEFAULT 'pending',
+	from_address varchar(1024) NOT NULL,
+	reply_to varchar(1024) NOT NULL DEFAULT '',
+	to_address varchar(320) NOT NULL,
+	subject varchar(998) NOT NULL,
+	text_body text NOT NULL,
+	html_body text NOT NULL DEFAULT '',
+	attempts int NOT NULL DEFAULT 0,
+	next_attempt_at timestamp NOT NULL,
+	last_error text NOT NULL DEFAULT '',
+	created_at timestamp NOT NULL,
+	sent_at timestamp NULL,
+	CONSTRAINT email_outbox_pk PRIMARY KEY (uuid)
+);
+
+CREATE INDEX email_outbox_pending_idx ON public.email_outbox (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,15 @@
+CREATE TABLE public.domain_events (
+	uuid uuid NOT NULL,
+	type varchar(64) NOT NULL,
+	user_uuid uuid NOT NULL,
+	data jsonb NOT NULL,
+	occurred_at timestamp NOT NULL,
+	attempts int NOT NULL DEFAULT 0,
+	next_attempt_at timestamp NOT NULL,
+	last_error text NOT NULL DEFAULT '',
+	published_at timestamp NULL,
+	CONSTRAINT domain_events_pk PRIMARY KEY (uuid)
+);
+
+CREATE INDEX domain_events_pending_idx ON public.domain_events (next_attempt_at) WHERE published_at IS NULL;
+CREATE INDEX domain_events_pending_user_idx ON public.domain_events (user_uuid, occurred_at) WHERE published_at IS NULL;
@@ -0,0 +1,34 @@
+CREATE TABLE public.webhook_subscriptions (
+	uuid uuid NOT NULL,
+	url varchar(2048) NOT NULL,
+	secret varchar(128) NOT NULL,
+	events varchar(64)[] NOT NULL DEFAULT '{}',
+	active boolean NOT NULL DEFAULT true,
+	created_by uuid NOT NULL,
+	created_at timestamp NOT NULL,
+	updated_at timestamp NOT NULL,
+	CONSTRAINT webhook_subscriptions_pk PRIMARY KEY (uuid)
+);
+
+CREATE TABLE public.webhook_deliveries (
+	uuid uuid NOT NULL,
+	subscription_uuid uuid NOT NULL,
+	event_uuid uuid NOT NULL,
+	event_type varchar(64) NOT NULL,
+	payload bytea NOT NULL,
+	status varchar(16) NOT NULL DEFAULT 'pending',
+	attempts int NOT NULL DEFAULT 0,
+	next_attempt_at timestamp NOT NULL,
+	response_code int NOT NULL DEFAULT 0,
+	response_body text NOT NULL DEFAULT '',
+	last_error text NOT NULL DEFAULT '',
+	redelivery_of uuid NULL,
+	created_at timestamp NOT NULL,
+	delivered_at timestamp NULL,
+	CONSTRAINT webhook_deliveries_pk PRIMARY KEY (uuid),
+	CONSTRAINT webhook_deliveries_subscription_fk FOREIGN KEY (subscription_uuid) REFERENCES public.webhook_subscriptions(uuid) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX webhook_deliveries_event_idx ON public.webhook_deliveries (subscription_uuid, event_uuid) WHERE redelivery_of IS NULL;
+CREATE INDEX webhook_deliveries_subscription_idx ON public.webhook_deliveries (subscription_uuid, created_at);
+CREATE INDEX webhook_deliveries_pending_idx ON public.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,135 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuthEventModel struct {
+	UUID      uuid.UUID  `db:"uuid"`
+	Type      string     `db:"type"`
+	UserUUID  *uuid.UUID `db:"user_uuid"`
+	ActorUUID *uuid.UUID `db:"actor_uuid"`
+	Email     string     `db:"email"`
+	IP        string     `db:"ip"`
+	UserAgent string     `db:"user_agent"`
+	RequestID string     `db:"request_id"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// AuthEventPgsqlRepository appends to auth_events, the table refuses updates and deletes
+type AuthEventPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuthEventPgsqlRepository(pool *pgxpool.Pool) *AuthEventPgsqlRepository {
+	return &AuthEventPgsqlRepository{pool}
+}
+
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	return &id
+}
+
+func (s *AuthEventPgsqlRepository) Add(ctx context.Context, e *audit.Event) error {
+	_, err := s.pool.Exec(ctx, "insert into auth_events(uuid, type, user_uuid, actor_uuid, email, ip, user_agent, request_id, created_at) values($1,$2,$3,$4,$5,$6,$7,$8,$9)",
+		e.UUID,
+		e.Type.String(),
+		nullableUUID(e.UserUUID),
+		nullableUUID(e.ActorUUID),
+		e.Email,
+		e.IP,
+		e.UserAgent,
+		e.RequestID,
+		e.CreatedAt)
+
+	return err
+}
+
+func (s *AuthEventPgsqlRepository) Find(ctx context.Context, q audit.Query) ([]*audit.Event, error) {
+	q = q.Normalized()
+
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.UserUUID != uuid.Nil {
+		where("user_uuid = $%d", q.UserUUID)
+	}
+
+	if q.Email != "" {
+		where("lower(email) = lower($%d)", q.Email)
+	}
+
+	if len(q.Types) > 0 {
+		types := make([]string, 0, len(q.Types))
+		for _, t := range q.Types {
+			types = append(types, t.String())
+		}
+		where("type = any($%d)", types)
+	}
+
+	if !q.Before.IsZero() {
+		where("created_at < $%d", q.Before)
+	}
+
+	query := "select uuid, type, user_uuid, actor_uuid, email, ip, user_agent, request_id, created_at from auth_events"
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+
+	args = append(args, q.Limit)
+	query += fmt.Sprintf(" order by created_at desc limit $%d", len(args))
+
+	var models []*AuthEventModel
+	if err := pgxscan.Select(ctx, s.pool, &models, query, args...); err != nil {
+		return []*audit.Event{}, err
+	}
+
+	events := make([]*audit.Event, 0, len(models))
+	for _, model := range models {
+		events = append(events, serviceAuthEventFromModel(model))
+	}
+
+	return events, nil
+}
+
+func serviceAuthEventFromModel(model *AuthEventModel) *audit.Event {
+	eventType, err := audit.FromString(model.Type)
+	if err != nil {
+		eventType = audit.UNKNOWN
+	}
+
+	event := &audit.Event{
+		UUID:      model.UUID,
+		Type:      eventType,
+		Email:     model.Email,
+		IP:        model.IP,
+		UserAgent: model.UserAgent,
+		RequestID: model.RequestID,
+		CreatedAt: model.CreatedAt,
+	}
+
+	if model.UserUUID != nil {
+		event.UserUUID = *model.UserUUID
+	}
+
+	if model.ActorUUID != nil {
+		event.ActorUUID = *model.ActorUUID
+	}
+
+	return event
+}
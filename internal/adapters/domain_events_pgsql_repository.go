@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DomainEventModel struct {
+	UUID          uuid.UUID  `db:"uuid"`
+	Type          string     `db:"type"`
+	UserUUID      uuid.UUID  `db:"user_uuid"`
+	Data          []byte     `db:"data"`
+	OccurredAt    time.Time  `db:"occurred_at"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     string     `db:"last_error"`
+	PublishedAt   *time.Time `db:"published_at"`
+}
+
+type DomainEventsPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewDomainEventsPgsqlRepository(pool *pgxpool.Pool) *DomainEventsPgsqlRepository {
+	return &DomainEventsPgsqlRepository{pool}
+}
+
+func (s *DomainEventsPgsqlRepository) Add(ctx context.Context, e *event.Event) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "insert into domain_events(uuid, type, user_uuid, data, occurred_at, attempts, next_attempt_at) values($1,$2,$3,$4,$5,$6,$7)",
+		e.UUID,
+		e.Type.String(),
+		e.UserUUID,
+		string(e.Data),
+		e.OccurredAt,
+		e.Attempts,
+		e.NextAttemptAt)
+
+	return err
+}
+
+func (s *DomainEventsPgsqlRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*event.Event, error) {
+	models := []*DomainEventModel{}
+	err := pgxscan.Select(ctx, connectorFor(ctx, s.pool), &models,
+		`update domain_events set next_attempt_at = $2 where uuid in (
+			select e.uuid from domain_events e where e.published_at is null and e.next_attempt_at <= $1
+			and not exists (
+				select 1 from domain_events p where p.user_uuid = e.user_uuid and p.published_at is null
+				and (p.occurred_at, p.uuid) < (e.occurred_at, e.uuid)
+			)
+			order by e.occurred_at limit $3 for update skip locked
+		) returning *`,
+		now, now.Add(lease), limit,
+	)
+	if err != nil {
+		return []*event.Event{}, err
+	}
+
+	events := make([]*event.Event, 0, len(models))
+	for _, model := range models {
+		t, err := event.FromString(model.Type)
+		if err != nil {
+			return []*event.Event{}, err
+		}
+
+		events = append(events, &event.Event{
+			UUID:          model.UUID,
+			Type:          t,
+			UserUUID:      model.UserUUID,
+			Data:          model.Data,
+			OccurredAt:    model.OccurredAt,
+			Attempts:      model.Attempts,
+			NextAttemptAt: model.NextAttemptAt,
+			LastError:     model.LastError,
+			PublishedAt:   model.PublishedAt,
+		})
+	}
+
+	return events, nil
+}
+
+func (s *DomainEventsPgsqlRepository) MarkPublished(ctx context.Context, uuid uuid.UUID, at time.Time) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "update domain_events set published_at = $1 where uuid = $2", at, uuid)
+
+	return err
+}
+
+func (s *DomainEventsPgsqlRepository) MarkFailed(ctx context.Context, uuid uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "update domain_events set attempts = $1, next_attempt_at = $2, last_error = $3 where uuid = $4",
+		attempts, nextAttemptAt, lastError, uuid)
+
+	return err
+}
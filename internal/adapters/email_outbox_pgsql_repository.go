@@ -0,0 +1,115 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/email"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxEmailModel struct {
+	UUID          uuid.UUID  `db:"uuid"`
+	Status        string     `db:"status"`
+	FromAddress   string     `db:"from_address"`
+	ReplyTo       string     `db:"reply_to"`
+	ToAddress     string     `db:"to_address"`
+	Subject       string     `db:"subject"`
+	TextBody      string     `db:"text_body"`
+	HtmlBody      string     `db:"html_body"`
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     string     `db:"last_error"`
+	CreatedAt     time.Time  `db:"created_at"`
+	SentAt        *time.Time `db:"sent_at"`
+}
+
+type EmailOutboxPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewEmailOutboxPgsqlRepository(pool *pgxpool.Pool) *EmailOutboxPgsqlRepository {
+	return &EmailOutboxPgsqlRepository{pool}
+}
+
+func (s *EmailOutboxPgsqlRepository) Add(ctx context.Context, e *email.OutboxEmail) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "insert into email_outbox(uuid, status, from_address, reply_to, to_address, subject, text_body, html_body, attempts, next_attempt_at, created_at) values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)",
+		e.UUID,
+		e.Status.String(),
+		e.Message.From,
+		e.Message.ReplyTo,
+		e.Message.To,
+		e.Message.Subject,
+		e.Message.Text,
+		e.Message.HTML,
+		e.Attempts,
+		e.NextAttemptAt,
+		e.CreatedAt)
+
+	return err
+}
+
+func (s *EmailOutboxPgsqlRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*email.OutboxEmail, error) {
+	models := []*OutboxEmailModel{}
+	err := pgxscan.Select(ctx, connectorFor(ctx, s.pool), &models,
+		`update email_outbox set next_attempt_at = $2 where uuid in (
+			select uuid from email_outbox where status = $3 and next_attempt_at <= $1
+			order by next_attempt_at limit $4 for update skip locked
+		) returning *`,
+		now, now.Add(lease), email.PENDING.String(), limit,
+	)
+	if err != nil {
+		return []*email.OutboxEmail{}, err
+	}
+
+	emails := make([]*email.OutboxEmail, 0, len(models))
+	for _, model := range models {
+		status, err := email.OutboxStatusFromString(model.Status)
+		if err != nil {
+			return []*email.OutboxEmail{}, err
+		}
+
+		emails = append(emails, &email.OutboxEmail{
+			UUID: model.UUID,
+			Message: &email.Message{
+				From:    model.FromAddress,
+				ReplyTo: model.ReplyTo,
+				To:      model.ToAddress,
+				Subject: model.Subject,
+				Text:    model.TextBody,
+				HTML:    model.HtmlBody,
+			},
+			Status:        status,
+			Attempts:      model.Attempts,
+			NextAttemptAt: model.NextAttemptAt,
+			LastError:     model.LastError,
+			CreatedAt:     model.CreatedAt,
+			SentAt:        model.SentAt,
+		})
+	}
+
+	return emails, nil
+}
+
+func (s *EmailOutboxPgsqlRepository) MarkSent(ctx context.Context, uuid uuid.UUID, at time.Time) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "update email_outbox set status = $1, sent_at = $2 where uuid = $3",
+		email.SENT.String(), at, uuid)
+
+	return err
+}
+
+func (s *EmailOutboxPgsqlRepository) MarkFailed(ctx context.Context, uuid uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "update email_outbox set attempts = $1, next_attempt_at = $2, last_error = $3 where uuid = $4",
+		attempts, nextAttemptAt, lastError, uuid)
+
+	return err
+}
+
+func (s *EmailOutboxPgsqlRepository) MarkDead(ctx context.Context, uuid uuid.UUID, attempts int, lastError string) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "update email_outbox set status = $1, attempts = $2, last_error = $3 where uuid = $4",
+		email.DEAD.String(), attempts, lastError, uuid)
+
+	return err
+}
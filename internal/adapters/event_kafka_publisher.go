@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const DefaultKafkaEventTopic = "users.events"
+
+// EventKafkaPublisher produces CloudEvents keyed by the user UUID, the default partitioner hashes keys like the
+// Java client so that the events of a user land on one partition in order
+type EventKafkaPublisher struct {
+	client *kgo.Client
+	topic  string
+	source string
+}
+
+func NewEventKafkaPublisher(client *kgo.Client, topic, source string) *EventKafkaPublisher {
+	return &EventKafkaPublisher{
+		client: client,
+		topic:  topic,
+		source: source,
+	}
+}
+
+func (p *EventKafkaPublisher) Publish(ctx context.Context, e *event.Event) error {
+	body, err := json.Marshal(event.NewCloudEvent(e, p.source))
+	if err != nil {
+		return err
+	}
+
+	record := &kgo.Record{
+		Topic: event.Destination(p.topic, e),
+		Key:   []byte(e.UserUUID.String()),
+		Value: body,
+		Headers: []kgo.RecordHeader{
+			{Key: "content-type", Value: []byte(event.CloudEventsContentType)},
+		},
+	}
+
+	return p.client.ProduceSync(ctx, record).FirstErr()
+}
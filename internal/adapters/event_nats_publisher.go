@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/nats-io/nats.go"
+)
+
+const DefaultNatsEventSubject = "users.{type}.{user}"
+
+// EventNatsPublisher publishes CloudEvents to JetStream. The subject template ends with the user UUID by default,
+// so that streams can map users to partitions, and the event UUID is the message id which JetStream dedupes
+// within its duplicate window when the relay publishes an event again.
+type EventNatsPublisher struct {
+	js      nats.JetStreamContext
+	subject string
+	source  string
+}
+
+func NewEventNatsPublisher(js nats.JetStreamContext, subject, source string) *EventNatsPublisher {
+	return &EventNatsPublisher{
+		js:      js,
+		subject: subject,
+		source:  source,
+	}
+}
+
+func (p *EventNatsPublisher) Publish(ctx context.Context, e *event.Event) error {
+	body, err := json.Marshal(event.NewCloudEvent(e, p.source))
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(event.Destination(p.subject, e))
+	msg.Data = body
+	msg.Header.Set(nats.MsgIdHdr, e.UUID.String())
+	msg.Header.Set("Content-Type", event.CloudEventsContentType)
+
+	_, err = p.js.PublishMsg(msg, nats.Context(ctx))
+	return err
+}
+
+// EnsureNatsStream creates the stream for the subject template unless it exists
+func EnsureNatsStream(js nats.JetStreamContext, name, subject string) error {
+	_, err := js.StreamInfo(name)
+	if err == nil {
+		return nil
+	}
+
+	if err != nats.ErrStreamNotFound {
+		return err
+	}
+
+	_, err = js.AddStream(&nats.StreamConfig{Name: name, Subjects: []string{natsStreamSubject(subject)}})
+	return err
+}
+
+// natsStreamSubject matches every subject of the template, "users.{type}.{user}" gives "users.>"
+func natsStreamSubject(template string) string {
+	i := strings.Index(template, "{")
+	if i < 0 {
+		return template
+	}
+
+	return template[:i] + ">"
+}
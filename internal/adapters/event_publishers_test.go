@@ -0,0 +1,188 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func newTestEvent(t *testing.T, eventType event.Type, userUUID uuid.UUID) *event.Event {
+	e, err := event.New(eventType, userUUID, event.UserPayload{UUID: userUUID, Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func assertCloudEvent(t *testing.T, body []byte, e *event.Event) {
+	var envelope event.CloudEvent
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	if envelope.SpecVersion != "1.0" || envelope.ID != e.UUID.String() || envelope.Type != e.Type.String() ||
+		envelope.Source != "/users" || envelope.Subject != e.UserUUID.String() || string(envelope.Data) != string(e.Data) {
+		t.Errorf("Want the CloudEvent of %s, got %+v", e.UUID, envelope)
+	}
+}
+
+func TestEventNatsPublisher(t *testing.T) {
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go ns.Start()
+	defer ns.Shutdown()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnsureNatsStream(js, "USERS", DefaultNatsEventSubject); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := EnsureNatsStream(js, "USERS", DefaultNatsEventSubject); err != nil {
+		t.Fatalf("Want an existing stream kept, got %v", err)
+	}
+
+	publisher := NewEventNatsPublisher(js, DefaultNatsEventSubject, event.DefaultSource)
+	userUUID := uuid.New()
+	created := newTestEvent(t, event.USER_CREATED, userUUID)
+	updated := newTestEvent(t, event.USER_UPDATED, userUUID)
+
+	for _, e := range []*event.Event{created, updated, created} {
+		if err := publisher.Publish(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := js.StreamInfo("USERS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.State.Msgs != 2 {
+		t.Fatalf("Want the republished event deduped to 2 messages, got %d", info.State.Msgs)
+	}
+
+	for seq, e := range []*event.Event{created, updated} {
+		msg, err := js.GetMsg("USERS", uint64(seq+1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := "users." + e.Type.String() + "." + userUUID.String(); msg.Subject != want {
+			t.Errorf("Want subject '%s', got '%s'", want, msg.Subject)
+		}
+
+		if msg.Header.Get("Content-Type") != event.CloudEventsContentType {
+			t.Errorf("Want the CloudEvents content type, got '%s'", msg.Header.Get("Content-Type"))
+		}
+
+		assertCloudEvent(t, msg.Data, e)
+	}
+}
+
+func TestEventKafkaPublisher(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(4, DefaultKafkaEventTopic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cluster.Close()
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+
+	publisher := NewEventKafkaPublisher(producer, DefaultKafkaEventTopic, event.DefaultSource)
+	published := map[string]*event.Event{}
+	order := map[uuid.UUID][]string{}
+	for i := 0; i < 4; i++ {
+		userUUID := uuid.New()
+		for _, eventType := range []event.Type{event.USER_CREATED, event.USER_UPDATED, event.SESSION_REVOKED} {
+			e := newTestEvent(t, eventType, userUUID)
+			if err := publisher.Publish(context.Background(), e); err != nil {
+				t.Fatal(err)
+			}
+
+			published[e.UUID.String()] = e
+			order[userUUID] = append(order[userUUID], e.UUID.String())
+		}
+	}
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(DefaultKafkaEventTopic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	partitions := map[string]int32{}
+	consumed := map[uuid.UUID][]string{}
+	for count := 0; count < len(published); {
+		fetches := consumer.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("Want %d records, got %d", len(published), count)
+		}
+
+		fetches.EachRecord(func(r *kgo.Record) {
+			count++
+
+			var envelope event.CloudEvent
+			if err := json.Unmarshal(r.Value, &envelope); err != nil {
+				t.Fatal(err)
+			}
+
+			e := published[envelope.ID]
+			if e == nil || string(r.Key) != e.UserUUID.String() {
+				t.Fatalf("Want a record keyed by the user, got key '%s'", r.Key)
+			}
+
+			assertCloudEvent(t, r.Value, e)
+
+			if partition, ok := partitions[string(r.Key)]; ok && partition != r.Partition {
+				t.Errorf("Want the events of %s on one partition, got %d and %d", r.Key, partition, r.Partition)
+			}
+			partitions[string(r.Key)] = r.Partition
+			consumed[e.UserUUID] = append(consumed[e.UserUUID], envelope.ID)
+		})
+	}
+
+	for userUUID, ids := range order {
+		for i, id := range ids {
+			if consumed[userUUID][i] != id {
+				t.Errorf("Want the events of %s in order, got %v", userUUID, consumed[userUUID])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/household"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HouseholdModel struct {
+	UUID      uuid.UUID `db:"uuid"`
+	Name      string    `db:"name"`
+	OwnerUUID uuid.UUID `db:"owner_uuid"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type HouseholdMemberModel struct {
+	HouseholdUUID uuid.UUID `db:"household_uuid"`
+	UserUUID      uuid.UUID `db:"user_uuid"`
+	Name          string    `db:"name"`
+	Email         string    `db:"email"`
+	Role          string    `db:"role"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+type HouseholdInvitationModel struct {
+	UUID          uuid.UUID `db:"uuid"`
+	HouseholdUUID uuid.UUID `db:"household_uuid"`
+	HouseholdName string    `db:"household_name"`
+	Email         string    `db:"email"`
+	InviterUUID   uuid.UUID `db:"inviter_uuid"`
+	Status        string    `db:"status"`
+	ExpiresAt     time.Time `db:"expires_at"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+type HouseholdPgsqlRepository struct {
+	pool *pgxpool.Pool
+	tx   pgx.Tx
+}
+
+func NewHouseholdPgsqlRepository(pool *pgxpool.Pool) *HouseholdPgsqlRepository {
+	return &HouseholdPgsqlRepository{pool, nil}
+}
+
+func (s *HouseholdPgsqlRepository) connector(ctx context.Context) PgxConnector {
+	if s.tx != nil {
+		return s.tx
+	}
+
+	return connectorFor(ctx, s.pool)
+}
+
+func (s *HouseholdPgsqlRepository) exec(ctx context.Context, sql string, arguments ...any) error {
+	_, err := s.connector(ctx).Exec(ctx, sql, arguments...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *HouseholdPgsqlRepository) get(ctx context.Context, dst interface{}, notFound string, query string, args ...interface{}) error {
+	if err := pgxscan.Get(
+		ctx, s.connector(ctx), dst, query, args...,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return errors.NewNotFoundError("Not found", notFound)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Transactional runs the callback with a repository bound to a new transaction,
+// nested calls join the outer transaction
+func (s *HouseholdPgsqlRepository) Transactional(ctx context.Context, cb func(r household.Repository) error) error {
+	if s.tx != nil || txFromContext(ctx) != nil {
+		return cb(s)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = cb(&HouseholdPgsqlRepository{s.pool, tx})
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *HouseholdPgsqlRepository) Add(ctx context.Context, h *household.Household) error {
+	return s.exec(ctx, "insert into households(uuid, name, owner_uuid, created_at, updated_at) values($1,$2,$3,$4,$5)",
+		h.UUID, h.Name, h.OwnerUUID, h.CreatedAt, h.UpdatedAt)
+}
+
+func (s *HouseholdPgsqlRepository) FindById(ctx context.Context, uuid uuid.UUID) (*household.Household, error) {
+	model := &HouseholdModel{}
+	if err := s.get(
+		ctx, model, "household-not-found", "select uuid, name, owner_uuid, created_at, updated_at from households where uuid = $1", uuid,
+	); err != nil {
+		return &household.Household{}, err
+	}
+
+	return serviceHouseholdFromModel(model), nil
+}
+
+func (s *HouseholdPgsqlRepository) ListForUser(ctx context.Context, userUUID uuid.UUID) ([]*household.Household, error) {
+	var models []*HouseholdModel
+	if err := pgxscan.Select(
+		ctx, s.connector(ctx), &models, `select h.uuid, h.name, h.owner_uuid, h.created_at, h.updated_at from households h
+		join household_members m on m.household_uuid = h.uuid where m.user_uuid = $1 order by h.created_at`, userUUID,
+	); err != nil {
+		return []*household.Household{}, err
+	}
+
+	households := make([]*household.Household, 0, len(models))
+	for _, model := range models {
+		households = append(households, serviceHouseholdFromModel(model))
+	}
+
+	return households, nil
+}
+
+func (s *HouseholdPgsqlRepository) Delete(ctx context.Context, uuid uuid.UUID) error {
+	return s.exec(ctx, "delete from households where uuid = $1", uuid)
+}
+
+func (s *HouseholdPgsqlRepository) AddMember(ctx context.Context, m *household.Member) error {
+	return s.exec(ctx, "insert into household_members(household_uuid, user_uuid, role, created_at) values($1,$2,$3,$4)",
+		m.HouseholdUUID, m.UserUUID, m.Role.String(), m.CreatedAt)
+}
+
+func (s *HouseholdPgsqlRepository) FindMember(ctx context.Context, householdUUID, userUUID uuid.UUID) (*household.Member, error) {
+	model := &HouseholdMemberModel{}
+	if err := s.get(
+		ctx, model, "household-not-found", `select m.household_uuid, m.user_uuid, u.name, u.email, m.role, m.created_at from household_members m
+		join users u on u.uuid = m.user_uuid where m.household_uuid = $1 and m.user_uuid = $2`, householdUUID, userUUID,
+	); err != nil {
+		return &household.Member{}, err
+	}
+
+	return serviceMemberFromModel(model), nil
+}
+
+func (s *HouseholdPgsqlRepository) Members(ctx context.Context, householdUUID uuid.UUID) ([]*household.Member, error) {
+	var models []*HouseholdMemberModel
+	if err := pgxscan.Select(
+		ctx, s.connector(ctx), &models, `select m.household_uuid, m.user_uuid, u.name, u.email, m.role, m.created_at from household_members m
+		join users u on u.uuid = m.user_uuid where m.household_uuid = $1 order by m.created_at`, householdUUID,
+	); err != nil {
+		return []*household.Member{}, err
+	}
+
+	members := make([]*household.Member, 0, len(models))
+	for _, model := range models {
+		members = append(members, serviceMemberFromModel(model))
+	}
+
+	return members, nil
+}
+
+func (s *HouseholdPgsqlRepository) RemoveMember(ctx context.Context, householdUUID, userUUID uuid.UUID) error {
+	return s.exec(ctx, "delete from household_members where household_uuid = $1 and user_uuid = $2", householdUUID, userUUID)
+}
+
+func (s *HouseholdPgsqlRepository) AddInvitation(ctx context.Context, i *household.Invitation) error {
+	return s.exec(ctx, "insert into household_invitations(uuid, household_uuid, email, inviter_uuid, status, expires_at, created_at, updated_at) values($1,$2,$3,$4,$5,$6,$7,$8)",
+		i.UUID, i.HouseholdUUID, i.Email, i.InviterUUID, i.Status.String(), i.ExpiresAt, i.CreatedAt, i.UpdatedAt)
+}
+
+func (s *HouseholdPgsqlRepository) FindInvitation(ctx context.Context, uuid uuid.UUID) (*household.Invitation, error) {
+	model := &HouseholdInvitationModel{}
+	if err := s.get(
+		ctx, model, "household-invitation-not-found", `select i.uuid, i.household_uuid, h.name as household_name, i.email, i.inviter_uuid, i.status, i.expires_at, i.created_at, i.updated_at
+		from household_invitations i join households h on h.uuid = i.household_uuid where i.uuid = $1`, uuid,
+	); err != nil {
+		return &household.Invitation{}, err
+	}
+
+	return serviceInvitationFromModel(model), nil
+}
+
+func (s *HouseholdPgsqlRepository) PendingInvitationsForEmail(ctx context.Context, email string) ([]*household.Invitation, error) {
+	var models []*HouseholdInvitationModel
+	if err := pgxscan.Select(
+		ctx, s.connector(ctx), &models, `select i.uuid, i.household_uuid, h.name as household_name, i.email, i.inviter_uuid, i.status, i.expires_at, i.created_at, i.updated_at
+		from household_invitations i join households h on h.uuid = i.household_uuid
+		where i.email = $1 and i.status = $2 and i.expires_at > $3 order by i.created_at`, email, household.PENDING.String(), time.Now(),
+	); err != nil {
+		return []*household.Invitation{}, err
+	}
+
+	invitations := make([]*household.Invitation, 0, len(models))
+	for _, model := range models {
+		invitations = append(invitations, serviceInvitationFromModel(model))
+	}
+
+	return invitations, nil
+}
+
+func (s *HouseholdPgsqlRepository) UpdateInvitationStatus(ctx context.Context, uuid uuid.UUID, status household.InvitationStatus) error {
+	return s.exec(ctx, "update household_invitations set status = $1, updated_at = $2 where uuid = $3", status.String(), time.Now(), uuid)
+}
+
+func serviceHouseholdFromModel(model *HouseholdModel) *household.Household {
+	return &household.Household{
+		UUID:      model.UUID,
+		Name:      model.Name,
+		OwnerUUID: model.OwnerUUID,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
+
+func serviceMemberFromModel(model *HouseholdMemberModel) *household.Member {
+	r, err := household.RoleFromString(model.Role)
+	if err != nil {
+		r = household.MEMBER
+	}
+
+	return &household.Member{
+		HouseholdUUID: model.HouseholdUUID,
+		UserUUID:      model.UserUUID,
+		Name:          model.Name,
+		Email:         model.Email,
+		Role:          r,
+		CreatedAt:     model.CreatedAt,
+	}
+}
+
+func serviceInvitationFromModel(model *HouseholdInvitationModel) *household.Invitation {
+	status, err := household.StatusFromString(model.Status)
+	if err != nil {
+		status = household.UNKNOWN_STATUS
+	}
+
+	return &household.Invitation{
+		UUID:          model.UUID,
+		HouseholdUUID: model.HouseholdUUID,
+		HouseholdName: model.HouseholdName,
+		Email:         model.Email,
+		InviterUUID:   model.InviterUUID,
+		Status:        status,
+		ExpiresAt:     model.ExpiresAt,
+		CreatedAt:     model.CreatedAt,
+		UpdatedAt:     model.UpdatedAt,
+	}
+}
@@ -0,0 +1,32 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ImpersonationPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewImpersonationPgsqlRepository(pool *pgxpool.Pool) *ImpersonationPgsqlRepository {
+	return &ImpersonationPgsqlRepository{pool}
+}
+
+func (s *ImpersonationPgsqlRepository) Add(ctx context.Context, i *user.Impersonation) error {
+	_, err := s.pool.Exec(ctx, "insert into impersonations(uuid, actor_uuid, target_uuid, reason, expires_at, created_at) values($1,$2,$3,$4,$5,$6)",
+		i.UUID,
+		i.ActorUUID,
+		i.TargetUUID,
+		i.Reason,
+		i.ExpiresAt,
+		i.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package adapters
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ibgl/microservice-users/internal/app/user"
+)
+
+// LoginAttemptMemoryRepository keeps counters in process memory, suitable for a single instance
+type LoginAttemptMemoryRepository struct {
+	mu       sync.Mutex
+	attempts map[string]user.LoginAttempts
+	ttl      time.Duration
+	lastGC   time.Time
+}
+
+// NewLoginAttemptMemoryRepository creates the repository, counters untouched for ttl are dropped
+func NewLoginAttemptMemoryRepository(ttl time.Duration) *LoginAttemptMemoryRepository {
+	return &LoginAttemptMemoryRepository{
+		attempts: map[string]user.LoginAttempts{},
+		ttl:      ttl,
+		lastGC:   time.Now(),
+	}
+}
+
+func (s *LoginAttemptMemoryRepository) Get(ctx context.Context, key string) (*user.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts, ok := s.attempts[key]
+	if !ok {
+		return &user.LoginAttempts{Key: key}, nil
+	}
+
+	return &attempts, nil
+}
+
+func (s *LoginAttemptMemoryRepository) Increment(ctx context.Context, key string, now time.Time, policy user.LockoutPolicy) (*user.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gc(now)
+
+	attempts, ok := s.attempts[key]
+	if !ok || now.Sub(attempts.LastFailureAt) > policy.Window {
+		attempts = user.LoginAttempts{Key: key, LockedUntil: attempts.LockedUntil}
+	}
+
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	if delay := policy.Delay(attempts.Failures); delay > 0 && now.Add(delay).After(attempts.LockedUntil) {
+		attempts.LockedUntil = now.Add(delay)
+	}
+	s.attempts[key] = attempts
+
+	return &attempts, nil
+}
+
+func (s *LoginAttemptMemoryRepository) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+
+	return nil
+}
+
+// gc drops stale counters at most once per ttl, must be called with the lock held
+func (s *LoginAttemptMemoryRepository) gc(now time.Time) {
+	if now.Sub(s.lastGC) < s.ttl {
+		return
+	}
+
+	for key, attempts := range s.attempts {
+		if now.Sub(attempts.LastFailureAt) > s.ttl && now.After(attempts.LockedUntil) {
+			delete(s.attempts, key)
+		}
+	}
+
+	s.lastGC = now
+}
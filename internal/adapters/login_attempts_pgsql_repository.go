@@ -0,0 +1,89 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginAttemptModel struct {
+	Key           string    `db:"key"`
+	Failures      int       `db:"failures"`
+	LastFailureAt time.Time `db:"last_failure_at"`
+	LockedUntil   time.Time `db:"locked_until"`
+}
+
+// LoginAttemptPgsqlRepository shares counters between all instances of the service
+type LoginAttemptPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewLoginAttemptPgsqlRepository(pool *pgxpool.Pool) *LoginAttemptPgsqlRepository {
+	return &LoginAttemptPgsqlRepository{pool}
+}
+
+func (s *LoginAttemptPgsqlRepository) Get(ctx context.Context, key string) (*user.LoginAttempts, error) {
+	model := &LoginAttemptModel{}
+	if err := pgxscan.Get(
+		ctx, s.pool, model, "select key, failures, last_failure_at, locked_until from login_attempts where key = $1", key,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return &user.LoginAttempts{Key: key}, nil
+		}
+
+		return &user.LoginAttempts{}, err
+	}
+
+	return serviceLoginAttemptsFromModel(model), nil
+}
+
+// loginAttemptFailures is the counter after the failure, it starts over when the last failure is out of the window
+const loginAttemptFailures = "case when login_attempts.last_failure_at < $3 then 1 else login_attempts.failures + 1 end"
+
+// lockedUntilSQL is user.LockoutPolicy.Delay in SQL: no lock below $4 free attempts, then $5 seconds doubling with
+// every failure up to $6 seconds. A running longer lock is kept.
+func lockedUntilSQL(failures, lockedUntil string) string {
+	return fmt.Sprintf(
+		"case when %[1]s < $4 then %[2]s else greatest(%[2]s, $2 + least($5 * power(2, least(%[1]s - $4, 32)), $6) * interval '1 second') end",
+		failures, lockedUntil,
+	)
+}
+
+// Increment counts the failure and sets the lock in one upsert, the row lock of the conflict serializes
+// concurrent failures of a key
+func (s *LoginAttemptPgsqlRepository) Increment(ctx context.Context, key string, now time.Time, policy user.LockoutPolicy) (*user.LoginAttempts, error) {
+	model := &LoginAttemptModel{}
+	if err := pgxscan.Get(
+		ctx, s.pool, model, `insert into login_attempts(key, failures, last_failure_at, locked_until)
+		values($1, 1, $2, `+lockedUntilSQL("1", "to_timestamp(0)::timestamp")+`)
+		on conflict (key) do update set
+			failures = `+loginAttemptFailures+`,
+			last_failure_at = excluded.last_failure_at,
+			locked_until = `+lockedUntilSQL(loginAttemptFailures, "login_attempts.locked_until")+`
+		returning key, failures, last_failure_at, locked_until`,
+		key, now, now.Add(-policy.Window), policy.FreeAttempts, policy.BaseDelay.Seconds(), policy.MaxDelay.Seconds(),
+	); err != nil {
+		return &user.LoginAttempts{}, err
+	}
+
+	return serviceLoginAttemptsFromModel(model), nil
+}
+
+func (s *LoginAttemptPgsqlRepository) Reset(ctx context.Context, key string) error {
+	_, err := s.pool.Exec(ctx, "delete from login_attempts where key = $1", key)
+
+	return err
+}
+
+func serviceLoginAttemptsFromModel(model *LoginAttemptModel) *user.LoginAttempts {
+	return &user.LoginAttempts{
+		Key:           model.Key,
+		Failures:      model.Failures,
+		LastFailureAt: model.LastFailureAt,
+		LockedUntil:   model.LockedUntil,
+	}
+}
@@ -0,0 +1,197 @@
+package adapters
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	testAccountPolicy = user.LockoutPolicy{FreeAttempts: 3, BaseDelay: 10 * time.Second, MaxDelay: time.Minute, Window: time.Hour}
+	testIPPolicy      = user.LockoutPolicy{FreeAttempts: 5, BaseDelay: 20 * time.Second, MaxDelay: time.Minute, Window: time.Hour}
+)
+
+// assertLocked checks the slug and the Retry-After of a refused sign in, a zero wait means no refusal
+func assertLocked(t *testing.T, err error, wait time.Duration) {
+	t.Helper()
+
+	if wait == 0 {
+		if err != nil {
+			t.Fatalf("Want sign in allowed, got '%v'", err)
+		}
+		return
+	}
+
+	appError, ok := err.(appErr.AppError)
+	if !ok || appError.Slug() != "too-many-attempts" {
+		t.Fatalf("Want too-many-attempts, got '%v'", err)
+	}
+
+	if got := appError.RetryAfter(); got > wait || got < wait-time.Second {
+		t.Errorf("Want retry after '%s', got '%s'", wait, got)
+	}
+}
+
+func TestLoginThrottler_AccountLockout(t *testing.T) {
+	ctx := context.Background()
+	throttler := user.NewLoginThrottler(NewLoginAttemptMemoryRepository(time.Hour), testAccountPolicy, testIPPolicy)
+
+	for i := 0; i < 2; i++ {
+		if err := throttler.RegisterFailure(ctx, "user@example.com", "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertLocked(t, throttler.Check(ctx, "user@example.com", "10.0.0.1"), 0)
+
+	throttler.RegisterFailure(ctx, "User@Example.com ", "10.0.0.2")
+	assertLocked(t, throttler.Check(ctx, "user@example.com", "10.0.0.3"), 10*time.Second)
+
+	throttler.RegisterFailure(ctx, "user@example.com", "10.0.0.1")
+	assertLocked(t, throttler.Check(ctx, "user@example.com", "10.0.0.3"), 20*time.Second)
+
+	assertLocked(t, throttler.Check(ctx, "other@example.com", "10.0.0.1"), 0)
+
+	if err := throttler.RegisterSuccess(ctx, "user@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	assertLocked(t, throttler.Check(ctx, "user@example.com", "10.0.0.1"), 0)
+
+	failures, err := throttler.Failures(ctx, "user@example.com")
+	if err != nil || failures != 0 {
+		t.Errorf("Want the account counter cleared, got %d", failures)
+	}
+}
+
+func TestLoginThrottler_IPLockout(t *testing.T) {
+	ctx := context.Background()
+	throttler := user.NewLoginThrottler(NewLoginAttemptMemoryRepository(time.Hour), testAccountPolicy, testIPPolicy)
+
+	// every account stays below its own limit, the IP collects all failures
+	emails := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	for _, email := range emails {
+		if err := throttler.RegisterFailure(ctx, email, "10.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertLocked(t, throttler.Check(ctx, "f@example.com", "10.0.0.1"), 20*time.Second)
+	assertLocked(t, throttler.Check(ctx, "f@example.com", "10.0.0.2"), 0)
+
+	// a successful sign in into an own account does not reset the IP counter
+	throttler.RegisterSuccess(ctx, "a@example.com")
+	assertLocked(t, throttler.Check(ctx, "a@example.com", "10.0.0.1"), 20*time.Second)
+
+	// failures without a known IP only count for the account
+	throttler.RegisterFailure(ctx, "g@example.com", "")
+	assertLocked(t, throttler.Check(ctx, "g@example.com", "10.0.0.2"), 0)
+}
+
+// testLoginAttemptIncrement runs the counting and locking cases against a repository, keys are unique per run
+// so that a shared database can be used
+func testLoginAttemptIncrement(t *testing.T, r user.LoginAttemptRepository) {
+	ctx := context.Background()
+	start := time.Now().UTC().Truncate(time.Millisecond)
+
+	tt := []struct {
+		name         string
+		failuresAt   []time.Duration
+		wantFailures int
+		wantLocked   time.Duration
+	}{
+		{name: "Free attempts", failuresAt: []time.Duration{0, time.Minute}, wantFailures: 2},
+		{name: "Lock after the free attempts", failuresAt: []time.Duration{0, time.Minute, 2 * time.Minute}, wantFailures: 3, wantLocked: 2*time.Minute + 10*time.Second},
+		{name: "Lock doubles", failuresAt: []time.Duration{0, 0, 0, 0, 0}, wantFailures: 5, wantLocked: 40 * time.Second},
+		{name: "Counter starts over after the window", failuresAt: []time.Duration{0, time.Minute, 3 * time.Hour}, wantFailures: 1},
+		{
+			name:         "Running lock survives the window",
+			failuresAt:   []time.Duration{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 61 * time.Minute},
+			wantFailures: 1,
+			wantLocked:   5120 * time.Second,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			key := "account:" + uuid.NewString() + "@example.com"
+			t.Cleanup(func() { r.Reset(ctx, key) })
+
+			policy := testAccountPolicy
+			policy.MaxDelay = 2 * time.Hour
+
+			var attempts *user.LoginAttempts
+			var err error
+			for _, at := range tc.failuresAt {
+				if attempts, err = r.Increment(ctx, key, start.Add(at), policy); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if attempts.Failures != tc.wantFailures {
+				t.Errorf("Want %d failures, got %d", tc.wantFailures, attempts.Failures)
+			}
+
+			if tc.wantLocked == 0 {
+				if wait := attempts.RetryAfter(attempts.LastFailureAt); wait != 0 {
+					t.Errorf("Want no lock, got locked for '%s'", wait)
+				}
+				return
+			}
+
+			if wantLocked := start.Add(tc.wantLocked); !attempts.LockedUntil.Equal(wantLocked) {
+				t.Errorf("Want locked until '%s', got '%s'", wantLocked, attempts.LockedUntil)
+			}
+		})
+	}
+}
+
+func TestLoginAttemptMemoryRepository_Increment(t *testing.T) {
+	testLoginAttemptIncrement(t, NewLoginAttemptMemoryRepository(24*time.Hour))
+}
+
+// TestLoginAttemptPgsqlRepository_Increment needs a migrated database in TEST_POSTGRES_URL
+func TestLoginAttemptPgsqlRepository_Increment(t *testing.T) {
+	url := os.Getenv("TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("TEST_POSTGRES_URL is not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	testLoginAttemptIncrement(t, NewLoginAttemptPgsqlRepository(pool))
+}
+
+func TestLoginAttemptMemoryRepository_ConcurrentFailures(t *testing.T) {
+	ctx := context.Background()
+	r := NewLoginAttemptMemoryRepository(time.Hour)
+	throttler := user.NewLoginThrottler(r, testAccountPolicy, testIPPolicy)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			throttler.RegisterFailure(ctx, "user@example.com", "10.0.0.1")
+		}()
+	}
+	wg.Wait()
+
+	attempts, err := r.Get(ctx, "account:user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts.Failures != 20 || attempts.RetryAfter(time.Now()) < 50*time.Second {
+		t.Errorf("Want 20 failures and the longest lock, got %d failures and %s", attempts.Failures, attempts.RetryAfter(time.Now()))
+	}
+}
@@ -0,0 +1,111 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ClientModel struct {
+	ClientId   string    `db:"client_id"`
+	Name       string    `db:"name"`
+	SecretHash string    `db:"secret_hash"`
+	Scopes     []string  `db:"scopes"`
+	CreatedBy  uuid.UUID `db:"created_by"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+type ClientPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewClientPgsqlRepository(pool *pgxpool.Pool) *ClientPgsqlRepository {
+	return &ClientPgsqlRepository{pool}
+}
+
+func (s *ClientPgsqlRepository) Add(ctx context.Context, c *user.Client) error {
+	_, err := s.pool.Exec(ctx, "insert into oauth_clients(client_id, name, secret_hash, scopes, created_by, created_at) values($1,$2,$3,$4,$5,$6)",
+		c.ClientId,
+		c.Name,
+		c.SecretHash,
+		scope.ToStrings(c.Scopes),
+		c.CreatedBy,
+		c.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *ClientPgsqlRepository) FindById(ctx context.Context, clientId string) (*user.Client, error) {
+	model := &ClientModel{}
+	if err := pgxscan.Get(
+		ctx, s.pool, model, "select client_id, name, secret_hash, scopes, created_by, created_at from oauth_clients where client_id = $1", clientId,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return &user.Client{}, errors.NewNotFoundError("Client not found", "client-not-found")
+		}
+
+		return &user.Client{}, err
+	}
+
+	return serviceClientFromModel(model), nil
+}
+
+func (s *ClientPgsqlRepository) List(ctx context.Context) ([]*user.Client, error) {
+	var models []*ClientModel
+	if err := pgxscan.Select(
+		ctx, s.pool, &models, "select client_id, name, secret_hash, scopes, created_by, created_at from oauth_clients order by created_at",
+	); err != nil {
+		return []*user.Client{}, err
+	}
+
+	clients := make([]*user.Client, 0, len(models))
+	for _, model := range models {
+		clients = append(clients, serviceClientFromModel(model))
+	}
+
+	return clients, nil
+}
+
+func (s *ClientPgsqlRepository) Delete(ctx context.Context, clientId string) error {
+	tag, err := s.pool.Exec(ctx, "delete from oauth_clients where client_id = $1", clientId)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.NewNotFoundError("Client not found", "client-not-found")
+	}
+
+	return nil
+}
+
+func serviceClientFromModel(model *ClientModel) *user.Client {
+	scopes := make([]scope.Scope, 0, len(model.Scopes))
+	for _, value := range model.Scopes {
+		s, err := scope.FromString(value)
+		if err != nil {
+			continue
+		}
+
+		scopes = append(scopes, s)
+	}
+
+	return &user.Client{
+		ClientId:   model.ClientId,
+		Name:       model.Name,
+		SecretHash: model.SecretHash,
+		Scopes:     scopes,
+		CreatedBy:  model.CreatedBy,
+		CreatedAt:  model.CreatedAt,
+	}
+}
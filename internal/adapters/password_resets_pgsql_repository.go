@@ -0,0 +1,81 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PasswordResetModel struct {
+	UUID      uuid.UUID  `db:"uuid"`
+	UserUUID  uuid.UUID  `db:"user_uuid"`
+	Hash      string     `db:"hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+type PasswordResetPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPasswordResetPgsqlRepository(pool *pgxpool.Pool) *PasswordResetPgsqlRepository {
+	return &PasswordResetPgsqlRepository{pool}
+}
+
+func (s *PasswordResetPgsqlRepository) Add(ctx context.Context, r *user.PasswordReset) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "insert into password_resets(uuid, user_uuid, hash, expires_at, used_at, created_at) values($1,$2,$3,$4,$5,$6)",
+		r.UUID,
+		r.UserUUID,
+		r.Hash,
+		r.ExpiresAt,
+		r.UsedAt,
+		r.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PasswordResetPgsqlRepository) FindByHash(ctx context.Context, hash string) (*user.PasswordReset, error) {
+	model := &PasswordResetModel{}
+	if err := pgxscan.Get(
+		ctx, connectorFor(ctx, s.pool), model, "select uuid, user_uuid, hash, expires_at, used_at, created_at from password_resets where hash = $1", hash,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return &user.PasswordReset{}, errors.NewNotFoundError("Password reset not found", "password-reset-token-invalid")
+		}
+
+		return &user.PasswordReset{}, err
+	}
+
+	return &user.PasswordReset{
+		UUID:      model.UUID,
+		UserUUID:  model.UserUUID,
+		Hash:      model.Hash,
+		ExpiresAt: model.ExpiresAt,
+		UsedAt:    model.UsedAt,
+		CreatedAt: model.CreatedAt,
+	}, nil
+}
+
+// MarkUsed uses the reset once, a reset used by a concurrent request is not found
+func (s *PasswordResetPgsqlRepository) MarkUsed(ctx context.Context, uuid uuid.UUID, at time.Time) error {
+	tag, err := connectorFor(ctx, s.pool).Exec(ctx, "update password_resets set used_at = $1 where uuid = $2 and used_at is null", at, uuid)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.NewNotFoundError("Password reset not found", "password-reset-token-invalid")
+	}
+
+	return nil
+}
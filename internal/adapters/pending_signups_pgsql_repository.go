@@ -0,0 +1,75 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PendingSignUpModel struct {
+	UUID      uuid.UUID `db:"uuid"`
+	Email     string    `db:"email"`
+	Name      string    `db:"name"`
+	Hash      string    `db:"hash"`
+	TokenHash string    `db:"token_hash"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type PendingSignUpPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPendingSignUpPgsqlRepository(pool *pgxpool.Pool) *PendingSignUpPgsqlRepository {
+	return &PendingSignUpPgsqlRepository{pool}
+}
+
+func (s *PendingSignUpPgsqlRepository) Add(ctx context.Context, p *user.PendingSignUp) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "insert into pending_signups(uuid, email, name, hash, token_hash, expires_at, created_at) values($1,$2,$3,$4,$5,$6,$7)",
+		p.UUID,
+		p.Email,
+		p.Name,
+		p.Hash,
+		p.TokenHash,
+		p.ExpiresAt,
+		p.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PendingSignUpPgsqlRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*user.PendingSignUp, error) {
+	model := &PendingSignUpModel{}
+	if err := pgxscan.Get(
+		ctx, connectorFor(ctx, s.pool), model, "select uuid, email, name, hash, token_hash, expires_at, created_at from pending_signups where token_hash = $1", tokenHash,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return &user.PendingSignUp{}, errors.NewNotFoundError("Pending sign up not found", "sign-up-token-invalid")
+		}
+
+		return &user.PendingSignUp{}, err
+	}
+
+	return &user.PendingSignUp{
+		UUID:      model.UUID,
+		Email:     model.Email,
+		Name:      model.Name,
+		Hash:      model.Hash,
+		TokenHash: model.TokenHash,
+		ExpiresAt: model.ExpiresAt,
+		CreatedAt: model.CreatedAt,
+	}, nil
+}
+
+func (s *PendingSignUpPgsqlRepository) Delete(ctx context.Context, uuid uuid.UUID) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "delete from pending_signups where uuid = $1", uuid)
+	return err
+}
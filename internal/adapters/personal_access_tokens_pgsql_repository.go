@@ -0,0 +1,123 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PersonalAccessTokenModel struct {
+	UUID       uuid.UUID  `db:"uuid"`
+	UserUUID   uuid.UUID  `db:"user_uuid"`
+	Name       string     `db:"name"`
+	Hash       string     `db:"hash"`
+	Scopes     []string   `db:"scopes"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+type PersonalAccessTokenPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPersonalAccessTokenPgsqlRepository(pool *pgxpool.Pool) *PersonalAccessTokenPgsqlRepository {
+	return &PersonalAccessTokenPgsqlRepository{pool}
+}
+
+func (s *PersonalAccessTokenPgsqlRepository) Add(ctx context.Context, t *user.PersonalAccessToken) error {
+	_, err := s.pool.Exec(ctx, "insert into personal_access_tokens(uuid, user_uuid, name, hash, scopes, expires_at, last_used_at, created_at) values($1,$2,$3,$4,$5,$6,$7,$8)",
+		t.UUID,
+		t.UserUUID,
+		t.Name,
+		t.Hash,
+		scope.ToStrings(t.Scopes),
+		t.ExpiresAt,
+		t.LastUsedAt,
+		t.CreatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *PersonalAccessTokenPgsqlRepository) FindByHash(ctx context.Context, hash string) (*user.PersonalAccessToken, error) {
+	model := &PersonalAccessTokenModel{}
+	if err := pgxscan.Get(
+		ctx, s.pool, model, "select uuid, user_uuid, name, hash, scopes, expires_at, last_used_at, created_at from personal_access_tokens where hash = $1", hash,
+	); err != nil {
+		if pgxscan.NotFound(err) {
+			return &user.PersonalAccessToken{}, errors.NewNotFoundError("Token not found", "token-not-found")
+		}
+
+		return &user.PersonalAccessToken{}, err
+	}
+
+	return servicePersonalAccessTokenFromModel(model), nil
+}
+
+func (s *PersonalAccessTokenPgsqlRepository) ListForUser(ctx context.Context, userUUID uuid.UUID) ([]*user.PersonalAccessToken, error) {
+	var models []*PersonalAccessTokenModel
+	if err := pgxscan.Select(
+		ctx, s.pool, &models, "select uuid, user_uuid, name, hash, scopes, expires_at, last_used_at, created_at from personal_access_tokens where user_uuid = $1 order by created_at desc", userUUID,
+	); err != nil {
+		return []*user.PersonalAccessToken{}, err
+	}
+
+	tokens := make([]*user.PersonalAccessToken, 0, len(models))
+	for _, model := range models {
+		tokens = append(tokens, servicePersonalAccessTokenFromModel(model))
+	}
+
+	return tokens, nil
+}
+
+func (s *PersonalAccessTokenPgsqlRepository) Delete(ctx context.Context, uuid, userUUID uuid.UUID) error {
+	tag, err := s.pool.Exec(ctx, "delete from personal_access_tokens where uuid = $1 and user_uuid = $2", uuid, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.NewNotFoundError("Token not found", "token-not-found")
+	}
+
+	return nil
+}
+
+func (s *PersonalAccessTokenPgsqlRepository) TouchLastUsed(ctx context.Context, uuid uuid.UUID, at time.Time) error {
+	_, err := s.pool.Exec(ctx, "update personal_access_tokens set last_used_at = $1 where uuid = $2", at, uuid)
+
+	return err
+}
+
+func servicePersonalAccessTokenFromModel(model *PersonalAccessTokenModel) *user.PersonalAccessToken {
+	scopes := make([]scope.Scope, 0, len(model.Scopes))
+	for _, value := range model.Scopes {
+		s, err := scope.FromString(value)
+		if err != nil {
+			continue
+		}
+
+		scopes = append(scopes, s)
+	}
+
+	return &user.PersonalAccessToken{
+		UUID:       model.UUID,
+		UserUUID:   model.UserUUID,
+		Name:       model.Name,
+		Hash:       model.Hash,
+		Scopes:     scopes,
+		ExpiresAt:  model.ExpiresAt,
+		LastUsedAt: model.LastUsedAt,
+		CreatedAt:  model.CreatedAt,
+	}
+}
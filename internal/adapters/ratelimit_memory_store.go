@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
+)
+
+// RateLimitMemoryStore keeps buckets in process memory, suitable for a single instance
+type RateLimitMemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+	lastGC  time.Time
+}
+
+type memoryBucket struct {
+	ratelimit.Bucket
+	period time.Duration
+}
+
+func NewRateLimitMemoryStore() *RateLimitMemoryStore {
+	return &RateLimitMemoryStore{
+		buckets: map[string]memoryBucket{},
+		lastGC:  time.Now(),
+	}
+}
+
+func (s *RateLimitMemoryStore) Take(ctx context.Context, key string, p ratelimit.Policy, now time.Time) (ratelimit.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gc(now)
+
+	bucket, result := s.buckets[key].Take(p, now)
+	s.buckets[key] = memoryBucket{bucket, p.Period}
+
+	return result, nil
+}
+
+// gc drops buckets which are full again at most once a minute, must be called with the lock held
+func (s *RateLimitMemoryStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < time.Minute {
+		return
+	}
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.UpdatedAt) > bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastGC = now
+}
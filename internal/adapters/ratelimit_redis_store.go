@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript is ratelimit.Bucket.Take executed inside Redis so that concurrent instances share buckets,
+// times are rounded to milliseconds and the bucket expires once it is full again
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = limit
+if state[1] and state[2] then
+	local elapsed = math.max(0, now - tonumber(state[2]))
+	tokens = math.min(limit, tonumber(state[1]) + elapsed / interval)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.floor((1 - tokens) * interval + 0.5)
+end
+
+local reset = math.floor((limit - tokens) * interval + 0.5)
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], reset)
+
+return {allowed, math.floor(tokens), reset, retry}
+`)
+
+// RateLimitRedisStore keeps buckets in Redis or any server speaking its protocol with Lua scripting
+type RateLimitRedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+func NewRateLimitRedisStore(client redis.Scripter, prefix string) *RateLimitRedisStore {
+	return &RateLimitRedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *RateLimitRedisStore) Take(ctx context.Context, key string, p ratelimit.Policy, now time.Time) (ratelimit.Result, error) {
+	interval := float64(p.Period.Milliseconds()) / float64(p.Limit)
+
+	values, err := takeScript.Run(ctx, s.client, []string{s.prefix + key}, p.Limit, interval, now.UnixMilli()).Int64Slice()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return ratelimit.Result{
+		Allowed:    values[0] == 1,
+		Limit:      p.Limit,
+		Remaining:  int(values[1]),
+		Reset:      time.Duration(values[2]) * time.Millisecond,
+		RetryAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRateLimitStores(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	stores := map[string]ratelimit.Store{
+		"memory": NewRateLimitMemoryStore(),
+		"redis":  NewRateLimitRedisStore(client, "test:"),
+	}
+
+	policy := ratelimit.Policy{Limit: 2, Period: 10 * time.Second, KeyBy: ratelimit.IP}
+	start := time.UnixMilli(time.Now().UnixMilli())
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			tt := []struct {
+				key        string
+				at         time.Duration
+				allowed    bool
+				remaining  int
+				retryAfter time.Duration
+			}{
+				{key: "a", at: 0, allowed: true, remaining: 1},
+				{key: "a", at: time.Second, allowed: true, remaining: 0},
+				{key: "a", at: 2 * time.Second, allowed: false, remaining: 0, retryAfter: 3 * time.Second},
+				{key: "b", at: 2 * time.Second, allowed: true, remaining: 1},
+				{key: "a", at: 7 * time.Second, allowed: true, remaining: 0},
+			}
+
+			for _, tc := range tt {
+				result, err := store.Take(ctx, tc.key, policy, start.Add(tc.at))
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+
+				if result.Allowed != tc.allowed || result.Remaining != tc.remaining || result.RetryAfter != tc.retryAfter {
+					t.Errorf("Take %s at %s: want allowed '%t' remaining '%d' retry '%s', got '%t' '%d' '%s'",
+						tc.key, tc.at, tc.allowed, tc.remaining, tc.retryAfter, result.Allowed, result.Remaining, result.RetryAfter)
+				}
+			}
+		})
+	}
+}
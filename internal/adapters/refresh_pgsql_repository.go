@@ -11,11 +11,13 @@ import (
 )
 
 type RefreshModel struct {
-	UUID      uuid.UUID `db:"uuid"`
-	UserUUID  uuid.UUID `db:"user_uuid"`
-	Token     string    `db:"token"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	UUID     uuid.UUID `db:"uuid"`
+	UserUUID uuid.UUID `db:"user_uuid"`
+	Token    string    `db:"token"`
+	// DeviceFingerprint is empty for sessions started before fingerprints were stored
+	DeviceFingerprint string    `db:"device_fingerprint"`
+	CreatedAt         time.Time `db:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at"`
 }
 
 type RefreshPgsqlRepository struct {
@@ -26,11 +28,12 @@ func NewRefreshPgsqlRepository(pool *pgxpool.Pool) *RefreshPgsqlRepository {
 	return &RefreshPgsqlRepository{pool}
 }
 
-func (s *RefreshPgsqlRepository) Add(ctx context.Context, refresh *jwt.RefreshJWT) error {
-	_, err := s.pool.Exec(ctx, "insert into refresh_tokens(uuid, user_uuid, token, created_at, updated_at) values($1,$2,$3,$4,$5)",
+func (s *RefreshPgsqlRepository) Add(ctx context.Context, refresh *jwt.RefreshJWT, deviceFingerprint string) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "insert into refresh_tokens(uuid, user_uuid, token, device_fingerprint, created_at, updated_at) values($1,$2,$3,$4,$5,$6)",
 		refresh.Claims.UUID,
 		refresh.Claims.UserId,
 		refresh.Token,
+		deviceFingerprint,
 		time.Now(),
 		time.Now())
 
@@ -43,7 +46,7 @@ func (s *RefreshPgsqlRepository) Add(ctx context.Context, refresh *jwt.RefreshJW
 func (s *RefreshPgsqlRepository) Exists(ctx context.Context, uuid, userUUID uuid.UUID, token string) (bool, error) {
 	model := &RefreshModel{}
 	if err := pgxscan.Get(
-		ctx, s.pool, model, "select * from refresh_tokens where uuid = $1 and user_uuid = $2 and token = $3",
+		ctx, connectorFor(ctx, s.pool), model, "select * from refresh_tokens where uuid = $1 and user_uuid = $2 and token = $3",
 		uuid,
 		userUUID,
 		token,
@@ -59,7 +62,7 @@ func (s *RefreshPgsqlRepository) Exists(ctx context.Context, uuid, userUUID uuid
 }
 
 func (s *RefreshPgsqlRepository) Delete(ctx context.Context, uuid uuid.UUID) error {
-	_, err := s.pool.Exec(ctx, "delete from refresh_tokens where uuid = $1", uuid)
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "delete from refresh_tokens where uuid = $1", uuid)
 
 	if err != nil {
 		return err
@@ -69,7 +72,7 @@ func (s *RefreshPgsqlRepository) Delete(ctx context.Context, uuid uuid.UUID) err
 }
 
 func (s *RefreshPgsqlRepository) DeleteForUserUUID(ctx context.Context, userUUID uuid.UUID) error {
-	_, err := s.pool.Exec(ctx, "delete from refresh_tokens where user_uuid = $1", userUUID)
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "delete from refresh_tokens where user_uuid = $1", userUUID)
 
 	if err != nil {
 		return err
@@ -78,9 +81,30 @@ func (s *RefreshPgsqlRepository) DeleteForUserUUID(ctx context.Context, userUUID
 	return nil
 }
 
+// DeleteOthersForUserUUID deletes every session of the user but the kept one
+func (s *RefreshPgsqlRepository) DeleteOthersForUserUUID(ctx context.Context, userUUID, keepUUID uuid.UUID) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "delete from refresh_tokens where user_uuid = $1 and uuid <> $2", userUUID, keepUUID)
+	return err
+}
+
 func (s *RefreshPgsqlRepository) CountForUser(ctx context.Context, userUUID uuid.UUID) (int, error) {
 	var counter int
 
-	err := s.pool.QueryRow(ctx, "SELECT count(*) FROM refresh_tokens where user_uuid = $1", userUUID).Scan(&counter)
+	err := connectorFor(ctx, s.pool).QueryRow(ctx, "SELECT count(*) FROM refresh_tokens where user_uuid = $1", userUUID).Scan(&counter)
 	return counter, err
 }
+
+// HasDevices reports whether any session of the user was stored with a device fingerprint
+func (s *RefreshPgsqlRepository) HasDevices(ctx context.Context, userUUID uuid.UUID) (bool, error) {
+	var exists bool
+
+	err := connectorFor(ctx, s.pool).QueryRow(ctx, "select exists(select 1 from refresh_tokens where user_uuid = $1 and device_fingerprint <> '')", userUUID).Scan(&exists)
+	return exists, err
+}
+
+func (s *RefreshPgsqlRepository) HasDevice(ctx context.Context, userUUID uuid.UUID, deviceFingerprint string) (bool, error) {
+	var exists bool
+
+	err := connectorFor(ctx, s.pool).QueryRow(ctx, "select exists(select 1 from refresh_tokens where user_uuid = $1 and device_fingerprint = $2)", userUUID, deviceFingerprint).Scan(&exists)
+	return exists, err
+}
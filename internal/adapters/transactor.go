@@ -0,0 +1,53 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type txKey struct{}
+
+// PgsqlTransactor runs callbacks in a transaction carried by the context, repositories built on the same pool
+// pick it up through connectorFor, so that changes of several repositories commit together
+type PgsqlTransactor struct {
+	pool *pgxpool.Pool
+}
+
+func NewPgsqlTransactor(pool *pgxpool.Pool) *PgsqlTransactor {
+	return &PgsqlTransactor{pool}
+}
+
+// Transactional commits when the callback succeeds, nested calls join the outer transaction
+func (t *PgsqlTransactor) Transactional(ctx context.Context, cb func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return cb(ctx)
+	}
+
+	tx, err := t.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := cb(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func txFromContext(ctx context.Context) pgx.Tx {
+	tx, _ := ctx.Value(txKey{}).(pgx.Tx)
+	return tx
+}
+
+// connectorFor returns the transaction of the context or the pool
+func connectorFor(ctx context.Context, pool *pgxpool.Pool) PgxConnector {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx
+	}
+
+	return pool
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ibgl/microservice-users/internal/app/currency"
 	"github.com/ibgl/microservice-users/internal/app/day"
 	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/role"
 	"github.com/ibgl/microservice-users/internal/app/user"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -20,14 +21,18 @@ type UserModel struct {
 	Email     string            `db:"email"`
 	Name      string            `db:"name"`
 	Hash      string            `db:"hash"`
+	Role      string            `db:"role"`
 	Settings  map[string]string `db:"settings"`
 	CreatedAt time.Time         `db:"created_at"`
 	UpdatedAt time.Time         `db:"updated_at"`
+
+	ActiveHouseholdUUID *uuid.UUID `db:"active_household_uuid"`
 }
 
 type PgxConnector interface {
 	pgxscan.Querier
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
 type UserPgsqlRepository struct {
@@ -47,16 +52,16 @@ func NewUserPgsqlRepository(pool *pgxpool.Pool) *UserPgsqlRepository {
 	return &UserPgsqlRepository{pool, nil}
 }
 
-func (s *UserPgsqlRepository) connector() PgxConnector {
+func (s *UserPgsqlRepository) connector(ctx context.Context) PgxConnector {
 	if s.tx != nil {
 		return s.tx
 	}
 
-	return s.pool
+	return connectorFor(ctx, s.pool)
 }
 
 func (s *UserPgsqlRepository) exec(ctx context.Context, sql string, arguments ...any) error {
-	_, err := s.connector().Exec(ctx, sql, arguments...)
+	_, err := s.connector(ctx).Exec(ctx, sql, arguments...)
 	if err != nil {
 		return err
 	}
@@ -66,7 +71,7 @@ func (s *UserPgsqlRepository) exec(ctx context.Context, sql string, arguments ..
 
 func (s *UserPgsqlRepository) get(ctx context.Context, dst interface{}, query string, args ...interface{}) error {
 	if err := pgxscan.Get(
-		ctx, s.connector(), dst, query, args...,
+		ctx, s.connector(ctx), dst, query, args...,
 	); err != nil {
 		if pgxscan.NotFound(err) {
 			return errors.NewNotFoundError("Not found", "not-found")
@@ -79,6 +84,10 @@ func (s *UserPgsqlRepository) get(ctx context.Context, dst interface{}, query st
 }
 
 func (s *UserPgsqlRepository) Transactional(ctx context.Context, cb func(r user.UserRepository) error) error {
+	if txFromContext(ctx) != nil {
+		return cb(s)
+	}
+
 	if s.tx == nil {
 		tx, err := s.pool.Begin(ctx)
 		if err != nil {
@@ -108,7 +117,7 @@ func (s *UserPgsqlRepository) Transactional(ctx context.Context, cb func(r user.
 func (s *UserPgsqlRepository) FindById(ctx context.Context, uuid uuid.UUID) (*user.User, error) {
 	userModel := &UserModel{}
 	if err := s.get(
-		ctx, userModel, "select uuid, email, name, hash, settings, created_at, updated_at from users where uuid = $1", uuid,
+		ctx, userModel, "select uuid, email, name, hash, role, settings, active_household_uuid, created_at, updated_at from users where uuid = $1", uuid,
 	); err != nil {
 		if err.Error() == "not-found" {
 			return &user.User{}, errors.NewNotFoundError("User not found", "user-not-found")
@@ -120,10 +129,32 @@ func (s *UserPgsqlRepository) FindById(ctx context.Context, uuid uuid.UUID) (*us
 	return serviceUserFromModel(userModel)
 }
 
+// FindByIds returns the existing users among the uuids in one query, in no particular order
+func (s *UserPgsqlRepository) FindByIds(ctx context.Context, uuids []uuid.UUID) ([]*user.User, error) {
+	var models []*UserModel
+	if err := pgxscan.Select(
+		ctx, s.connector(ctx), &models, "select uuid, email, name, hash, role, settings, active_household_uuid, created_at, updated_at from users where uuid = any($1)", uuids,
+	); err != nil {
+		return []*user.User{}, err
+	}
+
+	users := make([]*user.User, 0, len(models))
+	for _, model := range models {
+		u, err := serviceUserFromModel(model)
+		if err != nil {
+			return []*user.User{}, err
+		}
+
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
 func (s *UserPgsqlRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
 	userModel := &UserModel{}
 	if err := s.get(
-		ctx, userModel, "select uuid, email, name, hash, settings, created_at, updated_at from users where email = $1", email,
+		ctx, userModel, "select uuid, email, name, hash, role, settings, active_household_uuid, created_at, updated_at from users where email = $1", email,
 	); err != nil {
 		if err.Error() == "not-found" {
 			return &user.User{}, errors.NewNotFoundError("User not found", "user-not-found")
@@ -136,7 +167,7 @@ func (s *UserPgsqlRepository) FindByEmail(ctx context.Context, email string) (*u
 }
 
 func (s *UserPgsqlRepository) Add(ctx context.Context, u *user.User) error {
-	err := s.exec(ctx, "insert into users(uuid, email, name, hash, settings, created_at, updated_at) values($1,$2,$3,$4,$5,$6, $7)", u.UUID, u.Email, u.Name, u.Hash, UserSettingsToMap(u.Settings), u.CreatedAt, u.UpdatedAt)
+	err := s.exec(ctx, "insert into users(uuid, email, name, hash, role, settings, created_at, updated_at) values($1,$2,$3,$4,$5,$6,$7,$8)", u.UUID, u.Email, u.Name, u.Hash, u.Role.String(), UserSettingsToMap(u.Settings), u.CreatedAt, u.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -153,6 +184,19 @@ func (s *UserPgsqlRepository) UpdateSettings(ctx context.Context, user_uuid uuid
 	return s.FindById(ctx, user_uuid)
 }
 
+func (s *UserPgsqlRepository) SetActiveHousehold(ctx context.Context, userUUID, householdUUID uuid.UUID) error {
+	var household *uuid.UUID
+	if householdUUID != uuid.Nil {
+		household = &householdUUID
+	}
+
+	return s.exec(ctx, "update users set active_household_uuid = $1 where uuid = $2", household, userUUID)
+}
+
+func (s *UserPgsqlRepository) UpdateHash(ctx context.Context, userUUID uuid.UUID, hash string) error {
+	return s.exec(ctx, "update users set hash = $1, updated_at = $2 where uuid = $3", hash, time.Now(), userUUID)
+}
+
 func serviceUserFromModel(model *UserModel) (*user.User, error) {
 	cur, err := currency.FromString(model.Settings["currency"])
 	if err != nil {
@@ -164,13 +208,25 @@ func serviceUserFromModel(model *UserModel) (*user.User, error) {
 		FirstDayOfWeek = day.MON
 	}
 
-	return user.NewUser(
+	r, err := role.FromString(model.Role)
+	if err != nil {
+		r = role.USER
+	}
+
+	u := user.NewUser(
 		model.UUID,
 		model.Email,
 		model.Name,
 		model.Hash,
+		r,
 		user.NewUserSettings(cur, FirstDayOfWeek, model.Settings["profile_picture_url"]),
 		model.CreatedAt,
 		model.UpdatedAt,
-	), nil
+	)
+
+	if model.ActiveHouseholdUUID != nil {
+		u.ActiveHouseholdUUID = *model.ActiveHouseholdUUID
+	}
+
+	return u, nil
 }
@@ -0,0 +1,149 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/webhook"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookDeliveryModel struct {
+	UUID             uuid.UUID  `db:"uuid"`
+	SubscriptionUUID uuid.UUID  `db:"subscription_uuid"`
+	EventUUID        uuid.UUID  `db:"event_uuid"`
+	EventType        string     `db:"event_type"`
+	Payload          []byte     `db:"payload"`
+	Status           string     `db:"status"`
+	Attempts         int        `db:"attempts"`
+	NextAttemptAt    time.Time  `db:"next_attempt_at"`
+	ResponseCode     int        `db:"response_code"`
+	ResponseBody     string     `db:"response_body"`
+	LastError        string     `db:"last_error"`
+	RedeliveryOf     *uuid.UUID `db:"redelivery_of"`
+	CreatedAt        time.Time  `db:"created_at"`
+	DeliveredAt      *time.Time `db:"delivered_at"`
+}
+
+type WebhookDeliveryPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookDeliveryPgsqlRepository(pool *pgxpool.Pool) *WebhookDeliveryPgsqlRepository {
+	return &WebhookDeliveryPgsqlRepository{pool}
+}
+
+func (s *WebhookDeliveryPgsqlRepository) Add(ctx context.Context, d *webhook.Delivery) error {
+	var redeliveryOf *uuid.UUID
+	if d.RedeliveryOf != uuid.Nil {
+		redeliveryOf = &d.RedeliveryOf
+	}
+
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, `insert into webhook_deliveries(uuid, subscription_uuid, event_uuid, event_type, payload, status, attempts, next_attempt_at, redelivery_of, created_at)
+		values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		on conflict (subscription_uuid, event_uuid) where redelivery_of is null do nothing`,
+		d.UUID,
+		d.SubscriptionUUID,
+		d.EventUUID,
+		d.EventType.String(),
+		d.Payload,
+		d.Status.String(),
+		d.Attempts,
+		d.NextAttemptAt,
+		redeliveryOf,
+		d.CreatedAt)
+
+	return err
+}
+
+func (s *WebhookDeliveryPgsqlRepository) FindById(ctx context.Context, uuid uuid.UUID) (*webhook.Delivery, error) {
+	model := &WebhookDeliveryModel{}
+	if err := pgxscan.Get(ctx, s.pool, model, "select * from webhook_deliveries where uuid = $1", uuid); err != nil {
+		if pgxscan.NotFound(err) {
+			return &webhook.Delivery{}, errors.NewNotFoundError("Delivery not found", "webhook-delivery-not-found")
+		}
+
+		return &webhook.Delivery{}, err
+	}
+
+	return webhookDeliveryFromModel(model)
+}
+
+func (s *WebhookDeliveryPgsqlRepository) ListForSubscription(ctx context.Context, subscriptionUUID uuid.UUID, limit int) ([]*webhook.Delivery, error) {
+	return s.selectDeliveries(ctx, "select * from webhook_deliveries where subscription_uuid = $1 order by created_at desc limit $2",
+		subscriptionUUID, limit)
+}
+
+func (s *WebhookDeliveryPgsqlRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*webhook.Delivery, error) {
+	return s.selectDeliveries(ctx,
+		`update webhook_deliveries set next_attempt_at = $2 where uuid in (
+			select uuid from webhook_deliveries where status = $3 and next_attempt_at <= $1
+			order by next_attempt_at limit $4 for update skip locked
+		) returning *`,
+		now, now.Add(lease), webhook.PENDING.String(), limit,
+	)
+}
+
+func (s *WebhookDeliveryPgsqlRepository) Save(ctx context.Context, d *webhook.Delivery) error {
+	_, err := connectorFor(ctx, s.pool).Exec(ctx, "update webhook_deliveries set status = $1, attempts = $2, next_attempt_at = $3, response_code = $4, response_body = $5, last_error = $6, delivered_at = $7 where uuid = $8",
+		d.Status.String(), d.Attempts, d.NextAttemptAt, d.ResponseCode, d.ResponseBody, d.LastError, d.DeliveredAt, d.UUID)
+
+	return err
+}
+
+func (s *WebhookDeliveryPgsqlRepository) selectDeliveries(ctx context.Context, query string, args ...interface{}) ([]*webhook.Delivery, error) {
+	models := []*WebhookDeliveryModel{}
+	if err := pgxscan.Select(ctx, connectorFor(ctx, s.pool), &models, query, args...); err != nil {
+		return []*webhook.Delivery{}, err
+	}
+
+	deliveries := make([]*webhook.Delivery, 0, len(models))
+	for _, model := range models {
+		d, err := webhookDeliveryFromModel(model)
+		if err != nil {
+			return []*webhook.Delivery{}, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+func webhookDeliveryFromModel(model *WebhookDeliveryModel) (*webhook.Delivery, error) {
+	status, err := webhook.StatusFromString(model.Status)
+	if err != nil {
+		return &webhook.Delivery{}, err
+	}
+
+	eventType, err := event.FromString(model.EventType)
+	if err != nil {
+		return &webhook.Delivery{}, err
+	}
+
+	d := &webhook.Delivery{
+		UUID:             model.UUID,
+		SubscriptionUUID: model.SubscriptionUUID,
+		EventUUID:        model.EventUUID,
+		EventType:        eventType,
+		Payload:          model.Payload,
+		Status:           status,
+		Attempts:         model.Attempts,
+		NextAttemptAt:    model.NextAttemptAt,
+		ResponseCode:     model.ResponseCode,
+		ResponseBody:     model.ResponseBody,
+		LastError:        model.LastError,
+		CreatedAt:        model.CreatedAt,
+		DeliveredAt:      model.DeliveredAt,
+	}
+
+	if model.RedeliveryOf != nil {
+		d.RedeliveryOf = *model.RedeliveryOf
+	}
+
+	return d, nil
+}
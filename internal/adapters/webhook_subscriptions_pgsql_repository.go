@@ -0,0 +1,140 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/webhook"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookSubscriptionModel struct {
+	UUID      uuid.UUID `db:"uuid"`
+	URL       string    `db:"url"`
+	Secret    string    `db:"secret"`
+	Events    []string  `db:"events"`
+	Active    bool      `db:"active"`
+	CreatedBy uuid.UUID `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type WebhookSubscriptionPgsqlRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookSubscriptionPgsqlRepository(pool *pgxpool.Pool) *WebhookSubscriptionPgsqlRepository {
+	return &WebhookSubscriptionPgsqlRepository{pool}
+}
+
+func (s *WebhookSubscriptionPgsqlRepository) Add(ctx context.Context, w *webhook.Subscription) error {
+	_, err := s.pool.Exec(ctx, "insert into webhook_subscriptions(uuid, url, secret, events, active, created_by, created_at, updated_at) values($1,$2,$3,$4,$5,$6,$7,$8)",
+		w.UUID,
+		w.URL,
+		w.Secret,
+		eventTypesToStrings(w.Events),
+		w.Active,
+		w.CreatedBy,
+		w.CreatedAt,
+		w.UpdatedAt)
+
+	return err
+}
+
+func (s *WebhookSubscriptionPgsqlRepository) FindById(ctx context.Context, uuid uuid.UUID) (*webhook.Subscription, error) {
+	model := &WebhookSubscriptionModel{}
+	if err := pgxscan.Get(ctx, s.pool, model, "select * from webhook_subscriptions where uuid = $1", uuid); err != nil {
+		if pgxscan.NotFound(err) {
+			return &webhook.Subscription{}, errors.NewNotFoundError("Webhook not found", "webhook-not-found")
+		}
+
+		return &webhook.Subscription{}, err
+	}
+
+	return webhookSubscriptionFromModel(model), nil
+}
+
+func (s *WebhookSubscriptionPgsqlRepository) List(ctx context.Context) ([]*webhook.Subscription, error) {
+	return s.list(ctx, "select * from webhook_subscriptions order by created_at")
+}
+
+func (s *WebhookSubscriptionPgsqlRepository) ListActive(ctx context.Context) ([]*webhook.Subscription, error) {
+	return s.list(ctx, "select * from webhook_subscriptions where active order by created_at")
+}
+
+func (s *WebhookSubscriptionPgsqlRepository) list(ctx context.Context, query string) ([]*webhook.Subscription, error) {
+	var models []*WebhookSubscriptionModel
+	if err := pgxscan.Select(ctx, s.pool, &models, query); err != nil {
+		return []*webhook.Subscription{}, err
+	}
+
+	subscriptions := make([]*webhook.Subscription, 0, len(models))
+	for _, model := range models {
+		subscriptions = append(subscriptions, webhookSubscriptionFromModel(model))
+	}
+
+	return subscriptions, nil
+}
+
+func (s *WebhookSubscriptionPgsqlRepository) Update(ctx context.Context, w *webhook.Subscription) error {
+	tag, err := s.pool.Exec(ctx, "update webhook_subscriptions set url = $1, events = $2, active = $3, updated_at = $4 where uuid = $5",
+		w.URL, eventTypesToStrings(w.Events), w.Active, w.UpdatedAt, w.UUID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.NewNotFoundError("Webhook not found", "webhook-not-found")
+	}
+
+	return nil
+}
+
+func (s *WebhookSubscriptionPgsqlRepository) Delete(ctx context.Context, uuid uuid.UUID) error {
+	tag, err := s.pool.Exec(ctx, "delete from webhook_subscriptions where uuid = $1", uuid)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.NewNotFoundError("Webhook not found", "webhook-not-found")
+	}
+
+	return nil
+}
+
+func eventTypesToStrings(types []event.Type) []string {
+	values := make([]string, 0, len(types))
+	for _, t := range types {
+		values = append(values, t.String())
+	}
+
+	return values
+}
+
+func webhookSubscriptionFromModel(model *WebhookSubscriptionModel) *webhook.Subscription {
+	events := make([]event.Type, 0, len(model.Events))
+	for _, value := range model.Events {
+		t, err := event.FromString(value)
+		if err != nil {
+			continue
+		}
+
+		events = append(events, t)
+	}
+
+	return &webhook.Subscription{
+		UUID:      model.UUID,
+		URL:       model.URL,
+		Secret:    model.Secret,
+		Events:    events,
+		Active:    model.Active,
+		CreatedBy: model.CreatedBy,
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}
+}
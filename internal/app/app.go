@@ -1,26 +1,56 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/ibgl/microservice-users/internal/adapters"
+	"github.com/ibgl/microservice-users/internal/app/captcha"
+	"github.com/ibgl/microservice-users/internal/app/email"
+	"github.com/ibgl/microservice-users/internal/app/emaildomain"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/household"
 	"github.com/ibgl/microservice-users/internal/app/jwt"
+	"github.com/ibgl/microservice-users/internal/app/password"
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
 	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/ibgl/microservice-users/internal/app/webhook"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
+	"github.com/twmb/franz-go/pkg/kgo"
 )
 
 type App struct {
-	authService user.UserAuthManager
-	logger      *logrus.Logger
-	config      *Config
+	authService      user.UserAuthManager
+	householdService household.HouseholdManager
+	webhookService   webhook.WebhookManager
+	rateLimiter      *ratelimit.Limiter
+	passwordPool     *password.Pool
+	logger           *logrus.Logger
+	config           *Config
 }
 
 type Application interface {
 	GetConfig() *Config
 	GetLogger() *logrus.Logger
 	GetAuthService() user.UserAuthManager
+	GetHouseholdService() household.HouseholdManager
+	GetWebhookService() webhook.WebhookManager
+	GetRateLimiter() *ratelimit.Limiter
+	GetPasswordPool() *password.Pool
 	SetConfig(config *Config) Application
 	SetLogger(logger *logrus.Logger) Application
 	SetAuthService(authService user.UserAuthManager) Application
+	SetHouseholdService(householdService household.HouseholdManager) Application
+	SetWebhookService(webhookService webhook.WebhookManager) Application
+	SetRateLimiter(rateLimiter *ratelimit.Limiter) Application
+	SetPasswordPool(passwordPool *password.Pool) Application
 }
 
 func (h *App) GetConfig() *Config {
@@ -35,6 +65,22 @@ func (h *App) GetAuthService() user.UserAuthManager {
 	return h.authService
 }
 
+func (h *App) GetHouseholdService() household.HouseholdManager {
+	return h.householdService
+}
+
+func (h *App) GetWebhookService() webhook.WebhookManager {
+	return h.webhookService
+}
+
+func (h *App) GetRateLimiter() *ratelimit.Limiter {
+	return h.rateLimiter
+}
+
+func (h *App) GetPasswordPool() *password.Pool {
+	return h.passwordPool
+}
+
 func (h *App) SetConfig(config *Config) Application {
 	h.config = config
 	return h
@@ -50,33 +96,506 @@ func (h *App) SetAuthService(authService user.UserAuthManager) Application {
 	return h
 }
 
+func (h *App) SetHouseholdService(householdService household.HouseholdManager) Application {
+	h.householdService = householdService
+	return h
+}
+
+func (h *App) SetWebhookService(webhookService webhook.WebhookManager) Application {
+	h.webhookService = webhookService
+	return h
+}
+
+func (h *App) SetRateLimiter(rateLimiter *ratelimit.Limiter) Application {
+	h.rateLimiter = rateLimiter
+	return h
+}
+
+func (h *App) SetPasswordPool(passwordPool *password.Pool) Application {
+	h.passwordPool = passwordPool
+	return h
+}
+
 type Config struct {
-	JwtSecret       string
-	JwtAccessTTL    int
-	JwtRefreshTTL   int
-	MaxUserSessions int
-	GoogleKey       string
+	JwtSecret           string
+	JwtAccessTTL        int
+	JwtRefreshTTL       int
+	JwtImpersonationTTL int
+	MaxUserSessions     int
+	GoogleKey           string
+	FrontendUrl         string
+	MailSender          string
+	MailHost            string
+	MailPort            string
+	MailPassword        string
+	MailFromName        string
+	MailReplyTo         string
+	MailTemplatesDir    string
+	MailDefaultLocale   string
+	MailTransport       string
+	MailFileDir         string
+	MailSinkAddr        string
+	LoginAttemptsStore  string
+	SignUpMode          string
+	RateLimitStore      string
+	RateLimits          string
+	RedisUrl            string
+
+	MailOutboxInterval    int
+	MailOutboxMaxAttempts int
+
+	MailSmtpSecurity   string
+	MailSmtpPoolSize   int
+	MailSendmailPath   string
+	MailHttpEndpoint   string
+	MailHttpApiKey     string
+	MailHttpAuthHeader string
+
+	EventsPublisher     string
+	EventsRelayInterval int
+	EventsSource        string
+	EventsNatsUrl       string
+	EventsNatsSubject   string
+	EventsNatsStream    string
+	EventsKafkaBrokers  string
+	EventsKafkaTopic    string
+
+	WebhooksAllowHttp        bool
+	WebhooksDispatchInterval int
+	WebhooksMaxAttempts      int
+
+	PasswordMinLength         int
+	PasswordMinClasses        int
+	PasswordMinScore          int
+	BreachedPasswordsFile     string
+	BreachedPasswordsMinCount int
+
+	PasswordHasher    string
+	BcryptCost        int
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+
+	HashingWorkers int
+	HashingQueue   int
+	HashingMaxWait int
+
+	CaptchaProvider    string
+	CaptchaSecret      string
+	CaptchaVerifyUrl   string
+	CaptchaSignInAfter int
+
+	EmailDomainsAllow     string
+	EmailDomainsDeny      string
+	DisposableDomainsFile string
+
+	SessionMode           string
+	SessionCookieDomain   string
+	SessionCookiePath     string
+	SessionCookieSameSite string
+	SessionCookieInsecure bool
+
+	TrustedProxies string
+
+	CorsAllowedOrigins   string
+	CorsAllowedMethods   string
+	CorsAllowedHeaders   string
+	CorsAllowCredentials bool
+	CorsMaxAge           int
 }
 
+const (
+	LoginAttemptsStoreMemory   = "memory"
+	LoginAttemptsStorePostgres = "postgres"
+
+	SignUpModeDirect  = "direct"
+	SignUpModeConfirm = "confirm"
+
+	SessionModeToken  = "token"
+	SessionModeCookie = "cookie"
+
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+
+	MailTransportSMTP     = "smtp"
+	MailTransportFile     = "file"
+	MailTransportSink     = "sink"
+	MailTransportSendmail = "sendmail"
+	MailTransportHTTP     = "http"
+
+	EventsPublisherNone  = "none"
+	EventsPublisherLog   = "log"
+	EventsPublisherNats  = "nats"
+	EventsPublisherKafka = "kafka"
+)
+
+// NewApplication builds the services from the config, the background workers (outbox dispatchers, event relay
+// and disposable domains reload) run until the context is done
 func NewApplication(
+	ctx context.Context,
 	config *Config,
 	logger *logrus.Logger,
 	dbPool *pgxpool.Pool,
 ) (Application, error) {
 	app := &App{}
 	jwtService := jwt.NewJwtService(&jwt.JWTConfig{
-		Secret:     config.JwtSecret,
-		AccessTTL:  config.JwtAccessTTL,
-		RefreshTTL: config.JwtRefreshTTL,
+		Secret:           config.JwtSecret,
+		AccessTTL:        config.JwtAccessTTL,
+		RefreshTTL:       config.JwtRefreshTTL,
+		ImpersonationTTL: config.JwtImpersonationTTL,
 	})
 
+	userRepository := adapters.NewUserPgsqlRepository(dbPool)
+	transactor := adapters.NewPgsqlTransactor(dbPool)
+	outbox := adapters.NewEmailOutboxPgsqlRepository(dbPool)
+	mailer, err := newMailer(config, outbox)
+	if err != nil {
+		return nil, err
+	}
+
+	mailTransport, err := newMailTransport(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	dispatchPolicy := email.DefaultDispatchPolicy
+	dispatchPolicy.Interval = time.Duration(config.MailOutboxInterval) * time.Millisecond
+	dispatchPolicy.MaxAttempts = config.MailOutboxMaxAttempts
+	emailDispatcher := email.NewDispatcher(outbox, mailTransport, dispatchPolicy)
+
+	events := adapters.NewDomainEventsPgsqlRepository(dbPool)
+	eventPublisher, err := newEventPublisher(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := adapters.NewWebhookSubscriptionPgsqlRepository(dbPool)
+	webhookDeliveries := adapters.NewWebhookDeliveryPgsqlRepository(dbPool)
+	webhookService := webhook.NewService(webhooks, webhookDeliveries, userRepository, config.EventsSource, config.WebhooksAllowHttp)
+
+	deliveryPolicy := webhook.DefaultDeliveryPolicy
+	deliveryPolicy.Interval = time.Duration(config.WebhooksDispatchInterval) * time.Millisecond
+	deliveryPolicy.MaxAttempts = config.WebhooksMaxAttempts
+	webhookDispatcher := webhook.NewDispatcher(webhookDeliveries, webhooks, deliveryPolicy)
+
+	// webhooks go first, they are queued locally and a retry after a bus failure does not queue them twice
+	publishers := event.Fanout{webhookService}
+	if eventPublisher != nil {
+		publishers = append(publishers, eventPublisher)
+	}
+
+	relayPolicy := event.DefaultRelayPolicy
+	relayPolicy.Interval = time.Duration(config.EventsRelayInterval) * time.Millisecond
+	eventRelay := event.NewRelay(events, publishers, relayPolicy)
+
+	var loginAttempts user.LoginAttemptRepository
+	switch config.LoginAttemptsStore {
+	case LoginAttemptsStoreMemory:
+		loginAttempts = adapters.NewLoginAttemptMemoryRepository(user.DefaultAccountLockoutPolicy.Window)
+	case LoginAttemptsStorePostgres, "":
+		loginAttempts = adapters.NewLoginAttemptPgsqlRepository(dbPool)
+	default:
+		return nil, fmt.Errorf("unknown login attempts store %s", config.LoginAttemptsStore)
+	}
+
+	switch config.SignUpMode {
+	case SignUpModeDirect, SignUpModeConfirm, "":
+	default:
+		return nil, fmt.Errorf("unknown sign up mode %s", config.SignUpMode)
+	}
+
+	switch config.SessionMode {
+	case SessionModeToken, SessionModeCookie, "":
+	default:
+		return nil, fmt.Errorf("unknown session mode %s", config.SessionMode)
+	}
+
+	if config.SessionMode == SessionModeCookie {
+		origins := SplitList(config.CorsAllowedOrigins)
+		if len(origins) == 0 {
+			return nil, fmt.Errorf("cookie sessions need the CORS origins listed, set CORS_ALLOWED_ORIGINS")
+		}
+		for _, origin := range origins {
+			if origin == "*" {
+				return nil, fmt.Errorf("cookie sessions can not be allowed for any origin, list the CORS origins")
+			}
+		}
+	}
+
+	if config.CorsAllowCredentials {
+		for _, origin := range SplitList(config.CorsAllowedOrigins) {
+			if origin == "*" {
+				return nil, fmt.Errorf("CORS credentials can not be allowed for any origin, list the origins")
+			}
+		}
+	}
+
+	passwordPolicy, err := newPasswordPolicy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	hashers, err := password.NewHashers(config.PasswordHasher, config.BcryptCost, password.Argon2Params{
+		Memory:      uint32(config.Argon2Memory),
+		Iterations:  uint32(config.Argon2Iterations),
+		Parallelism: uint8(config.Argon2Parallelism),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPool := password.NewPool(
+		hashers,
+		config.HashingWorkers,
+		config.HashingQueue,
+		time.Duration(config.HashingMaxWait)*time.Millisecond,
+	)
+
+	captchaVerifier, err := newCaptchaVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+
+	emailDomainFilter, err := emaildomain.NewFilter(
+		SplitList(config.EmailDomainsAllow),
+		SplitList(config.EmailDomainsDeny),
+		config.DisposableDomainsFile,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("disposable domains file: %w", err)
+	}
+
 	authService := user.NewAuthService(
-		adapters.NewUserPgsqlRepository(dbPool),
+		userRepository,
 		jwtService,
 		adapters.NewRefreshPgsqlRepository(dbPool),
-		config.MaxUserSessions,
-		config.GoogleKey,
+		adapters.NewImpersonationPgsqlRepository(dbPool),
+		adapters.NewPersonalAccessTokenPgsqlRepository(dbPool),
+		adapters.NewClientPgsqlRepository(dbPool),
+		adapters.NewPasswordResetPgsqlRepository(dbPool),
+		adapters.NewPendingSignUpPgsqlRepository(dbPool),
+		adapters.NewAuthEventPgsqlRepository(dbPool),
+		&user.AuthServiceConfig{
+			LoginThrottler:       user.NewLoginThrottler(loginAttempts, user.DefaultAccountLockoutPolicy, user.DefaultIPLockoutPolicy),
+			PasswordHasher:       passwordPool,
+			PasswordPolicy:       passwordPolicy,
+			CaptchaVerifier:      captchaVerifier,
+			CaptchaAfterFailures: config.CaptchaSignInAfter,
+			EmailDomainFilter:    emailDomainFilter,
+			Transactor:           transactor,
+			Mailer:               mailer,
+			Events:               events,
+			MaxUserSessions:      config.MaxUserSessions,
+			GoogleKey:            config.GoogleKey,
+			FrontendUrl:          config.FrontendUrl,
+		},
+	)
+
+	householdService := household.NewService(
+		adapters.NewHouseholdPgsqlRepository(dbPool),
+		userRepository,
+		transactor,
+		mailer,
+		household.DefaultInvitationTTL,
+		config.FrontendUrl,
 	)
 
-	return app.SetAuthService(authService).SetConfig(config).SetLogger(logger), nil
+	rateLimiter, err := newRateLimiter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// started once the whole config is valid, a failed start leaves nothing running
+	go emailDispatcher.Run(ctx, func(err error) {
+		logger.Errorf("Email dispatch error %v", err)
+	})
+	go webhookDispatcher.Run(ctx, func(err error) {
+		logger.Errorf("Webhook dispatch error %v", err)
+	})
+	go eventRelay.Run(ctx, func(err error) {
+		logger.Errorf("Event relay error %v", err)
+	})
+	go emailDomainFilter.Watch(ctx, time.Minute, func(err error) {
+		logger.Errorf("Disposable domains reload error %v", err)
+	})
+
+	return app.SetAuthService(authService).
+		SetHouseholdService(householdService).
+		SetWebhookService(webhookService).
+		SetRateLimiter(rateLimiter).
+		SetPasswordPool(passwordPool).
+		SetConfig(config).
+		SetLogger(logger), nil
+}
+
+func newPasswordPolicy(config *Config) (password.Policy, error) {
+	policy := password.DefaultPolicy
+	policy.MinLength = config.PasswordMinLength
+	policy.MinClasses = config.PasswordMinClasses
+	policy.MinScore = config.PasswordMinScore
+
+	if config.BreachedPasswordsFile != "" {
+		checker, err := password.NewHashFileChecker(config.BreachedPasswordsFile, config.BreachedPasswordsMinCount)
+		if err != nil {
+			return password.Policy{}, fmt.Errorf("breached passwords file: %w", err)
+		}
+
+		policy.Breached = checker
+	}
+
+	return policy, nil
+}
+
+// SplitList splits a comma separated config value, blank items are dropped
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// newCaptchaVerifier returns nil when no provider is configured, captchas are not asked for then
+func newCaptchaVerifier(config *Config) (user.CaptchaVerifier, error) {
+	if config.CaptchaProvider == "" {
+		return nil, nil
+	}
+
+	provider, err := captcha.ProviderFromString(config.CaptchaProvider)
+	if err != nil {
+		return nil, fmt.Errorf("unknown captcha provider %s", config.CaptchaProvider)
+	}
+
+	return captcha.NewVerifier(provider, config.CaptchaSecret, config.CaptchaVerifyUrl), nil
+}
+
+func newRateLimiter(config *Config) (*ratelimit.Limiter, error) {
+	policies, err := ratelimit.ParsePolicies(config.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	var store ratelimit.Store
+	switch config.RateLimitStore {
+	case RateLimitStoreMemory, "":
+		store = adapters.NewRateLimitMemoryStore()
+	case RateLimitStoreRedis:
+		options, err := redis.ParseURL(config.RedisUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis url: %w", err)
+		}
+
+		store = adapters.NewRateLimitRedisStore(redis.NewClient(options), "users:ratelimit:")
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %s", config.RateLimitStore)
+	}
+
+	return ratelimit.NewLimiter(store, policies), nil
+}
+
+// newMailer renders the shipped email templates, or those of MailTemplatesDir, into the outbox
+func newMailer(config *Config, outbox email.OutboxRepository) (*email.Mailer, error) {
+	templates := email.DefaultTemplates()
+	if config.MailTemplatesDir != "" {
+		templates = os.DirFS(config.MailTemplatesDir)
+	}
+
+	renderer, err := email.NewRenderer(templates, config.MailDefaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("email templates: %w", err)
+	}
+
+	return email.NewMailer(renderer, outbox, email.Address(config.MailFromName, config.MailSender), config.MailReplyTo), nil
+}
+
+// newMailTransport delivers the outbox over SMTP, through the local sendmail, to the send endpoint of an email
+// API, into .eml files of MailFileDir or, for local development, to an in-process SMTP sink which keeps the
+// messages in MailFileDir
+func newMailTransport(config *Config, logger *logrus.Logger) (email.Transport, error) {
+	switch config.MailTransport {
+	case MailTransportSMTP, "":
+		return email.NewSMTPTransport(email.SMTPConfig{
+			Host:     config.MailHost,
+			Port:     config.MailPort,
+			Username: config.MailSender,
+			Password: config.MailPassword,
+			Security: config.MailSmtpSecurity,
+			PoolSize: config.MailSmtpPoolSize,
+		}), nil
+	case MailTransportSendmail:
+		return email.NewSendmailTransport(config.MailSendmailPath), nil
+	case MailTransportHTTP:
+		if config.MailHttpEndpoint == "" {
+			return nil, fmt.Errorf("mail transport %s needs an endpoint", MailTransportHTTP)
+		}
+
+		return email.NewHTTPTransport(email.HTTPConfig{
+			Endpoint:   config.MailHttpEndpoint,
+			APIKey:     config.MailHttpApiKey,
+			AuthHeader: config.MailHttpAuthHeader,
+		}), nil
+	case MailTransportFile:
+		return email.NewFileTransport(config.MailFileDir)
+	case MailTransportSink:
+		sink, err := email.NewSink(config.MailSinkAddr, config.MailFileDir)
+		if err != nil {
+			return nil, fmt.Errorf("mail sink: %w", err)
+		}
+
+		host, port, err := net.SplitHostPort(sink.Addr())
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Infof("Mail sink listening on %s", sink.Addr())
+		return email.NewSMTPTransport(email.SMTPConfig{
+			Host:     host,
+			Port:     port,
+			Security: email.SMTPSecurityNone,
+			PoolSize: config.MailSmtpPoolSize,
+		}), nil
+	}
+
+	return nil, fmt.Errorf("unknown mail transport %s", config.MailTransport)
+}
+
+// newEventPublisher publishes to NATS JetStream, Kafka or the log. It is nil for EventsPublisherNone, the events
+// then only go to the webhooks.
+func newEventPublisher(config *Config, logger *logrus.Logger) (event.Publisher, error) {
+	switch config.EventsPublisher {
+	case EventsPublisherNone, "":
+		return nil, nil
+	case EventsPublisherLog:
+		return event.NewLogPublisher(logger.Infof), nil
+	case EventsPublisherNats:
+		nc, err := nats.Connect(config.EventsNatsUrl, nats.MaxReconnects(-1))
+		if err != nil {
+			return nil, fmt.Errorf("nats: %w", err)
+		}
+
+		js, err := nc.JetStream()
+		if err != nil {
+			return nil, fmt.Errorf("nats: %w", err)
+		}
+
+		if config.EventsNatsStream != "" {
+			if err := adapters.EnsureNatsStream(js, config.EventsNatsStream, config.EventsNatsSubject); err != nil {
+				return nil, fmt.Errorf("nats stream: %w", err)
+			}
+		}
+
+		return adapters.NewEventNatsPublisher(js, config.EventsNatsSubject, config.EventsSource), nil
+	case EventsPublisherKafka:
+		client, err := kgo.NewClient(kgo.SeedBrokers(strings.Split(config.EventsKafkaBrokers, ",")...))
+		if err != nil {
+			return nil, fmt.Errorf("kafka: %w", err)
+		}
+
+		return adapters.NewEventKafkaPublisher(client, config.EventsKafkaTopic, config.EventsSource), nil
+	}
+
+	return nil, fmt.Errorf("unknown events publisher %s", config.EventsPublisher)
 }
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type struct {
+	v string
+}
+
+var (
+	UNKNOWN               = Type{"UNKNOWN"}
+	SIGN_IN               = Type{"sign-in"}
+	SIGN_IN_FAILED        = Type{"sign-in-failed"}
+	SIGN_UP               = Type{"sign-up"}
+	GOOGLE_SIGN_IN        = Type{"google-sign-in"}
+	REFRESH               = Type{"refresh"}
+	SIGN_OUT              = Type{"sign-out"}
+	SETTINGS_UPDATED      = Type{"settings-updated"}
+	PASSWORD_CHANGED      = Type{"password-changed"}
+	PASSWORD_RESET        = Type{"password-reset"}
+	TOKEN_REVOKED         = Type{"token-revoked"}
+	IMPERSONATION_STARTED = Type{"impersonation-started"}
+	SIGN_IN_REPORTED      = Type{"sign-in-reported"}
+)
+
+func (c Type) String() string {
+	return c.v
+}
+
+func FromString(value string) (Type, error) {
+	for _, t := range All() {
+		if t.String() == value {
+			return t, nil
+		}
+	}
+
+	return UNKNOWN, errors.New("Invalid auth event type value")
+}
+
+func All() []Type {
+	return []Type{
+		SIGN_IN, SIGN_IN_FAILED, SIGN_UP, GOOGLE_SIGN_IN, REFRESH, SIGN_OUT,
+		SETTINGS_UPDATED, PASSWORD_CHANGED, PASSWORD_RESET, TOKEN_REVOKED, IMPERSONATION_STARTED, SIGN_IN_REPORTED,
+	}
+}
+
+// Event is an authentication event, events are only ever appended
+type Event struct {
+	UUID uuid.UUID
+	Type Type
+	// UserUUID is uuid.Nil for failed sign ins of unknown emails
+	UserUUID uuid.UUID
+	// ActorUUID is the admin acting on the user, uuid.Nil when users act themselves
+	ActorUUID uuid.UUID
+	Email     string
+	IP        string
+	UserAgent string
+	RequestID string
+	CreatedAt time.Time
+}
+
+// Query filters events newest first, zero fields do not filter
+type Query struct {
+	UserUUID uuid.UUID
+	Email    string
+	Types    []Type
+	Before   time.Time
+	Limit    int
+}
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Normalized applies the default limit and caps it
+func (q Query) Normalized() Query {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+
+	return q
+}
+
+type Repository interface {
+	Add(ctx context.Context, event *Event) error
+	Find(ctx context.Context, q Query) ([]*Event, error)
+}
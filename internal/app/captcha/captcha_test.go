@@ -0,0 +1,79 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifier(t *testing.T) {
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("secret") != "secret" {
+			w.Write([]byte(`{"success":false,"error-codes":["invalid-input-secret"]}`))
+			return
+		}
+
+		if r.FormValue("remoteip") != "10.0.0.1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch r.FormValue("response") {
+		case "solved":
+			w.Write([]byte(`{"success":true}`))
+		case "broken":
+			w.Write([]byte(`not json`))
+		default:
+			w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+		}
+	}))
+	defer stub.Close()
+
+	tt := []struct {
+		name    string
+		secret  string
+		token   string
+		ip      string
+		want    bool
+		wantErr bool
+	}{
+		{name: "Solved token", secret: "secret", token: "solved", ip: "10.0.0.1", want: true},
+		{name: "Wrong token", secret: "secret", token: "guess", ip: "10.0.0.1", want: false},
+		{name: "Wrong secret", secret: "other", token: "solved", ip: "10.0.0.1", wantErr: true},
+		{name: "Provider error", secret: "secret", token: "solved", ip: "10.0.0.2", wantErr: true},
+		{name: "Malformed response", secret: "secret", token: "broken", ip: "10.0.0.1", wantErr: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier := NewVerifier(TURNSTILE, tc.secret, stub.URL)
+
+			got, err := verifier.Verify(context.Background(), tc.token, tc.ip)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Want error %v, got %v", tc.wantErr, err)
+			}
+
+			if got != tc.want {
+				t.Errorf("Want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestProviderFromString(t *testing.T) {
+	for _, provider := range []Provider{HCAPTCHA, TURNSTILE, RECAPTCHA} {
+		got, err := ProviderFromString(provider.String())
+		if err != nil || got != provider {
+			t.Errorf("Want %s, got %s (%v)", provider, got, err)
+		}
+
+		if provider.VerifyUrl() == "" {
+			t.Errorf("Want a verify url for %s", provider)
+		}
+	}
+
+	if _, err := ProviderFromString("other"); err == nil {
+		t.Error("Want an error for an unknown provider")
+	}
+}
@@ -0,0 +1,169 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDefaultTemplates(t *testing.T) {
+	renderer, err := NewRenderer(DefaultTemplates(), DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range renderer.Names() {
+		data, err := Sample(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, locale := range renderer.Locales() {
+			msg, err := renderer.Render(name, locale, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, name, err)
+			}
+
+			if msg.Subject == "" || msg.Text == "" || msg.HTML == "" {
+				t.Errorf("%s/%s: want subject, text and html, got %+v", locale, name, msg)
+			}
+
+			if strings.Contains(msg.Text+msg.HTML, "<no value>") {
+				t.Errorf("%s/%s: sample misses a field", locale, name)
+			}
+		}
+	}
+}
+
+func TestRenderer_Render(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html":       {Data: []byte(`{{define "layout"}}<body>{{template "content" .}}</body>{{end}}`)},
+		"en/greeting.txt":   {Data: []byte(`{{define "subject"}}Hello{{end}}{{define "text"}}Hello {{.Name}}{{end}}`)},
+		"en/greeting.html":  {Data: []byte(`{{define "content"}}<p>Hello {{.Name}}</p>{{end}}`)},
+		"pt/greeting.txt":   {Data: []byte(`{{define "subject"}}Olá{{end}}{{define "text"}}Olá {{.Name}}{{end}}`)},
+		"en/plain_only.txt": {Data: []byte(`{{define "subject"}}Plain{{end}}{{define "text"}}Plain{{end}}`)},
+	}
+
+	renderer, err := NewRenderer(fsys, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name     string
+		template string
+		locale   string
+		subject  string
+		html     string
+		err      error
+	}{
+		{name: "Default locale", template: "greeting", locale: "en", subject: "Hello", html: "<body><p>Hello &lt;Ann&gt;</p></body>"},
+		{name: "Language of a region", template: "greeting", locale: "pt-BR", subject: "Olá"},
+		{name: "Unknown locale", template: "greeting", locale: "de", subject: "Hello", html: "<body><p>Hello &lt;Ann&gt;</p></body>"},
+		{name: "Text only", template: "plain_only", locale: "pt", subject: "Plain"},
+		{name: "Unknown template", template: "missing", locale: "en", err: ErrTemplateNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := renderer.Render(tc.template, tc.locale, map[string]string{"Name": "<Ann>"})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Want error '%v', got '%v'", tc.err, err)
+			}
+
+			if msg.Subject != tc.subject {
+				t.Errorf("Want subject '%s', got '%s'", tc.subject, msg.Subject)
+			}
+
+			if msg.HTML != tc.html {
+				t.Errorf("Want html '%s', got '%s'", tc.html, msg.HTML)
+			}
+		})
+	}
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	msg := &Message{
+		From:    Address("Budget", "noreply@example.com"),
+		ReplyTo: "support@example.com",
+		To:      "user@example.com",
+		Subject: "Восстановление пароля",
+		Text:    "Здравствуйте",
+		HTML:    "<p>Здравствуйте</p>",
+	}
+
+	content, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Want subject '%s', got '%s' (%v)", msg.Subject, subject, err)
+	}
+
+	if parsed.Header.Get("Reply-To") != msg.ReplyTo {
+		t.Errorf("Want reply to '%s', got '%s'", msg.ReplyTo, parsed.Header.Get("Reply-To"))
+	}
+
+	if !strings.HasSuffix(parsed.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Want message id of the sender domain, got '%s'", parsed.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Want multipart/alternative, got '%s' (%v)", mediaType, err)
+	}
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Type") != want.contentType || string(body) != want.body {
+			t.Errorf("Want %s '%s', got %s '%s'", want.contentType, want.body, part.Header.Get("Content-Type"), body)
+		}
+	}
+
+	msg.Subject = "Hi\r\nBcc: victim@example.com"
+	if _, err := msg.Bytes(); !errors.Is(err, ErrHeaderInjection) {
+		t.Errorf("Want header injection error, got '%v'", err)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tt := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "ru-RU,ru;q=0.9,en-US;q=0.8", want: "ru-ru"},
+		{header: "en;q=0.5, de_DE;q=0.9", want: "de-de"},
+		{header: "*", want: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.header, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tc.header); got != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package email
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const DefaultHTTPAuthHeader = "Authorization"
+
+type HTTPConfig struct {
+	Endpoint string
+	APIKey   string
+	// AuthHeader carries the API key, as a bearer token for Authorization and as is for other headers
+	// like X-Postmark-Server-Token
+	AuthHeader string
+	Timeout    time.Duration
+}
+
+// HTTPTransport posts messages as JSON to the send endpoint of an email API
+type HTTPTransport struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+type httpMessage struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text"`
+	HTML    string   `json:"html,omitempty"`
+}
+
+func NewHTTPTransport(config HTTPConfig) *HTTPTransport {
+	if config.AuthHeader == "" {
+		config.AuthHeader = DefaultHTTPAuthHeader
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = DefaultSMTPTimeout
+	}
+
+	return &HTTPTransport{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (t *HTTPTransport) Send(msg *Message) error {
+	body, err := json.Marshal(httpMessage{
+		From:    msg.From,
+		To:      []string{msg.To},
+		ReplyTo: msg.ReplyTo,
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if t.config.APIKey != "" {
+		if strings.EqualFold(t.config.AuthHeader, DefaultHTTPAuthHeader) {
+			req.Header.Set(t.config.AuthHeader, "Bearer "+t.config.APIKey)
+		} else {
+			req.Header.Set(t.config.AuthHeader, t.config.APIKey)
+		}
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		reason, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("email api answered %d: %s", res.StatusCode, strings.TrimSpace(string(reason)))
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var ErrHeaderInjection = errors.New("email header contains a line break")
+
+// Message is a rendered email, Text is required and HTML is sent as its alternative when set
+type Message struct {
+	From    string
+	ReplyTo string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Date    time.Time
+}
+
+// Bytes encodes the message as RFC 5322 with a multipart/alternative body in quoted-printable UTF-8
+func (m *Message) Bytes() ([]byte, error) {
+	for _, v := range []string{m.From, m.ReplyTo, m.To, m.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, ErrHeaderInjection
+		}
+	}
+
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	messageId, err := m.messageId()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", m.From)
+	header("To", m.To)
+	if m.ReplyTo != "" {
+		header("Reply-To", m.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageId)
+	header("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+
+		if err := writeQuotedPrintable(&buf, m.Text); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	body := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+body.Boundary())
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *Message) messageId() (string, error) {
+	domain := "localhost"
+	if from, err := mail.ParseAddress(m.From); err == nil {
+		if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+			domain = from.Address[at+1:]
+		}
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain), nil
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+
+	return qp.Close()
+}
+
+// Address formats the From header of a sender, the name is optional
+func Address(name, address string) string {
+	if name == "" {
+		return address
+	}
+
+	return (&mail.Address{Name: name, Address: address}).String()
+}
@@ -0,0 +1,176 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/outbox"
+)
+
+type OutboxStatus struct {
+	v string
+}
+
+var (
+	UNKNOWN = OutboxStatus{"UNKNOWN"}
+	PENDING = OutboxStatus{"pending"}
+	SENT    = OutboxStatus{"sent"}
+	DEAD    = OutboxStatus{"dead"}
+)
+
+func (s OutboxStatus) String() string {
+	return s.v
+}
+
+func OutboxStatusFromString(value string) (OutboxStatus, error) {
+	for _, s := range []OutboxStatus{PENDING, SENT, DEAD} {
+		if s.String() == value {
+			return s, nil
+		}
+	}
+
+	return UNKNOWN, errors.New("Invalid outbox status value")
+}
+
+// OutboxEmail is a rendered message waiting for delivery, dead emails ran out of attempts
+type OutboxEmail struct {
+	UUID          uuid.UUID
+	Message       *Message
+	Status        OutboxStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+type OutboxRepository interface {
+	// Add joins the transaction of the context, the email is only queued when the triggering change commits
+	Add(ctx context.Context, email *OutboxEmail) error
+	// Claim returns up to limit due pending emails and postpones them by the lease, so that concurrent
+	// dispatchers skip them while they are sent
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*OutboxEmail, error)
+	MarkSent(ctx context.Context, uuid uuid.UUID, at time.Time) error
+	MarkFailed(ctx context.Context, uuid uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkDead(ctx context.Context, uuid uuid.UUID, attempts int, lastError string) error
+}
+
+// Mailer renders templates and queues the messages in the outbox with the configured sender
+type Mailer struct {
+	renderer *Renderer
+	outbox   OutboxRepository
+	from     string
+	replyTo  string
+}
+
+func NewMailer(renderer *Renderer, outbox OutboxRepository, from, replyTo string) *Mailer {
+	return &Mailer{
+		renderer: renderer,
+		outbox:   outbox,
+		from:     from,
+		replyTo:  replyTo,
+	}
+}
+
+func (m *Mailer) Send(ctx context.Context, recipient, template, locale string, data interface{}) error {
+	msg, err := m.renderer.Render(template, locale, data)
+	if err != nil {
+		return err
+	}
+
+	msg.From = m.from
+	msg.ReplyTo = m.replyTo
+	msg.To = recipient
+
+	now := time.Now()
+	return m.outbox.Add(ctx, &OutboxEmail{
+		UUID:          uuid.New(),
+		Message:       msg,
+		Status:        PENDING,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
+
+type DispatchPolicy struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Lease       time.Duration
+}
+
+var DefaultDispatchPolicy = DispatchPolicy{
+	Interval:    2 * time.Second,
+	BatchSize:   20,
+	MaxAttempts: 8,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    time.Hour,
+	Lease:       5 * time.Minute,
+}
+
+// Backoff is the delay after the given number of failed attempts, doubling from BaseDelay up to MaxDelay
+func (p DispatchPolicy) Backoff(attempts int) time.Duration {
+	return outbox.Backoff(p.BaseDelay, p.MaxDelay, attempts)
+}
+
+// Dispatcher delivers the outbox through the transport
+type Dispatcher struct {
+	outbox    OutboxRepository
+	transport Transport
+	policy    DispatchPolicy
+}
+
+func NewDispatcher(outbox OutboxRepository, transport Transport, policy DispatchPolicy) *Dispatcher {
+	return &Dispatcher{
+		outbox:    outbox,
+		transport: transport,
+		policy:    policy,
+	}
+}
+
+// Run dispatches every interval until the context is done, a full batch is followed by the next one right away
+func (d *Dispatcher) Run(ctx context.Context, onError func(error)) {
+	outbox.Poll(ctx, d.policy.Interval, d.policy.BatchSize, func(ctx context.Context) (int, error) {
+		return d.Dispatch(ctx, onError)
+	}, onError)
+}
+
+// Dispatch sends one batch and returns the number of claimed emails. Delivery failures are passed to onError
+// and retried with backoff, the returned error is an outbox failure.
+func (d *Dispatcher) Dispatch(ctx context.Context, onError func(error)) (int, error) {
+	emails, err := d.outbox.Claim(ctx, time.Now(), d.policy.Lease, d.policy.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range emails {
+		e.Message.Date = e.CreatedAt
+
+		sendErr := d.transport.Send(e.Message)
+		if sendErr == nil {
+			if err := d.outbox.MarkSent(ctx, e.UUID, time.Now()); err != nil {
+				return len(emails), err
+			}
+			continue
+		}
+
+		onError(sendErr)
+
+		attempts := e.Attempts + 1
+		if attempts >= d.policy.MaxAttempts {
+			err = d.outbox.MarkDead(ctx, e.UUID, attempts, sendErr.Error())
+		} else {
+			err = d.outbox.MarkFailed(ctx, e.UUID, attempts, time.Now().Add(d.policy.Backoff(attempts)), sendErr.Error())
+		}
+
+		if err != nil {
+			return len(emails), err
+		}
+	}
+
+	return len(emails), nil
+}
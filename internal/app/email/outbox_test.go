@@ -0,0 +1,197 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type memoryOutbox struct {
+	mu     sync.Mutex
+	emails []*OutboxEmail
+}
+
+func (o *memoryOutbox) Add(ctx context.Context, e *OutboxEmail) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.emails = append(o.emails, e)
+	return nil
+}
+
+func (o *memoryOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*OutboxEmail, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	claimed := []*OutboxEmail{}
+	for _, e := range o.emails {
+		if len(claimed) < limit && e.Status == PENDING && !e.NextAttemptAt.After(now) {
+			e.NextAttemptAt = now.Add(lease)
+			copied := *e
+			claimed = append(claimed, &copied)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (o *memoryOutbox) update(id uuid.UUID, cb func(e *OutboxEmail)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, e := range o.emails {
+		if e.UUID == id {
+			cb(e)
+			return nil
+		}
+	}
+
+	return errors.New("not found")
+}
+
+func (o *memoryOutbox) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return o.update(id, func(e *OutboxEmail) {
+		e.Status = SENT
+		e.SentAt = &at
+	})
+}
+
+func (o *memoryOutbox) MarkFailed(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return o.update(id, func(e *OutboxEmail) {
+		e.Attempts = attempts
+		e.NextAttemptAt = nextAttemptAt
+		e.LastError = lastError
+	})
+}
+
+func (o *memoryOutbox) MarkDead(ctx context.Context, id uuid.UUID, attempts int, lastError string) error {
+	return o.update(id, func(e *OutboxEmail) {
+		e.Status = DEAD
+		e.Attempts = attempts
+		e.LastError = lastError
+	})
+}
+
+type failingTransport struct{}
+
+func (failingTransport) Send(msg *Message) error {
+	return errors.New("connection refused")
+}
+
+func testMailer(t *testing.T, outbox OutboxRepository) *Mailer {
+	renderer, err := NewRenderer(DefaultTemplates(), DefaultLocale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewMailer(renderer, outbox, Address("Budget", "noreply@example.com"), "")
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	sink, err := NewSink("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	host, port, _ := net.SplitHostPort(sink.Addr())
+	outbox := &memoryOutbox{}
+	data, _ := Sample("password-reset")
+
+	if err := testMailer(t, outbox).Send(context.Background(), "user@example.com", "password-reset", "ru", data); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := NewDispatcher(outbox, NewSMTPTransport(SMTPConfig{Host: host, Port: port, Username: "noreply@example.com", Password: "secret"}), DefaultDispatchPolicy)
+	claimed, err := dispatcher.Dispatch(context.Background(), func(err error) { t.Error(err) })
+	if err != nil || claimed != 1 {
+		t.Fatalf("Want 1 claimed, got %d (%v)", claimed, err)
+	}
+
+	if outbox.emails[0].Status != SENT {
+		t.Errorf("Want status '%s', got '%s'", SENT, outbox.emails[0].Status)
+	}
+
+	messages := sink.Messages()
+	if len(messages) != 1 || messages[0].From != "noreply@example.com" || messages[0].To[0] != "user@example.com" {
+		t.Fatalf("Want one message to user@example.com, got %+v", messages)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(messages[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Header.Get("From") != `"Budget" <noreply@example.com>` {
+		t.Errorf("Want the configured sender, got '%s'", parsed.Header.Get("From"))
+	}
+
+	claimed, _ = dispatcher.Dispatch(context.Background(), func(err error) { t.Error(err) })
+	if claimed != 0 || len(sink.Messages()) != 1 {
+		t.Errorf("Want sent emails to stay sent, claimed %d", claimed)
+	}
+}
+
+func TestDispatcher_DispatchRetries(t *testing.T) {
+	outbox := &memoryOutbox{}
+	data, _ := Sample("password-reset")
+	if err := testMailer(t, outbox).Send(context.Background(), "user@example.com", "password-reset", "en", data); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := DefaultDispatchPolicy
+	policy.MaxAttempts = 3
+	dispatcher := NewDispatcher(outbox, failingTransport{}, policy)
+
+	failures := 0
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		before := time.Now()
+		if _, err := dispatcher.Dispatch(context.Background(), func(err error) { failures++ }); err != nil {
+			t.Fatal(err)
+		}
+
+		e := outbox.emails[0]
+		if e.Attempts != attempt || e.LastError != "connection refused" {
+			t.Fatalf("Want %d attempts, got %+v", attempt, e)
+		}
+
+		if attempt < policy.MaxAttempts {
+			if e.Status != PENDING || e.NextAttemptAt.Before(before.Add(policy.Backoff(attempt))) {
+				t.Fatalf("Want a retry after %s, got %+v", policy.Backoff(attempt), e)
+			}
+
+			// the backoff has passed
+			e.NextAttemptAt = time.Now()
+		}
+	}
+
+	if outbox.emails[0].Status != DEAD || failures != policy.MaxAttempts {
+		t.Errorf("Want a dead email after %d failures, got '%s' after %d", policy.MaxAttempts, outbox.emails[0].Status, failures)
+	}
+}
+
+func TestFileTransport_Send(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := NewFileTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = transport.Send(&Message{From: "noreply@example.com", To: "user@example.com", Subject: "Hi", Text: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("Want one file, got %d", len(entries))
+	}
+}
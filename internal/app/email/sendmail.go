@@ -0,0 +1,49 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"os/exec"
+	"strings"
+)
+
+const DefaultSendmailPath = "/usr/sbin/sendmail"
+
+// SendmailTransport pipes messages into the local sendmail binary, e.g. of postfix or msmtp
+type SendmailTransport struct {
+	path string
+}
+
+func NewSendmailTransport(path string) *SendmailTransport {
+	if path == "" {
+		path = DefaultSendmailPath
+	}
+
+	return &SendmailTransport{path}
+}
+
+func (t *SendmailTransport) Send(msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// -i keeps lines with a single dot, the envelope is given explicitly rather than read from the headers
+	cmd := exec.Command(t.path, "-i", "-f", from.Address, "--", msg.To)
+	cmd.Stdin = bytes.NewReader(body)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sendmail: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package email
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SinkMessage is a message received by the sink, Data is the message as sent over SMTP
+type SinkMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Sink is an in-process SMTP server which accepts every message and keeps it, tests and local development
+// send to it with the SMTP transport and assert on Messages. It accepts any credentials.
+type Sink struct {
+	listener  net.Listener
+	dir       string
+	tlsConfig *tls.Config
+	implicit  bool
+
+	mu          sync.Mutex
+	messages    []SinkMessage
+	conns       map[net.Conn]struct{}
+	connections int
+	wg          sync.WaitGroup
+}
+
+// NewSink listens on addr, e.g. "127.0.0.1:0" for a free port, and writes the messages to dir unless it is empty
+func NewSink(addr, dir string) (*Sink, error) {
+	return NewTLSSink(addr, dir, nil, false)
+}
+
+// NewTLSSink offers STARTTLS with the config, or speaks TLS from the start when implicit
+func NewTLSSink(addr, dir string, config *tls.Config, implicit bool) (*Sink, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if config != nil && implicit {
+		listener = tls.NewListener(listener, config)
+	}
+
+	s := &Sink{
+		listener:  listener,
+		dir:       dir,
+		tlsConfig: config,
+		implicit:  implicit,
+		conns:     map[net.Conn]struct{}{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *Sink) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Messages returns a copy of the received messages in arrival order
+func (s *Sink) Messages() []SinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]SinkMessage{}, s.messages...)
+}
+
+// Connections is the number of accepted connections
+func (s *Sink) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.connections
+}
+
+// Close stops listening and drops open connections
+func (s *Sink) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Sink) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.connections++
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.session(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Sink) session(conn net.Conn) {
+	defer conn.Close()
+
+	c := textproto.NewConn(conn)
+	secure := s.implicit && s.tlsConfig != nil
+	var msg SinkMessage
+	reply := func(format string, args ...interface{}) bool {
+		return c.PrintfLine(format, args...) == nil
+	}
+
+	if !reply("220 localhost sink ready") {
+		return
+	}
+
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], line[i+1:]
+		}
+
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = reply("250-localhost") && reply("250-8BITMIME")
+			if ok && s.tlsConfig != nil && !secure {
+				ok = reply("250-STARTTLS")
+			}
+			ok = ok && reply("250 AUTH PLAIN LOGIN")
+		case "HELO":
+			ok = reply("250 localhost")
+		case "STARTTLS":
+			if s.tlsConfig == nil || secure {
+				ok = reply("502 5.5.1 TLS not available")
+				break
+			}
+
+			if !reply("220 2.0.0 Ready to start TLS") {
+				return
+			}
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+
+			c = textproto.NewConn(tlsConn)
+			secure = true
+			msg = SinkMessage{}
+		case "AUTH":
+			ok = reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			msg = SinkMessage{From: sinkAddress(arg)}
+			ok = reply("250 2.1.0 OK")
+		case "RCPT":
+			msg.To = append(msg.To, sinkAddress(arg))
+			ok = reply("250 2.1.5 OK")
+		case "DATA":
+			if len(msg.To) == 0 {
+				ok = reply("503 5.5.1 RCPT first")
+				break
+			}
+
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+
+			data, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+
+			msg.Data = data
+			if err := s.keep(msg); err != nil {
+				ok = reply("451 4.3.0 %s", err)
+			} else {
+				ok = reply("250 2.0.0 OK")
+			}
+			msg = SinkMessage{}
+		case "RSET":
+			msg = SinkMessage{}
+			ok = reply("250 2.0.0 OK")
+		case "NOOP":
+			ok = reply("250 2.0.0 OK")
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			ok = reply("502 5.5.2 Command not implemented")
+		}
+
+		if !ok {
+			return
+		}
+	}
+}
+
+func (s *Sink) keep(msg SinkMessage) error {
+	if s.dir != "" {
+		if err := writeEml(s.dir, strings.Join(msg.To, ","), msg.Data); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// sinkAddress takes the address of "FROM:<a@b.c> BODY=8BITMIME"
+func sinkAddress(arg string) string {
+	start, end := strings.IndexByte(arg, '<'), strings.IndexByte(arg, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return arg[start+1 : end]
+}
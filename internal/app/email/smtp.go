@@ -0,0 +1,227 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+const (
+	// SMTPSecurityAuto uses implicit TLS on port 465 and STARTTLS when the server offers it otherwise
+	SMTPSecurityAuto     = ""
+	SMTPSecurityStartTLS = "starttls"
+	SMTPSecurityTLS      = "tls"
+	SMTPSecurityNone     = "none"
+)
+
+const (
+	DefaultSMTPPoolSize    = 2
+	DefaultSMTPIdleTimeout = time.Minute
+	DefaultSMTPTimeout     = 30 * time.Second
+)
+
+var ErrStartTLSUnsupported = errors.New("smtp server does not offer STARTTLS")
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Security string
+	// PoolSize is the number of idle connections kept for the next messages, 0 closes every connection after use
+	PoolSize    int
+	IdleTimeout time.Duration
+	Timeout     time.Duration
+	// TLSConfig replaces the default verification of the server certificate against Host
+	TLSConfig *tls.Config
+}
+
+// SMTPTransport sends over SMTP, credentials are only sent over TLS or to localhost
+type SMTPTransport struct {
+	config SMTPConfig
+	idle   chan *smtpConn
+}
+
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+func NewSMTPTransport(config SMTPConfig) *SMTPTransport {
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = DefaultSMTPIdleTimeout
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = DefaultSMTPTimeout
+	}
+
+	return &SMTPTransport{
+		config: config,
+		idle:   make(chan *smtpConn, config.PoolSize),
+	}
+}
+
+func (t *SMTPTransport) Send(msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	c, err := t.get()
+	if err != nil {
+		return err
+	}
+
+	if err := c.deliver(from.Address, msg.To, body, t.config.Timeout); err != nil {
+		c.close()
+		return err
+	}
+
+	t.put(c)
+	return nil
+}
+
+// Close quits the idle connections
+func (t *SMTPTransport) Close() {
+	for {
+		select {
+		case c := <-t.idle:
+			c.client.Quit()
+		default:
+			return
+		}
+	}
+}
+
+// get reuses an idle connection which is still alive or dials a new one
+func (t *SMTPTransport) get() (*smtpConn, error) {
+	for {
+		select {
+		case c := <-t.idle:
+			if time.Since(c.lastUsed) > t.config.IdleTimeout {
+				c.close()
+				continue
+			}
+
+			c.conn.SetDeadline(time.Now().Add(t.config.Timeout))
+			if err := c.client.Reset(); err != nil {
+				c.close()
+				continue
+			}
+
+			return c, nil
+		default:
+			return t.dial()
+		}
+	}
+}
+
+func (t *SMTPTransport) put(c *smtpConn) {
+	c.lastUsed = time.Now()
+
+	select {
+	case t.idle <- c:
+	default:
+		c.client.Quit()
+	}
+}
+
+func (t *SMTPTransport) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(t.config.Host, t.config.Port)
+	dialer := &net.Dialer{Timeout: t.config.Timeout}
+	implicit := t.config.Security == SMTPSecurityTLS || (t.config.Security == SMTPSecurityAuto && t.config.Port == "465")
+
+	var conn net.Conn
+	var err error
+	if implicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, t.tlsConfig())
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(t.config.Timeout))
+	client, err := smtp.NewClient(conn, t.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &smtpConn{conn: conn, client: client}
+	if err := t.secure(c, implicit); err != nil {
+		c.close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (t *SMTPTransport) secure(c *smtpConn, implicit bool) error {
+	if !implicit && t.config.Security != SMTPSecurityNone {
+		if ok, _ := c.client.Extension("STARTTLS"); ok {
+			if err := c.client.StartTLS(t.tlsConfig()); err != nil {
+				return err
+			}
+		} else if t.config.Security == SMTPSecurityStartTLS {
+			return ErrStartTLSUnsupported
+		}
+	}
+
+	if t.config.Username == "" {
+		return nil
+	}
+
+	return c.client.Auth(smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host))
+}
+
+func (t *SMTPTransport) tlsConfig() *tls.Config {
+	if t.config.TLSConfig == nil {
+		return &tls.Config{ServerName: t.config.Host, MinVersion: tls.VersionTLS12}
+	}
+
+	config := t.config.TLSConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName = t.config.Host
+	}
+
+	return config
+}
+
+func (c *smtpConn) deliver(from, to string, body []byte, timeout time.Duration) error {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+
+	if err := c.client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func (c *smtpConn) close() {
+	c.client.Close()
+}
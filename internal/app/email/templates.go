@@ -0,0 +1,229 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+const DefaultLocale = "en"
+
+var ErrTemplateNotFound = errors.New("email template not found")
+
+//go:embed templates samples
+var embedded embed.FS
+
+// DefaultTemplates are the templates shipped with the service, a directory with the same layout replaces them
+func DefaultTemplates() fs.FS {
+	sub, _ := fs.Sub(embedded, "templates")
+	return sub
+}
+
+// Sample is the preview data of a template
+func Sample(name string) (map[string]interface{}, error) {
+	content, err := embedded.ReadFile(path.Join("samples", name+".json"))
+	if err != nil {
+		return map[string]interface{}{}, fmt.Errorf("%w: no sample for %s", ErrTemplateNotFound, name)
+	}
+
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	return data, nil
+}
+
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renderer renders named templates in per locale variants. Templates are read from a file system laid out as
+//
+//	layout.html          the HTML frame, defines "layout" and calls "content"
+//	<locale>/<name>.txt  defines "subject" and "text"
+//	<locale>/<name>.html defines "content", optional
+type Renderer struct {
+	defaultLocale string
+	templates     map[string]map[string]*template
+}
+
+// NewRenderer parses every template upfront so that broken templates fail the start rather than a send
+func NewRenderer(fsys fs.FS, defaultLocale string) (*Renderer, error) {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+
+	layout, err := htmltemplate.ParseFS(fsys, "layout.html")
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{
+		defaultLocale: normalizeLocale(defaultLocale),
+		templates:     map[string]map[string]*template{},
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		locale := normalizeLocale(entry.Name())
+		files, err := fs.Glob(fsys, path.Join(entry.Name(), "*.txt"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			name := strings.TrimSuffix(path.Base(file), ".txt")
+
+			text, err := texttemplate.ParseFS(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if text.Lookup("subject") == nil || text.Lookup("text") == nil {
+				return nil, fmt.Errorf("email template %s must define subject and text", file)
+			}
+
+			t := &template{text: text}
+
+			htmlFile := strings.TrimSuffix(file, ".txt") + ".html"
+			if _, err := fs.Stat(fsys, htmlFile); err == nil {
+				frame, err := layout.Clone()
+				if err != nil {
+					return nil, err
+				}
+
+				if t.html, err = frame.ParseFS(fsys, htmlFile); err != nil {
+					return nil, err
+				}
+			}
+
+			if r.templates[locale] == nil {
+				r.templates[locale] = map[string]*template{}
+			}
+			r.templates[locale][name] = t
+		}
+	}
+
+	return r, nil
+}
+
+// Render executes the template in the locale, falling back to its language ("pt" for "pt-BR") and to the
+// default locale. The message is returned without sender and recipient.
+func (r *Renderer) Render(name, locale string, data interface{}) (*Message, error) {
+	t, ok := r.lookup(name, locale)
+	if !ok {
+		return &Message{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return &Message{}, err
+	}
+
+	if err := t.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return &Message{}, err
+	}
+
+	if t.html != nil {
+		if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+			return &Message{}, err
+		}
+	}
+
+	return &Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+func (r *Renderer) lookup(name, locale string) (*template, bool) {
+	locale = normalizeLocale(locale)
+	candidates := []string{locale}
+	if i := strings.Index(locale, "-"); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, r.defaultLocale)
+
+	for _, candidate := range candidates {
+		if t, ok := r.templates[candidate][name]; ok {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
+
+// Names lists the templates of every locale
+func (r *Renderer) Names() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, templates := range r.templates {
+		for name := range templates {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (r *Renderer) Locales() []string {
+	locales := []string{}
+	for locale := range r.templates {
+		locales = append(locales, locale)
+	}
+
+	sort.Strings(locales)
+	return locales
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ParseAcceptLanguage returns the preferred locale of an Accept-Language header, ignoring the wildcard
+func ParseAcceptLanguage(header string) string {
+	best, bestQ := "", -1.0
+	for _, part := range strings.Split(header, ",") {
+		tag, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			tag = part[:i]
+			if _, err := fmt.Sscanf(strings.TrimSpace(part[i+1:]), "q=%g", &q); err != nil {
+				q = 0
+			}
+		}
+
+		tag = normalizeLocale(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,48 @@
+package email
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transport delivers a rendered message, implementations are SMTP, sendmail, an HTTP API, files and the sink
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// FileTransport writes every message as an .eml file into a directory, for local development
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileTransport{dir}, nil
+}
+
+func (t *FileTransport) Send(msg *Message) error {
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return writeEml(t.dir, msg.To, body)
+}
+
+func writeEml(dir, recipient string, body []byte) error {
+	name := fmt.Sprintf("%s-%s-%s.eml",
+		time.Now().UTC().Format("20060102T150405.000000000"),
+		strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(recipient),
+		uuid.NewString()[:8],
+	)
+
+	return os.WriteFile(filepath.Join(dir, name), body, 0644)
+}
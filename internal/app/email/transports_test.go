@@ -0,0 +1,233 @@
+package email
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertificate is a self-signed certificate for 127.0.0.1 and the pool which trusts it
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func testMessage(to string) *Message {
+	return &Message{From: Address("Budget", "noreply@example.com"), To: to, Subject: "Hi", Text: "Hi there", HTML: "<p>Hi there</p>"}
+}
+
+func TestSMTPTransport_Send(t *testing.T) {
+	cert, roots := testCertificate(t)
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	cases := []struct {
+		name     string
+		tls      *tls.Config
+		implicit bool
+		security string
+		wantErr  error
+	}{
+		{name: "plain", security: SMTPSecurityNone},
+		{name: "auto without STARTTLS", security: SMTPSecurityAuto},
+		{name: "STARTTLS", tls: serverTLS, security: SMTPSecurityStartTLS},
+		{name: "auto with STARTTLS", tls: serverTLS, security: SMTPSecurityAuto},
+		{name: "implicit TLS", tls: serverTLS, implicit: true, security: SMTPSecurityTLS},
+		{name: "STARTTLS required", security: SMTPSecurityStartTLS, wantErr: ErrStartTLSUnsupported},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sink, err := NewTLSSink("127.0.0.1:0", "", tc.tls, tc.implicit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sink.Close()
+
+			host, port, _ := net.SplitHostPort(sink.Addr())
+			transport := NewSMTPTransport(SMTPConfig{
+				Host:      host,
+				Port:      port,
+				Username:  "noreply@example.com",
+				Password:  "secret",
+				Security:  tc.security,
+				PoolSize:  1,
+				TLSConfig: &tls.Config{RootCAs: roots},
+			})
+			defer transport.Close()
+
+			err = transport.Send(testMessage("user@example.com"))
+			if err != tc.wantErr {
+				t.Fatalf("Want error '%v', got '%v'", tc.wantErr, err)
+			}
+
+			if tc.wantErr != nil {
+				return
+			}
+
+			messages := sink.Messages()
+			if len(messages) != 1 || messages[0].From != "noreply@example.com" || messages[0].To[0] != "user@example.com" {
+				t.Fatalf("Want one message to user@example.com, got %+v", messages)
+			}
+		})
+	}
+}
+
+func TestSMTPTransport_SendPooled(t *testing.T) {
+	sink, err := NewSink("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	host, port, _ := net.SplitHostPort(sink.Addr())
+	pooled := NewSMTPTransport(SMTPConfig{Host: host, Port: port, Security: SMTPSecurityNone, PoolSize: 1})
+	defer pooled.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := pooled.Send(testMessage("user@example.com")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sink.Messages()) != 3 || sink.Connections() != 1 {
+		t.Fatalf("Want 3 messages over 1 connection, got %d over %d", len(sink.Messages()), sink.Connections())
+	}
+
+	unpooled := NewSMTPTransport(SMTPConfig{Host: host, Port: port, Security: SMTPSecurityNone})
+	for i := 0; i < 2; i++ {
+		if err := unpooled.Send(testMessage("user@example.com")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sink.Connections() != 3 {
+		t.Errorf("Want a connection per message without a pool, got %d connections", sink.Connections())
+	}
+}
+
+func TestSendmailTransport_Send(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "sendmail")
+	body := "#!/bin/sh\necho \"$@\" > " + out + ".args\ncat > " + out + "\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewSendmailTransport(script).Send(testMessage("user@example.com")); err != nil {
+		t.Fatal(err)
+	}
+
+	args, _ := os.ReadFile(out + ".args")
+	if strings.TrimSpace(string(args)) != "-i -f noreply@example.com -- user@example.com" {
+		t.Errorf("Want the envelope as arguments, got '%s'", args)
+	}
+
+	data, _ := os.ReadFile(out)
+	if !strings.Contains(string(data), "Subject: Hi") {
+		t.Errorf("Want the message on stdin, got '%s'", data)
+	}
+
+	failing := filepath.Join(dir, "failing")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\necho 'no such user' >&2\nexit 67\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewSendmailTransport(failing).Send(testMessage("user@example.com"))
+	if err == nil || !strings.Contains(err.Error(), "no such user") {
+		t.Errorf("Want the stderr of sendmail in the error, got '%v'", err)
+	}
+}
+
+func TestHTTPTransport_Send(t *testing.T) {
+	cases := []struct {
+		name       string
+		authHeader string
+		status     int
+		wantHeader string
+		wantValue  string
+		wantErr    bool
+	}{
+		{name: "bearer", status: http.StatusAccepted, wantHeader: "Authorization", wantValue: "Bearer key"},
+		{name: "custom header", authHeader: "X-Api-Key", status: http.StatusOK, wantHeader: "X-Api-Key", wantValue: "key"},
+		{name: "rejected", status: http.StatusUnprocessableEntity, wantHeader: "Authorization", wantValue: "Bearer key", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got httpMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get(tc.wantHeader) != tc.wantValue {
+					t.Errorf("Want header %s '%s', got '%s'", tc.wantHeader, tc.wantValue, r.Header.Get(tc.wantHeader))
+				}
+
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{"message":"invalid recipient"}`))
+			}))
+			defer server.Close()
+
+			transport := NewHTTPTransport(HTTPConfig{Endpoint: server.URL, APIKey: "key", AuthHeader: tc.authHeader})
+			err := transport.Send(testMessage("user@example.com"))
+			if tc.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid recipient") {
+					t.Errorf("Want the answer of the api in the error, got '%v'", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got.To) != 1 || got.To[0] != "user@example.com" || got.Subject != "Hi" || got.HTML != "<p>Hi there</p>" {
+				t.Errorf("Want the message as json, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package emaildomain
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed disposable.txt
+var bundledDisposable string
+
+// Filter decides which email domains may sign up. Domains match themselves and their subdomains,
+// a non empty allow list admits only its domains, the deny list and the disposable list refuse theirs.
+type Filter struct {
+	allow []string
+	deny  []string
+
+	disposableFile string
+	modTime        time.Time
+
+	mu         sync.RWMutex
+	disposable map[string]struct{}
+}
+
+// NewFilter creates the filter, disposableFile replaces the bundled disposable list when set
+func NewFilter(allow, deny []string, disposableFile string) (*Filter, error) {
+	f := &Filter{
+		allow:          normalize(allow),
+		deny:           normalize(deny),
+		disposableFile: disposableFile,
+	}
+
+	if disposableFile == "" {
+		f.disposable = parseList(strings.NewReader(bundledDisposable))
+		return f, nil
+	}
+
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Allowed reports whether the domain of the email may be used for a new account
+func (f *Filter) Allowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := normalizeDomain(email[at+1:])
+
+	if len(f.allow) > 0 && !matchesAny(domain, f.allow) {
+		return false
+	}
+
+	if matchesAny(domain, f.deny) {
+		return false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, candidate := range parents(domain) {
+		if _, found := f.disposable[candidate]; found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Reload reads the disposable list file again, a failed read keeps the current list
+func (f *Filter) Reload() error {
+	file, err := os.Open(f.disposableFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	disposable := parseList(file)
+
+	f.mu.Lock()
+	f.disposable = disposable
+	f.modTime = info.ModTime()
+	f.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the disposable list file whenever its modification time changes until ctx is done
+func (f *Filter) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	if f.disposableFile == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(f.disposableFile)
+			if err == nil {
+				f.mu.RLock()
+				changed := !info.ModTime().Equal(f.modTime)
+				f.mu.RUnlock()
+
+				if !changed {
+					continue
+				}
+
+				err = f.Reload()
+			}
+
+			if err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func parseList(r io.Reader) map[string]struct{} {
+	domains := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		domains[normalizeDomain(line)] = struct{}{}
+	}
+
+	return domains
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+func normalize(domains []string) []string {
+	result := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain = normalizeDomain(domain); domain != "" {
+			result = append(result, domain)
+		}
+	}
+
+	return result
+}
+
+// parents returns the domain and every parent domain of it, e.g. a.b.com, b.com and com
+func parents(domain string) []string {
+	result := []string{domain}
+	for {
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return result
+		}
+
+		domain = domain[dot+1:]
+		result = append(result, domain)
+	}
+}
+
+func matchesAny(domain string, list []string) bool {
+	for _, entry := range list {
+		if domain == entry || strings.HasSuffix(domain, "."+entry) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package emaildomain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilter_Allowed(t *testing.T) {
+	open, err := NewFilter(nil, []string{"competitor.com"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corporate, err := NewFilter([]string{"acme.com", "Acme.org."}, []string{"contractors.acme.com"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name   string
+		filter *Filter
+		email  string
+		want   bool
+	}{
+		{name: "Regular domain", filter: open, email: "user@example.com", want: true},
+		{name: "Denied domain", filter: open, email: "user@competitor.com", want: false},
+		{name: "Subdomain of a denied domain", filter: open, email: "user@mail.competitor.com", want: false},
+		{name: "Disposable domain", filter: open, email: "user@Mailinator.com", want: false},
+		{name: "Subdomain of a disposable domain", filter: open, email: "user@eu.yopmail.com", want: false},
+		{name: "Without a domain", filter: open, email: "user", want: false},
+		{name: "Allowed domain", filter: corporate, email: "user@acme.com", want: true},
+		{name: "Subdomain of an allowed domain", filter: corporate, email: "user@eu.acme.org", want: true},
+		{name: "Domain out of the allow list", filter: corporate, email: "user@example.com", want: false},
+		{name: "Denied subdomain of an allowed domain", filter: corporate, email: "user@contractors.acme.com", want: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Allowed(tc.email); got != tc.want {
+				t.Errorf("Allowed(%s): want %v, got %v", tc.email, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFilter_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disposable.txt")
+	if err := os.WriteFile(path, []byte("# list\nthrowaway.test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := NewFilter(nil, nil, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Allowed("user@throwaway.test") || !filter.Allowed("user@mailinator.com") {
+		t.Error("Want the file to replace the bundled list")
+	}
+
+	if err := os.WriteFile(path, []byte("other.test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := filter.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !filter.Allowed("user@throwaway.test") || filter.Allowed("user@other.test") {
+		t.Error("Want the reloaded list")
+	}
+
+	if _, err := NewFilter(nil, nil, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Want an error for a missing file")
+	}
+}
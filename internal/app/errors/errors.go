@@ -1,20 +1,26 @@
 package errors
 
+import "time"
+
 type ErrorType struct {
 	t string
 }
 
 var (
-	ErrorTypeUnknown        = ErrorType{"unknown"}
-	ErrorTypeAuthorization  = ErrorType{"authorization"}
-	ErrorTypeIncorrectInput = ErrorType{"incorrect-input"}
-	ErrorNotFound           = ErrorType{"not-found"}
+	ErrorTypeUnknown         = ErrorType{"unknown"}
+	ErrorTypeAuthorization   = ErrorType{"authorization"}
+	ErrorTypeIncorrectInput  = ErrorType{"incorrect-input"}
+	ErrorTypeForbidden       = ErrorType{"forbidden"}
+	ErrorTypeTooManyRequests = ErrorType{"too-many-requests"}
+	ErrorTypeUnavailable     = ErrorType{"unavailable"}
+	ErrorNotFound            = ErrorType{"not-found"}
 )
 
 type AppError struct {
-	error     string
-	slug      string
-	errorType ErrorType
+	error      string
+	slug       string
+	errorType  ErrorType
+	retryAfter time.Duration
 }
 
 func (s AppError) Error() string {
@@ -29,6 +35,11 @@ func (s AppError) ErrorType() ErrorType {
 	return s.errorType
 }
 
+// RetryAfter is the time the client should wait before repeating a throttled request
+func (s AppError) RetryAfter() time.Duration {
+	return s.retryAfter
+}
+
 func NewAppError(error string, slug string) AppError {
 	return AppError{
 		error:     error,
@@ -45,6 +56,33 @@ func NewAuthorizationError(error string, slug string) AppError {
 	}
 }
 
+func NewForbiddenError(error string, slug string) AppError {
+	return AppError{
+		error:     error,
+		slug:      slug,
+		errorType: ErrorTypeForbidden,
+	}
+}
+
+func NewTooManyRequestsError(error string, slug string, retryAfter time.Duration) AppError {
+	return AppError{
+		error:      error,
+		slug:       slug,
+		errorType:  ErrorTypeTooManyRequests,
+		retryAfter: retryAfter,
+	}
+}
+
+// NewUnavailableError reports a temporary overload, the request may be repeated after retryAfter
+func NewUnavailableError(error string, slug string, retryAfter time.Duration) AppError {
+	return AppError{
+		error:      error,
+		slug:       slug,
+		errorType:  ErrorTypeUnavailable,
+		retryAfter: retryAfter,
+	}
+}
+
 func NewIncorrectInputError(error string, slug string) AppError {
 	return AppError{
 		error:     error,
@@ -0,0 +1,45 @@
+package event
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsContentType is the content type of events in the structured mode, envelope and data in one JSON
+	CloudEventsContentType = "application/cloudevents+json"
+	DefaultSource          = "/users"
+)
+
+// CloudEvent is the CloudEvents 1.0 envelope of an event, Subject is the user the event is about
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+func NewCloudEvent(e *Event, source string) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              e.UUID.String(),
+		Source:          source,
+		Type:            e.Type.String(),
+		Subject:         e.UserUUID.String(),
+		Time:            e.OccurredAt.UTC(),
+		DataContentType: "application/json",
+		Data:            e.Data,
+	}
+}
+
+// Destination fills "{type}" and "{user}" of a subject or topic template, e.g. "users.{type}" gives
+// "users.user.created"
+func Destination(template string, e *Event) string {
+	return strings.NewReplacer("{type}", e.Type.String(), "{user}", e.UserUUID.String()).Replace(template)
+}
@@ -0,0 +1,141 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type struct {
+	v string
+}
+
+var (
+	UNKNOWN         = Type{"UNKNOWN"}
+	USER_CREATED    = Type{"user.created"}
+	USER_UPDATED    = Type{"user.updated"}
+	SESSION_REVOKED = Type{"session.revoked"}
+)
+
+func (t Type) String() string {
+	return t.v
+}
+
+func FromString(value string) (Type, error) {
+	for _, t := range All() {
+		if t.String() == value {
+			return t, nil
+		}
+	}
+
+	return UNKNOWN, errors.New("Invalid event type value")
+}
+
+func All() []Type {
+	return []Type{USER_CREATED, USER_UPDATED, SESSION_REVOKED}
+}
+
+// Event is a domain event of a user waiting in the outbox until it is published, Data is the JSON payload
+type Event struct {
+	UUID          uuid.UUID
+	Type          Type
+	UserUUID      uuid.UUID
+	Data          json.RawMessage
+	OccurredAt    time.Time
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	PublishedAt   *time.Time
+}
+
+// UserPayload is the data of user.created and user.updated, the user as it is after the change
+type UserPayload struct {
+	UUID              uuid.UUID `json:"uuid"`
+	Email             string    `json:"email"`
+	Name              string    `json:"name"`
+	Role              string    `json:"role"`
+	Currency          string    `json:"currency"`
+	FirstDayOfWeek    string    `json:"first_day_of_week"`
+	ProfilePictureUrl string    `json:"profile_picture_url"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+const (
+	ReasonSignOut         = "sign-out"
+	ReasonSessionLimit    = "session-limit"
+	ReasonPasswordChanged = "password-changed"
+	ReasonPasswordReset   = "password-reset"
+	ReasonSignInReported  = "sign-in-reported"
+)
+
+// SessionPayload is the data of session.revoked, SessionUUID is nil when every session of the user was revoked,
+// or every other session after a password change
+type SessionPayload struct {
+	UserUUID    uuid.UUID  `json:"user_uuid"`
+	SessionUUID *uuid.UUID `json:"session_uuid,omitempty"`
+	Reason      string     `json:"reason"`
+}
+
+func New(t Type, userUUID uuid.UUID, data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Event{
+		UUID:          uuid.New(),
+		Type:          t,
+		UserUUID:      userUUID,
+		Data:          raw,
+		OccurredAt:    now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+type Repository interface {
+	// Add joins the transaction of the context, the event only exists when the triggering change commits
+	Add(ctx context.Context, e *Event) error
+	// Claim returns up to limit due events and postpones them by the lease, so that concurrent relays skip them.
+	// Only the oldest unpublished event of a user is claimed, events of a user are published in order.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Event, error)
+	MarkPublished(ctx context.Context, uuid uuid.UUID, at time.Time) error
+	MarkFailed(ctx context.Context, uuid uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string) error
+}
+
+// Publisher puts events onto the bus, Publish returns once the bus has accepted the event
+type Publisher interface {
+	Publish(ctx context.Context, e *Event) error
+}
+
+// LogPublisher prints the events, for local development without a bus
+type LogPublisher struct {
+	printf func(format string, args ...interface{})
+}
+
+func NewLogPublisher(printf func(format string, args ...interface{})) *LogPublisher {
+	return &LogPublisher{printf}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, e *Event) error {
+	p.printf("event %s %s of user %s: %s", e.Type, e.UUID, e.UserUUID, e.Data)
+	return nil
+}
+
+// Fanout publishes to every publisher in turn and stops at the first failure, the relay then retries the event
+// on all of them, so the publishers have to tolerate events they already took
+type Fanout []Publisher
+
+func (f Fanout) Publish(ctx context.Context, e *Event) error {
+	for _, p := range f {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/ibgl/microservice-users/internal/app/outbox"
+)
+
+type RelayPolicy struct {
+	Interval  time.Duration
+	BatchSize int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Lease     time.Duration
+}
+
+var DefaultRelayPolicy = RelayPolicy{
+	Interval:  time.Second,
+	BatchSize: 100,
+	BaseDelay: 5 * time.Second,
+	MaxDelay:  10 * time.Minute,
+	Lease:     time.Minute,
+}
+
+// Backoff is the delay after the given number of failed attempts, doubling from BaseDelay up to MaxDelay
+func (p RelayPolicy) Backoff(attempts int) time.Duration {
+	return outbox.Backoff(p.BaseDelay, p.MaxDelay, attempts)
+}
+
+// Relay publishes the outbox at least once: an event is marked published only after the publisher accepted it,
+// so a crash in between publishes it again. Failed events are retried without limit, consumers dedupe by UUID.
+type Relay struct {
+	repository Repository
+	publisher  Publisher
+	policy     RelayPolicy
+}
+
+func NewRelay(repository Repository, publisher Publisher, policy RelayPolicy) *Relay {
+	return &Relay{
+		repository: repository,
+		publisher:  publisher,
+		policy:     policy,
+	}
+}
+
+// Run relays every interval until the context is done, a full batch is followed by the next one right away
+func (r *Relay) Run(ctx context.Context, onError func(error)) {
+	outbox.Poll(ctx, r.policy.Interval, r.policy.BatchSize, func(ctx context.Context) (int, error) {
+		return r.Relay(ctx, onError)
+	}, onError)
+}
+
+// Relay publishes one batch and returns the number of claimed events. Publishing failures are passed to onError
+// and retried with backoff, the returned error is an outbox failure.
+func (r *Relay) Relay(ctx context.Context, onError func(error)) (int, error) {
+	events, err := r.repository.Claim(ctx, time.Now(), r.policy.Lease, r.policy.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		publishErr := r.publisher.Publish(ctx, e)
+		if publishErr == nil {
+			if err := r.repository.MarkPublished(ctx, e.UUID, time.Now()); err != nil {
+				return len(events), err
+			}
+			continue
+		}
+
+		onError(publishErr)
+
+		attempts := e.Attempts + 1
+		err := r.repository.MarkFailed(ctx, e.UUID, attempts, time.Now().Add(r.policy.Backoff(attempts)), publishErr.Error())
+		if err != nil {
+			return len(events), err
+		}
+	}
+
+	return len(events), nil
+}
@@ -0,0 +1,175 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type memoryRepository struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+func (r *memoryRepository) Add(ctx context.Context, e *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *memoryRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []*Event{}
+	blocked := map[uuid.UUID]bool{}
+	for _, e := range r.events {
+		if e.PublishedAt != nil {
+			continue
+		}
+
+		if len(claimed) < limit && !blocked[e.UserUUID] && !e.NextAttemptAt.After(now) {
+			e.NextAttemptAt = now.Add(lease)
+			copied := *e
+			claimed = append(claimed, &copied)
+		}
+
+		blocked[e.UserUUID] = true
+	}
+
+	return claimed, nil
+}
+
+func (r *memoryRepository) update(id uuid.UUID, cb func(e *Event)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.events {
+		if e.UUID == id {
+			cb(e)
+			return nil
+		}
+	}
+
+	return errors.New("not found")
+}
+
+func (r *memoryRepository) MarkPublished(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.update(id, func(e *Event) {
+		e.PublishedAt = &at
+	})
+}
+
+func (r *memoryRepository) MarkFailed(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return r.update(id, func(e *Event) {
+		e.Attempts = attempts
+		e.NextAttemptAt = nextAttemptAt
+		e.LastError = lastError
+	})
+}
+
+type recordingPublisher struct {
+	published []*Event
+	fail      map[uuid.UUID]bool
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e *Event) error {
+	if p.fail[e.UUID] {
+		return errors.New("bus unavailable")
+	}
+
+	p.published = append(p.published, e)
+	return nil
+}
+
+func addEvent(t *testing.T, r *memoryRepository, eventType Type, userUUID uuid.UUID) *Event {
+	e, err := New(eventType, userUUID, UserPayload{UUID: userUUID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Add(context.Background(), e)
+	return e
+}
+
+func TestRelay_Relay(t *testing.T) {
+	repository := &memoryRepository{}
+	alice, bob := uuid.New(), uuid.New()
+	created := addEvent(t, repository, USER_CREATED, alice)
+	updated := addEvent(t, repository, USER_UPDATED, alice)
+	other := addEvent(t, repository, USER_CREATED, bob)
+
+	publisher := &recordingPublisher{}
+	relay := NewRelay(repository, publisher, DefaultRelayPolicy)
+	noError := func(err error) { t.Error(err) }
+
+	claimed, err := relay.Relay(context.Background(), noError)
+	if err != nil || claimed != 2 {
+		t.Fatalf("Want the first event of each user claimed, got %d (%v)", claimed, err)
+	}
+
+	claimed, _ = relay.Relay(context.Background(), noError)
+	if claimed != 1 {
+		t.Fatalf("Want the second event of the user claimed, got %d", claimed)
+	}
+
+	want := []uuid.UUID{created.UUID, other.UUID, updated.UUID}
+	if len(publisher.published) != len(want) {
+		t.Fatalf("Want %d published events, got %d", len(want), len(publisher.published))
+	}
+
+	for i, id := range want {
+		if publisher.published[i].UUID != id {
+			t.Errorf("Want event %s published at %d, got %s", id, i, publisher.published[i].UUID)
+		}
+	}
+
+	claimed, _ = relay.Relay(context.Background(), noError)
+	if claimed != 0 {
+		t.Errorf("Want published events to stay published, claimed %d", claimed)
+	}
+}
+
+func TestRelay_RelayRetries(t *testing.T) {
+	repository := &memoryRepository{}
+	alice := uuid.New()
+	created := addEvent(t, repository, USER_CREATED, alice)
+	updated := addEvent(t, repository, USER_UPDATED, alice)
+
+	publisher := &recordingPublisher{fail: map[uuid.UUID]bool{created.UUID: true}}
+	relay := NewRelay(repository, publisher, DefaultRelayPolicy)
+
+	failures := 0
+	for attempt := 1; attempt <= 3; attempt++ {
+		before := time.Now()
+		if _, err := relay.Relay(context.Background(), func(err error) { failures++ }); err != nil {
+			t.Fatal(err)
+		}
+
+		e := repository.events[0]
+		if e.Attempts != attempt || e.LastError != "bus unavailable" || e.NextAttemptAt.Before(before.Add(DefaultRelayPolicy.Backoff(attempt))) {
+			t.Fatalf("Want a retry after %s, got %+v", DefaultRelayPolicy.Backoff(attempt), e)
+		}
+
+		// the backoff has passed
+		e.NextAttemptAt = time.Now()
+	}
+
+	if failures != 3 || len(publisher.published) != 0 {
+		t.Fatalf("Want later events of the user held back, got %d published", len(publisher.published))
+	}
+
+	delete(publisher.fail, created.UUID)
+	relay.Relay(context.Background(), func(err error) { t.Error(err) })
+	relay.Relay(context.Background(), func(err error) { t.Error(err) })
+
+	if len(publisher.published) != 2 || publisher.published[0].UUID != created.UUID || publisher.published[1].UUID != updated.UUID {
+		t.Errorf("Want the events published in order once the bus is back, got %+v", publisher.published)
+	}
+}
@@ -0,0 +1,133 @@
+package household
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MemberRole struct {
+	v string
+}
+
+var (
+	UNKNOWN_ROLE = MemberRole{"UNKNOWN"}
+	OWNER        = MemberRole{"owner"}
+	MEMBER       = MemberRole{"member"}
+)
+
+func (c MemberRole) String() string {
+	return c.v
+}
+
+func RoleFromString(value string) (MemberRole, error) {
+	switch value {
+	case OWNER.String():
+		return OWNER, nil
+	case MEMBER.String():
+		return MEMBER, nil
+	}
+
+	return UNKNOWN_ROLE, errors.New("Invalid member role value")
+}
+
+type InvitationStatus struct {
+	v string
+}
+
+var (
+	UNKNOWN_STATUS = InvitationStatus{"UNKNOWN"}
+	PENDING        = InvitationStatus{"pending"}
+	ACCEPTED       = InvitationStatus{"accepted"}
+	DECLINED       = InvitationStatus{"declined"}
+)
+
+func (c InvitationStatus) String() string {
+	return c.v
+}
+
+func StatusFromString(value string) (InvitationStatus, error) {
+	switch value {
+	case PENDING.String():
+		return PENDING, nil
+	case ACCEPTED.String():
+		return ACCEPTED, nil
+	case DECLINED.String():
+		return DECLINED, nil
+	}
+
+	return UNKNOWN_STATUS, errors.New("Invalid invitation status value")
+}
+
+type Household struct {
+	UUID      uuid.UUID
+	Name      string
+	OwnerUUID uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Member struct {
+	HouseholdUUID uuid.UUID
+	UserUUID      uuid.UUID
+	Name          string
+	Email         string
+	Role          MemberRole
+	CreatedAt     time.Time
+}
+
+type Invitation struct {
+	UUID          uuid.UUID
+	HouseholdUUID uuid.UUID
+	HouseholdName string
+	Email         string
+	InviterUUID   uuid.UUID
+	Status        InvitationStatus
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (i *Invitation) IsExpired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+// Details is a household with its members as seen by one of them
+type Details struct {
+	Household *Household
+	Members   []*Member
+}
+
+type HouseholdManager interface {
+	Create(ctx context.Context, userUUID uuid.UUID, name string) (*Details, error)
+	List(ctx context.Context, userUUID uuid.UUID) ([]*Household, error)
+	Get(ctx context.Context, userUUID, householdUUID uuid.UUID) (*Details, error)
+	Invite(ctx context.Context, r *InviteRequest) (*Invitation, error)
+	ListInvitations(ctx context.Context, userUUID uuid.UUID) ([]*Invitation, error)
+	AcceptInvitation(ctx context.Context, userUUID, invitationUUID uuid.UUID) (*Details, error)
+	DeclineInvitation(ctx context.Context, userUUID, invitationUUID uuid.UUID) error
+	Leave(ctx context.Context, userUUID, householdUUID uuid.UUID) error
+	SetActive(ctx context.Context, userUUID, householdUUID uuid.UUID) error
+}
+
+type Repository interface {
+	Add(ctx context.Context, household *Household) error
+	FindById(ctx context.Context, uuid uuid.UUID) (*Household, error)
+	ListForUser(ctx context.Context, userUUID uuid.UUID) ([]*Household, error)
+	Delete(ctx context.Context, uuid uuid.UUID) error
+	AddMember(ctx context.Context, member *Member) error
+	FindMember(ctx context.Context, householdUUID, userUUID uuid.UUID) (*Member, error)
+	Members(ctx context.Context, householdUUID uuid.UUID) ([]*Member, error)
+	RemoveMember(ctx context.Context, householdUUID, userUUID uuid.UUID) error
+	AddInvitation(ctx context.Context, invitation *Invitation) error
+	FindInvitation(ctx context.Context, uuid uuid.UUID) (*Invitation, error)
+	PendingInvitationsForEmail(ctx context.Context, email string) ([]*Invitation, error)
+	UpdateInvitationStatus(ctx context.Context, uuid uuid.UUID, status InvitationStatus) error
+	Transactional(ctx context.Context, cb func(r Repository) error) error
+}
+
+type Mailer interface {
+	Send(ctx context.Context, recipient, template, locale string, data interface{}) error
+}
@@ -0,0 +1,316 @@
+package household
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/user"
+)
+
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+type InviteRequest struct {
+	InviterUUID   uuid.UUID
+	HouseholdUUID uuid.UUID
+	Email         string
+}
+
+type Service struct {
+	repository     Repository
+	userRepository user.UserRepository
+	transactor     user.Transactor
+	mailer         Mailer
+	invitationTTL  time.Duration
+	frontendUrl    string
+}
+
+func NewService(r Repository, ur user.UserRepository, tx user.Transactor, mailer Mailer, invitationTTL time.Duration, frontendUrl string) *Service {
+	if invitationTTL == 0 {
+		invitationTTL = DefaultInvitationTTL
+	}
+
+	return &Service{
+		repository:     r,
+		userRepository: ur,
+		transactor:     tx,
+		mailer:         mailer,
+		invitationTTL:  invitationTTL,
+		frontendUrl:    strings.TrimRight(frontendUrl, "/"),
+	}
+}
+
+func (h *Service) Create(ctx context.Context, userUUID uuid.UUID, name string) (*Details, error) {
+	owner, err := h.userRepository.FindById(ctx, userUUID)
+	if err != nil {
+		return &Details{}, err
+	}
+
+	now := time.Now()
+	household := &Household{
+		UUID:      uuid.New(),
+		Name:      name,
+		OwnerUUID: owner.UUID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	member := &Member{
+		HouseholdUUID: household.UUID,
+		UserUUID:      owner.UUID,
+		Name:          owner.Name,
+		Email:         owner.Email,
+		Role:          OWNER,
+		CreatedAt:     now,
+	}
+
+	err = h.repository.Transactional(ctx, func(r Repository) error {
+		if err := r.Add(ctx, household); err != nil {
+			return err
+		}
+
+		return r.AddMember(ctx, member)
+	})
+	if err != nil {
+		return &Details{}, appErr.NewAppError(err.Error(), "household-saving-error")
+	}
+
+	if owner.ActiveHouseholdUUID == uuid.Nil {
+		if err := h.userRepository.SetActiveHousehold(ctx, owner.UUID, household.UUID); err != nil {
+			return &Details{}, err
+		}
+	}
+
+	return &Details{
+		Household: household,
+		Members:   []*Member{member},
+	}, nil
+}
+
+func (h *Service) List(ctx context.Context, userUUID uuid.UUID) ([]*Household, error) {
+	return h.repository.ListForUser(ctx, userUUID)
+}
+
+func (h *Service) Get(ctx context.Context, userUUID, householdUUID uuid.UUID) (*Details, error) {
+	if _, err := h.repository.FindMember(ctx, householdUUID, userUUID); err != nil {
+		return &Details{}, err
+	}
+
+	return h.details(ctx, householdUUID)
+}
+
+func (h *Service) Invite(ctx context.Context, r *InviteRequest) (*Invitation, error) {
+	member, err := h.repository.FindMember(ctx, r.HouseholdUUID, r.InviterUUID)
+	if err != nil {
+		return &Invitation{}, err
+	}
+
+	if member.Role != OWNER {
+		return &Invitation{}, appErr.NewForbiddenError("Only the owner can invite members", "household-owner-required")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(r.Email))
+	if strings.EqualFold(email, member.Email) {
+		return &Invitation{}, appErr.NewIncorrectInputError("Can not invite yourself", "household-invitation-self")
+	}
+
+	members, err := h.repository.Members(ctx, r.HouseholdUUID)
+	if err != nil {
+		return &Invitation{}, err
+	}
+
+	for _, m := range members {
+		if strings.EqualFold(m.Email, email) {
+			return &Invitation{}, appErr.NewIncorrectInputError("User is already a member", "household-already-member")
+		}
+	}
+
+	household, err := h.repository.FindById(ctx, r.HouseholdUUID)
+	if err != nil {
+		return &Invitation{}, err
+	}
+
+	now := time.Now()
+	invitation := &Invitation{
+		UUID:          uuid.New(),
+		HouseholdUUID: household.UUID,
+		HouseholdName: household.Name,
+		Email:         email,
+		InviterUUID:   r.InviterUUID,
+		Status:        PENDING,
+		ExpiresAt:     now.Add(h.invitationTTL),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// the invitation is only mailed once it is stored
+	err = h.transactor.Transactional(ctx, func(ctx context.Context) error {
+		if err := h.repository.AddInvitation(ctx, invitation); err != nil {
+			return err
+		}
+
+		return h.mailer.Send(ctx, email, "household-invitation", user.ClientInfoFromContext(ctx).Locale, map[string]interface{}{
+			"InviterName":   member.Name,
+			"HouseholdName": invitation.HouseholdName,
+			"Link":          fmt.Sprintf("%s/invitations/%s", h.frontendUrl, invitation.UUID),
+			"ExpiresAt":     invitation.ExpiresAt.Format("2 January 2006"),
+		})
+	})
+	if err != nil {
+		return &Invitation{}, appErr.NewAppError(err.Error(), "household-invitation-error")
+	}
+
+	return invitation, nil
+}
+
+func (h *Service) ListInvitations(ctx context.Context, userUUID uuid.UUID) ([]*Invitation, error) {
+	u, err := h.userRepository.FindById(ctx, userUUID)
+	if err != nil {
+		return []*Invitation{}, err
+	}
+
+	return h.repository.PendingInvitationsForEmail(ctx, strings.ToLower(u.Email))
+}
+
+func (h *Service) AcceptInvitation(ctx context.Context, userUUID, invitationUUID uuid.UUID) (*Details, error) {
+	u, invitation, err := h.pendingInvitation(ctx, userUUID, invitationUUID)
+	if err != nil {
+		return &Details{}, err
+	}
+
+	err = h.repository.Transactional(ctx, func(r Repository) error {
+		_, err := r.FindMember(ctx, invitation.HouseholdUUID, u.UUID)
+		if err == nil {
+			return r.UpdateInvitationStatus(ctx, invitation.UUID, ACCEPTED)
+		} else if !appErr.IsNotFound(err) {
+			return err
+		}
+
+		err = r.AddMember(ctx, &Member{
+			HouseholdUUID: invitation.HouseholdUUID,
+			UserUUID:      u.UUID,
+			Role:          MEMBER,
+			CreatedAt:     time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return r.UpdateInvitationStatus(ctx, invitation.UUID, ACCEPTED)
+	})
+	if err != nil {
+		return &Details{}, appErr.NewAppError(err.Error(), "household-saving-error")
+	}
+
+	if u.ActiveHouseholdUUID == uuid.Nil {
+		if err := h.userRepository.SetActiveHousehold(ctx, u.UUID, invitation.HouseholdUUID); err != nil {
+			return &Details{}, err
+		}
+	}
+
+	return h.details(ctx, invitation.HouseholdUUID)
+}
+
+func (h *Service) DeclineInvitation(ctx context.Context, userUUID, invitationUUID uuid.UUID) error {
+	_, invitation, err := h.pendingInvitation(ctx, userUUID, invitationUUID)
+	if err != nil {
+		return err
+	}
+
+	return h.repository.UpdateInvitationStatus(ctx, invitation.UUID, DECLINED)
+}
+
+func (h *Service) Leave(ctx context.Context, userUUID, householdUUID uuid.UUID) error {
+	member, err := h.repository.FindMember(ctx, householdUUID, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == OWNER {
+		members, err := h.repository.Members(ctx, householdUUID)
+		if err != nil {
+			return err
+		}
+
+		if len(members) > 1 {
+			return appErr.NewIncorrectInputError("Owner can not leave a household with members", "household-owner-cannot-leave")
+		}
+
+		// the last member leaving removes the household, active household references are nulled by the database
+		return h.repository.Delete(ctx, householdUUID)
+	}
+
+	err = h.repository.RemoveMember(ctx, householdUUID, userUUID)
+	if err != nil {
+		return err
+	}
+
+	u, err := h.userRepository.FindById(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if u.ActiveHouseholdUUID == householdUUID {
+		return h.userRepository.SetActiveHousehold(ctx, userUUID, uuid.Nil)
+	}
+
+	return nil
+}
+
+// SetActive selects the household put into the "hid" claim of newly issued access tokens, uuid.Nil clears it
+func (h *Service) SetActive(ctx context.Context, userUUID, householdUUID uuid.UUID) error {
+	if householdUUID != uuid.Nil {
+		if _, err := h.repository.FindMember(ctx, householdUUID, userUUID); err != nil {
+			return err
+		}
+	}
+
+	return h.userRepository.SetActiveHousehold(ctx, userUUID, householdUUID)
+}
+
+func (h *Service) details(ctx context.Context, householdUUID uuid.UUID) (*Details, error) {
+	household, err := h.repository.FindById(ctx, householdUUID)
+	if err != nil {
+		return &Details{}, err
+	}
+
+	members, err := h.repository.Members(ctx, householdUUID)
+	if err != nil {
+		return &Details{}, err
+	}
+
+	return &Details{
+		Household: household,
+		Members:   members,
+	}, nil
+}
+
+func (h *Service) pendingInvitation(ctx context.Context, userUUID, invitationUUID uuid.UUID) (*user.User, *Invitation, error) {
+	u, err := h.userRepository.FindById(ctx, userUUID)
+	if err != nil {
+		return &user.User{}, &Invitation{}, err
+	}
+
+	invitation, err := h.repository.FindInvitation(ctx, invitationUUID)
+	if err != nil {
+		return &user.User{}, &Invitation{}, err
+	}
+
+	// invitations addressed to someone else are reported as missing to avoid leaking them
+	if !strings.EqualFold(invitation.Email, u.Email) {
+		return &user.User{}, &Invitation{}, appErr.NewNotFoundError("Invitation not found", "household-invitation-not-found")
+	}
+
+	if invitation.Status != PENDING {
+		return &user.User{}, &Invitation{}, appErr.NewIncorrectInputError("Invitation is already answered", "household-invitation-answered")
+	}
+
+	if invitation.IsExpired(time.Now()) {
+		return &user.User{}, &Invitation{}, appErr.NewIncorrectInputError("Invitation expired", "household-invitation-expired")
+	}
+
+	return u, invitation, nil
+}
@@ -0,0 +1,472 @@
+package household
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/role"
+	"github.com/ibgl/microservice-users/internal/app/user"
+)
+
+type memoryUserRepository struct {
+	user.UserRepository
+	users map[uuid.UUID]*user.User
+}
+
+func (r *memoryUserRepository) FindById(ctx context.Context, userUUID uuid.UUID) (*user.User, error) {
+	u, ok := r.users[userUUID]
+	if !ok {
+		return &user.User{}, appErr.NewNotFoundError("User not found", "user-not-found")
+	}
+
+	return u, nil
+}
+
+func (r *memoryUserRepository) SetActiveHousehold(ctx context.Context, userUUID, householdUUID uuid.UUID) error {
+	r.users[userUUID].ActiveHouseholdUUID = householdUUID
+	return nil
+}
+
+// memoryRepository joins the members with the users like the pgsql repository does
+type memoryRepository struct {
+	users       *memoryUserRepository
+	households  map[uuid.UUID]*Household
+	members     []*Member
+	invitations map[uuid.UUID]*Invitation
+}
+
+func (r *memoryRepository) Add(ctx context.Context, household *Household) error {
+	r.households[household.UUID] = household
+	return nil
+}
+
+func (r *memoryRepository) FindById(ctx context.Context, householdUUID uuid.UUID) (*Household, error) {
+	household, ok := r.households[householdUUID]
+	if !ok {
+		return &Household{}, appErr.NewNotFoundError("Not found", "household-not-found")
+	}
+
+	return household, nil
+}
+
+func (r *memoryRepository) ListForUser(ctx context.Context, userUUID uuid.UUID) ([]*Household, error) {
+	households := []*Household{}
+	for _, m := range r.members {
+		if m.UserUUID == userUUID {
+			households = append(households, r.households[m.HouseholdUUID])
+		}
+	}
+
+	return households, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, householdUUID uuid.UUID) error {
+	delete(r.households, householdUUID)
+
+	members := []*Member{}
+	for _, m := range r.members {
+		if m.HouseholdUUID != householdUUID {
+			members = append(members, m)
+		}
+	}
+	r.members = members
+
+	for _, u := range r.users.users {
+		if u.ActiveHouseholdUUID == householdUUID {
+			u.ActiveHouseholdUUID = uuid.Nil
+		}
+	}
+
+	return nil
+}
+
+func (r *memoryRepository) AddMember(ctx context.Context, member *Member) error {
+	r.members = append(r.members, member)
+	return nil
+}
+
+func (r *memoryRepository) FindMember(ctx context.Context, householdUUID, userUUID uuid.UUID) (*Member, error) {
+	for _, m := range r.members {
+		if m.HouseholdUUID == householdUUID && m.UserUUID == userUUID {
+			return r.joined(m), nil
+		}
+	}
+
+	return &Member{}, appErr.NewNotFoundError("Not found", "household-not-found")
+}
+
+func (r *memoryRepository) Members(ctx context.Context, householdUUID uuid.UUID) ([]*Member, error) {
+	members := []*Member{}
+	for _, m := range r.members {
+		if m.HouseholdUUID == householdUUID {
+			members = append(members, r.joined(m))
+		}
+	}
+
+	return members, nil
+}
+
+func (r *memoryRepository) RemoveMember(ctx context.Context, householdUUID, userUUID uuid.UUID) error {
+	members := []*Member{}
+	for _, m := range r.members {
+		if m.HouseholdUUID != householdUUID || m.UserUUID != userUUID {
+			members = append(members, m)
+		}
+	}
+	r.members = members
+
+	return nil
+}
+
+func (r *memoryRepository) AddInvitation(ctx context.Context, invitation *Invitation) error {
+	r.invitations[invitation.UUID] = invitation
+	return nil
+}
+
+func (r *memoryRepository) FindInvitation(ctx context.Context, invitationUUID uuid.UUID) (*Invitation, error) {
+	invitation, ok := r.invitations[invitationUUID]
+	if !ok {
+		return &Invitation{}, appErr.NewNotFoundError("Not found", "household-invitation-not-found")
+	}
+
+	return invitation, nil
+}
+
+func (r *memoryRepository) PendingInvitationsForEmail(ctx context.Context, email string) ([]*Invitation, error) {
+	invitations := []*Invitation{}
+	for _, i := range r.invitations {
+		if i.Email == email && i.Status == PENDING {
+			invitations = append(invitations, i)
+		}
+	}
+
+	return invitations, nil
+}
+
+func (r *memoryRepository) UpdateInvitationStatus(ctx context.Context, invitationUUID uuid.UUID, status InvitationStatus) error {
+	r.invitations[invitationUUID].Status = status
+	return nil
+}
+
+func (r *memoryRepository) Transactional(ctx context.Context, cb func(r Repository) error) error {
+	return cb(r)
+}
+
+func (r *memoryRepository) joined(m *Member) *Member {
+	joined := *m
+	if u, ok := r.users.users[m.UserUUID]; ok {
+		joined.Name = u.Name
+		joined.Email = u.Email
+	}
+
+	return &joined
+}
+
+type transactor struct{}
+
+func (transactor) Transactional(ctx context.Context, cb func(ctx context.Context) error) error {
+	return cb(ctx)
+}
+
+type sentMail struct {
+	recipient string
+	template  string
+	data      map[string]interface{}
+}
+
+type mailer struct {
+	sent []sentMail
+	err  error
+}
+
+func (m *mailer) Send(ctx context.Context, recipient, template, locale string, data interface{}) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.sent = append(m.sent, sentMail{recipient, template, data.(map[string]interface{})})
+	return nil
+}
+
+type fixture struct {
+	service *Service
+	repo    *memoryRepository
+	mailer  *mailer
+	owner   *user.User
+	member  *user.User
+	guest   *user.User
+	home    *Household
+}
+
+// newFixture sets up a household of the owner with one member and a guest who belongs to no household
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	newUser := func(email, name string) *user.User {
+		return user.NewUser(uuid.New(), email, name, "hash", role.USER, user.DefaultUserSettings(), time.Now(), time.Now())
+	}
+
+	f := &fixture{
+		owner:  newUser("owner@example.com", "Owner"),
+		member: newUser("member@example.com", "Member"),
+		guest:  newUser("guest@example.com", "Guest"),
+		mailer: &mailer{},
+	}
+
+	users := &memoryUserRepository{users: map[uuid.UUID]*user.User{f.owner.UUID: f.owner, f.member.UUID: f.member, f.guest.UUID: f.guest}}
+	f.repo = &memoryRepository{users: users, households: map[uuid.UUID]*Household{}, invitations: map[uuid.UUID]*Invitation{}}
+	f.service = NewService(f.repo, users, transactor{}, f.mailer, 0, "https://app.example.com/")
+
+	details, err := f.service.Create(context.Background(), f.owner.UUID, "Home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.home = details.Household
+
+	f.repo.AddMember(context.Background(), &Member{HouseholdUUID: f.home.UUID, UserUUID: f.member.UUID, Role: MEMBER, CreatedAt: time.Now()})
+	f.member.ActiveHouseholdUUID = f.home.UUID
+
+	return f
+}
+
+func (f *fixture) invitation(email string, status InvitationStatus, expiresAt time.Time) *Invitation {
+	invitation := &Invitation{
+		UUID:          uuid.New(),
+		HouseholdUUID: f.home.UUID,
+		HouseholdName: f.home.Name,
+		Email:         email,
+		InviterUUID:   f.owner.UUID,
+		Status:        status,
+		ExpiresAt:     expiresAt,
+	}
+	f.repo.invitations[invitation.UUID] = invitation
+
+	return invitation
+}
+
+func assertSlug(t *testing.T, err error, slug string) {
+	t.Helper()
+
+	if slug == "" {
+		if err != nil {
+			t.Fatalf("Want no error, got '%v'", err)
+		}
+		return
+	}
+
+	if appError, ok := err.(appErr.AppError); !ok || appError.Slug() != slug {
+		t.Fatalf("Want slug '%s', got '%v'", slug, err)
+	}
+}
+
+func TestService_Create(t *testing.T) {
+	f := newFixture(t)
+
+	if f.owner.ActiveHouseholdUUID != f.home.UUID {
+		t.Errorf("Want the first household active, got %s", f.owner.ActiveHouseholdUUID)
+	}
+
+	details, err := f.service.Create(context.Background(), f.owner.UUID, "Summer house")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if details.Household.OwnerUUID != f.owner.UUID || len(details.Members) != 1 || details.Members[0].Role != OWNER {
+		t.Errorf("Want the creator as the only owner, got %+v", details.Members)
+	}
+
+	if f.owner.ActiveHouseholdUUID != f.home.UUID {
+		t.Errorf("Want the active household kept, got %s", f.owner.ActiveHouseholdUUID)
+	}
+}
+
+func TestService_Invite(t *testing.T) {
+	tt := []struct {
+		name    string
+		inviter func(f *fixture) uuid.UUID
+		email   string
+		mailErr error
+		slug    string
+	}{
+		{name: "Owner invites", inviter: func(f *fixture) uuid.UUID { return f.owner.UUID }, email: " Guest@Example.com "},
+		{name: "Member can not invite", inviter: func(f *fixture) uuid.UUID { return f.member.UUID }, email: "guest@example.com", slug: "household-owner-required"},
+		{name: "Outsider can not invite", inviter: func(f *fixture) uuid.UUID { return f.guest.UUID }, email: "other@example.com", slug: "household-not-found"},
+		{name: "Owner invites their own email", inviter: func(f *fixture) uuid.UUID { return f.owner.UUID }, email: "OWNER@example.com", slug: "household-invitation-self"},
+		{name: "Member is invited again", inviter: func(f *fixture) uuid.UUID { return f.owner.UUID }, email: "member@example.com", slug: "household-already-member"},
+		{name: "Mail is not queued", inviter: func(f *fixture) uuid.UUID { return f.owner.UUID }, email: "guest@example.com", mailErr: errors.New("outbox is down"), slug: "household-invitation-error"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.mailer.err = tc.mailErr
+
+			invitation, err := f.service.Invite(context.Background(), &InviteRequest{InviterUUID: tc.inviter(f), HouseholdUUID: f.home.UUID, Email: tc.email})
+			assertSlug(t, err, tc.slug)
+			if tc.slug != "" {
+				return
+			}
+
+			if invitation.Email != "guest@example.com" || invitation.Status != PENDING || invitation.HouseholdName != "Home" {
+				t.Errorf("Want a pending invitation of the normalized email, got %+v", invitation)
+			}
+
+			if len(f.mailer.sent) != 1 || f.mailer.sent[0].template != "household-invitation" || f.mailer.sent[0].recipient != "guest@example.com" {
+				t.Fatalf("Want the invitation mailed, got %+v", f.mailer.sent)
+			}
+
+			if link := f.mailer.sent[0].data["Link"]; link != "https://app.example.com/invitations/"+invitation.UUID.String() {
+				t.Errorf("Want the invitation link, got '%v'", link)
+			}
+		})
+	}
+}
+
+func TestService_AcceptInvitation(t *testing.T) {
+	tt := []struct {
+		name       string
+		email      string
+		status     InvitationStatus
+		expiresAt  time.Time
+		slug       string
+		wantMember bool
+	}{
+		{name: "Pending invitation", email: "guest@example.com", status: PENDING, expiresAt: time.Now().Add(time.Hour), wantMember: true},
+		{name: "Invitation of someone else", email: "other@example.com", status: PENDING, expiresAt: time.Now().Add(time.Hour), slug: "household-invitation-not-found"},
+		{name: "Declined invitation", email: "guest@example.com", status: DECLINED, expiresAt: time.Now().Add(time.Hour), slug: "household-invitation-answered"},
+		{name: "Expired invitation", email: "guest@example.com", status: PENDING, expiresAt: time.Now().Add(-time.Minute), slug: "household-invitation-expired"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture(t)
+			invitation := f.invitation(tc.email, tc.status, tc.expiresAt)
+
+			details, err := f.service.AcceptInvitation(context.Background(), f.guest.UUID, invitation.UUID)
+			assertSlug(t, err, tc.slug)
+
+			_, memberErr := f.repo.FindMember(context.Background(), f.home.UUID, f.guest.UUID)
+			if isMember := memberErr == nil; isMember != tc.wantMember {
+				t.Fatalf("Want member '%t', got '%t'", tc.wantMember, isMember)
+			}
+
+			if !tc.wantMember {
+				return
+			}
+
+			if invitation.Status != ACCEPTED || len(details.Members) != 3 {
+				t.Errorf("Want the invitation accepted and three members, got %s and %d", invitation.Status, len(details.Members))
+			}
+
+			if f.guest.ActiveHouseholdUUID != f.home.UUID {
+				t.Errorf("Want the household active for the new member, got %s", f.guest.ActiveHouseholdUUID)
+			}
+		})
+	}
+}
+
+func TestService_DeclineInvitation(t *testing.T) {
+	f := newFixture(t)
+	invitation := f.invitation("guest@example.com", PENDING, time.Now().Add(time.Hour))
+
+	if err := f.service.DeclineInvitation(context.Background(), f.guest.UUID, invitation.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if invitation.Status != DECLINED {
+		t.Errorf("Want the invitation declined, got %s", invitation.Status)
+	}
+
+	err := f.service.DeclineInvitation(context.Background(), f.guest.UUID, invitation.UUID)
+	assertSlug(t, err, "household-invitation-answered")
+}
+
+func TestService_Leave(t *testing.T) {
+	tt := []struct {
+		name          string
+		leaving       func(f *fixture) *user.User
+		alone         bool
+		slug          string
+		wantHousehold bool
+	}{
+		{name: "Member leaves", leaving: func(f *fixture) *user.User { return f.member }, wantHousehold: true},
+		{name: "Owner of members", leaving: func(f *fixture) *user.User { return f.owner }, slug: "household-owner-cannot-leave", wantHousehold: true},
+		{name: "Last owner", leaving: func(f *fixture) *user.User { return f.owner }, alone: true},
+		{name: "Outsider", leaving: func(f *fixture) *user.User { return f.guest }, slug: "household-not-found", wantHousehold: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFixture(t)
+			if tc.alone {
+				f.repo.RemoveMember(context.Background(), f.home.UUID, f.member.UUID)
+			}
+
+			leaving := tc.leaving(f)
+			err := f.service.Leave(context.Background(), leaving.UUID, f.home.UUID)
+			assertSlug(t, err, tc.slug)
+
+			_, householdErr := f.repo.FindById(context.Background(), f.home.UUID)
+			if exists := householdErr == nil; exists != tc.wantHousehold {
+				t.Fatalf("Want household '%t', got '%t'", tc.wantHousehold, exists)
+			}
+
+			if tc.slug != "" {
+				return
+			}
+
+			if _, err := f.repo.FindMember(context.Background(), f.home.UUID, leaving.UUID); !appErr.IsNotFound(err) {
+				t.Errorf("Want the membership removed, got '%v'", err)
+			}
+
+			if leaving.ActiveHouseholdUUID != uuid.Nil {
+				t.Errorf("Want the active household cleared, got %s", leaving.ActiveHouseholdUUID)
+			}
+		})
+	}
+}
+
+func TestService_SetActive(t *testing.T) {
+	f := newFixture(t)
+
+	err := f.service.SetActive(context.Background(), f.guest.UUID, f.home.UUID)
+	assertSlug(t, err, "household-not-found")
+
+	if err := f.service.SetActive(context.Background(), f.member.UUID, uuid.Nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.member.ActiveHouseholdUUID != uuid.Nil {
+		t.Errorf("Want the active household cleared, got %s", f.member.ActiveHouseholdUUID)
+	}
+
+	if err := f.service.SetActive(context.Background(), f.member.UUID, f.home.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.member.ActiveHouseholdUUID != f.home.UUID {
+		t.Errorf("Want the household active, got %s", f.member.ActiveHouseholdUUID)
+	}
+}
+
+func TestService_ListInvitations(t *testing.T) {
+	f := newFixture(t)
+	pending := f.invitation("guest@example.com", PENDING, time.Now().Add(time.Hour))
+	f.invitation("guest@example.com", DECLINED, time.Now().Add(time.Hour))
+	f.invitation("other@example.com", PENDING, time.Now().Add(time.Hour))
+
+	f.guest.Email = strings.ToUpper(f.guest.Email)
+	invitations, err := f.service.ListInvitations(context.Background(), f.guest.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(invitations) != 1 || invitations[0].UUID != pending.UUID {
+		t.Errorf("Want the pending invitation of the user, got %+v", invitations)
+	}
+}
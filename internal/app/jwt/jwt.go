@@ -9,19 +9,41 @@ import (
 )
 
 type JWTService struct {
-	secret     string
-	accessTTL  int
-	refreshTTL int
+	secret           string
+	accessTTL        int
+	refreshTTL       int
+	impersonationTTL int
+	legacyCutoff     time.Time
 }
 
+// ActorClaims identifies the party acting on behalf of the token subject (RFC 8693 "act" claim)
+type ActorClaims struct {
+	Sub string `json:"sub"`
+}
+
+// AccessClaims.HouseholdId is the active household of the user, downstream services authorize shared data with it
 type AccessClaims struct {
-	UserId uuid.UUID
+	UserId      uuid.UUID
+	Type        string       `json:"typ,omitempty"`
+	ClientId    string       `json:"client_id,omitempty"`
+	Scope       string       `json:"scope,omitempty"`
+	HouseholdId string       `json:"hid,omitempty"`
+	Act         *ActorClaims `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type RefreshClaims struct {
 	UUID   uuid.UUID
 	UserId uuid.UUID
+	Type   string `json:"typ,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SessionReportClaims back the "this wasn't me" link of new sign in emails. Binding ties the link to the
+// password hash at sign in time so that it stops working once the password was reset.
+type SessionReportClaims struct {
+	UserId  uuid.UUID
+	Binding string
 	jwt.RegisteredClaims
 }
 
@@ -35,10 +57,26 @@ type RefreshJWT struct {
 	Token  string
 }
 
+type SessionReportJWT struct {
+	Claims SessionReportClaims
+	Token  string
+}
+
+const sessionReportAudience = "session-report"
+
+// Access and refresh tokens share the secret, the typ claim keeps one from being accepted as the other
+const (
+	AccessType  = "access"
+	RefreshType = "refresh"
+)
+
+// JWTConfig.LegacyCutoff is the time untyped access tokens were last issued, it defaults to the service start
 type JWTConfig struct {
-	Secret     string
-	AccessTTL  int
-	RefreshTTL int
+	Secret           string
+	AccessTTL        int
+	RefreshTTL       int
+	ImpersonationTTL int
+	LegacyCutoff     time.Time
 }
 
 func NewAccessClaims(UserId uuid.UUID, Email, Name string) *AccessClaims {
@@ -47,6 +85,16 @@ func NewAccessClaims(UserId uuid.UUID, Email, Name string) *AccessClaims {
 	}
 }
 
+// NewClientAccessClaims creates claims for machine-to-machine tokens which are issued to no user
+func NewClientAccessClaims(clientId string) *AccessClaims {
+	return &AccessClaims{
+		ClientId: clientId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: clientId,
+		},
+	}
+}
+
 func NewRefreshClaims(UserId uuid.UUID) *RefreshClaims {
 	return &RefreshClaims{
 		UserId: UserId,
@@ -54,15 +102,80 @@ func NewRefreshClaims(UserId uuid.UUID) *RefreshClaims {
 }
 
 func NewJwtService(config *JWTConfig) *JWTService {
+	legacyCutoff := config.LegacyCutoff
+	if legacyCutoff.IsZero() {
+		legacyCutoff = time.Now()
+	}
+
 	return &JWTService{
-		secret:     config.Secret,
-		accessTTL:  config.AccessTTL,
-		refreshTTL: config.RefreshTTL,
+		secret:           config.Secret,
+		accessTTL:        config.AccessTTL,
+		refreshTTL:       config.RefreshTTL,
+		impersonationTTL: config.ImpersonationTTL,
+		legacyCutoff:     legacyCutoff,
+	}
+}
+
+// ActorId returns the UUID of the acting party or uuid.Nil for regular tokens
+func (c AccessClaims) ActorId() uuid.UUID {
+	if c.Act == nil {
+		return uuid.Nil
 	}
+
+	actor, err := uuid.Parse(c.Act.Sub)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	return actor
+}
+
+// IsLegacy reports whether the token was issued before access tokens were typed, it carries no scope claim
+func (c AccessClaims) IsLegacy() bool {
+	return c.Type == ""
+}
+
+// ActiveHousehold returns the household selected by the user or uuid.Nil
+func (c AccessClaims) ActiveHousehold() uuid.UUID {
+	household, err := uuid.Parse(c.HouseholdId)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	return household
+}
+
+func (h *JWTService) AccessTTL() time.Duration {
+	return time.Duration(h.accessTTL) * time.Second
 }
 
 func (h *JWTService) CreateAccess(c AccessClaims) (*AccessJWT, error) {
-	c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Duration(h.accessTTL) * time.Second))
+	now := time.Now()
+	c.Type = AccessType
+	c.IssuedAt = jwt.NewNumericDate(now)
+	c.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(h.accessTTL) * time.Second))
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+
+	sign, err := token.SignedString([]byte(h.secret))
+	if err != nil {
+		return &AccessJWT{}, err
+	}
+
+	return &AccessJWT{
+		Claims: c,
+		Token:  sign,
+	}, nil
+}
+
+// CreateImpersonationAccess issues a short-lived access token for the claims subject
+// carrying the actor claim. The token ID is set to the given impersonation UUID.
+func (h *JWTService) CreateImpersonationAccess(c AccessClaims, actor, impersonation uuid.UUID) (*AccessJWT, error) {
+	now := time.Now()
+	c.Type = AccessType
+	c.Act = &ActorClaims{Sub: actor.String()}
+	c.ID = impersonation.String()
+	c.IssuedAt = jwt.NewNumericDate(now)
+	c.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(h.impersonationTTL) * time.Second))
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
 
 	sign, err := token.SignedString([]byte(h.secret))
@@ -77,7 +190,10 @@ func (h *JWTService) CreateAccess(c AccessClaims) (*AccessJWT, error) {
 }
 
 func (h *JWTService) CreateRefresh(c RefreshClaims) (*RefreshJWT, error) {
-	c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Duration(h.refreshTTL) * time.Second))
+	now := time.Now()
+	c.Type = RefreshType
+	c.IssuedAt = jwt.NewNumericDate(now)
+	c.ExpiresAt = jwt.NewNumericDate(now.Add(time.Duration(h.refreshTTL) * time.Second))
 	c.UUID = uuid.New()
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
@@ -92,6 +208,41 @@ func (h *JWTService) CreateRefresh(c RefreshClaims) (*RefreshJWT, error) {
 	}, nil
 }
 
+func (h *JWTService) CreateSessionReport(c SessionReportClaims, ttl time.Duration) (*SessionReportJWT, error) {
+	c.Audience = jwt.ClaimStrings{sessionReportAudience}
+	c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	sign, err := token.SignedString([]byte(h.secret))
+	if err != nil {
+		return &SessionReportJWT{}, err
+	}
+
+	return &SessionReportJWT{
+		Claims: c,
+		Token:  sign,
+	}, nil
+}
+
+// ValidateSessionReport only accepts tokens with the report audience, access and refresh tokens share the secret
+func (h *JWTService) ValidateSessionReport(token string) (*SessionReportJWT, error) {
+	t, err := jwt.ParseWithClaims(token, &SessionReportClaims{}, h.validateParsed)
+	if err != nil {
+		return &SessionReportJWT{}, err
+	}
+
+	claims := *t.Claims.(*SessionReportClaims)
+	if !t.Valid || !claims.VerifyAudience(sessionReportAudience, true) {
+		return &SessionReportJWT{}, errors.New("invalid token")
+	}
+
+	return &SessionReportJWT{
+		Claims: claims,
+		Token:  token,
+	}, nil
+}
+
+// ValidateAccess only accepts typed access tokens and the untyped ones issued before the cutoff
 func (h *JWTService) ValidateAccess(token string) (*AccessJWT, error) {
 	t, err := jwt.ParseWithClaims(token, &AccessClaims{}, h.validateParsed)
 	if err != nil {
@@ -102,16 +253,46 @@ func (h *JWTService) ValidateAccess(token string) (*AccessJWT, error) {
 		return &AccessJWT{}, errors.New("invalid token")
 	}
 
+	claims := *t.Claims.(*AccessClaims)
+	if claims.Type != AccessType && !h.isLegacyAccess(token, claims) {
+		return &AccessJWT{}, errors.New("invalid token type")
+	}
+
 	return &AccessJWT{
-		Claims: *t.Claims.(*AccessClaims),
+		Claims: claims,
 		Token:  token,
 	}, nil
 }
 
+// isLegacyAccess tells untyped access tokens from the other untyped tokens signed with the secret. They carried
+// no iat, so the issue time is taken back from the expiry, and refresh tokens of the time are told by their UUID.
+func (h *JWTService) isLegacyAccess(token string, c AccessClaims) bool {
+	if !c.IsLegacy() || len(c.Audience) > 0 || c.ExpiresAt == nil {
+		return false
+	}
+
+	issuedAt := c.ExpiresAt.Add(-h.AccessTTL())
+	if c.IssuedAt != nil {
+		issuedAt = c.IssuedAt.Time
+	}
+
+	if !issuedAt.Before(h.legacyCutoff) {
+		return false
+	}
+
+	var refresh RefreshClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &refresh); err != nil || refresh.UUID != uuid.Nil {
+		return false
+	}
+
+	return true
+}
+
+// ValidateRefresh parses a refresh token, access tokens and other tokens without a session uuid are refused
 func (h *JWTService) ValidateRefresh(token string) (*RefreshJWT, error) {
 	t, err := jwt.ParseWithClaims(token, &RefreshClaims{}, h.validateParsed)
 	if err != nil {
-		return &RefreshJWT{}, nil
+		return &RefreshJWT{}, err
 	}
 	claims := *t.Claims.(*RefreshClaims)
 
@@ -119,6 +300,10 @@ func (h *JWTService) ValidateRefresh(token string) (*RefreshJWT, error) {
 		return &RefreshJWT{}, errors.New("invalid token")
 	}
 
+	if claims.Type == AccessType || claims.UUID == uuid.Nil {
+		return &RefreshJWT{}, errors.New("not a refresh token")
+	}
+
 	return &RefreshJWT{
 		Claims: claims,
 		Token:  token,
@@ -0,0 +1,165 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const testSecret = "secret"
+
+func newTestService(legacyCutoff time.Time) *JWTService {
+	return NewJwtService(&JWTConfig{
+		Secret:           testSecret,
+		AccessTTL:        600,
+		RefreshTTL:       86400,
+		ImpersonationTTL: 900,
+		LegacyCutoff:     legacyCutoff,
+	})
+}
+
+// sign makes the untyped tokens of the versions before the typ claim
+func sign(t *testing.T, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestJWTService_ValidateAccess(t *testing.T) {
+	userUUID := uuid.New()
+	h := newTestService(time.Now())
+
+	access, err := h.CreateAccess(*NewAccessClaims(userUUID, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refresh, err := h.CreateRefresh(*NewRefreshClaims(userUUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beforeCutoff := time.Now().Add(-time.Minute)
+	afterCutoff := time.Now().Add(time.Minute)
+
+	tt := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "Access token", token: access.Token, valid: true},
+		{name: "Refresh token", token: refresh.Token},
+		{
+			name: "Untyped access token before the cutoff",
+			token: sign(t, AccessClaims{UserId: userUUID, RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(beforeCutoff.Add(h.AccessTTL())),
+			}}),
+			valid: true,
+		},
+		{
+			name: "Untyped access token after the cutoff",
+			token: sign(t, AccessClaims{UserId: userUUID, RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(afterCutoff.Add(h.AccessTTL())),
+			}}),
+		},
+		{
+			name: "Untyped refresh token",
+			token: sign(t, RefreshClaims{UUID: uuid.New(), UserId: userUUID, RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(beforeCutoff.Add(h.AccessTTL())),
+			}}),
+		},
+		{
+			name: "Untyped access token with an audience",
+			token: sign(t, AccessClaims{UserId: userUUID, RegisteredClaims: jwt.RegisteredClaims{
+				Audience:  jwt.ClaimStrings{"other"},
+				ExpiresAt: jwt.NewNumericDate(beforeCutoff.Add(h.AccessTTL())),
+			}}),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := h.ValidateAccess(tc.token)
+			if tc.valid && (err != nil || got.Claims.UserId != userUUID) {
+				t.Errorf("Want the token accepted, got '%v'", err)
+			}
+
+			if !tc.valid && err == nil {
+				t.Errorf("Want the token refused, got claims %+v", got.Claims)
+			}
+		})
+	}
+}
+
+func TestJWTService_ValidateRefresh(t *testing.T) {
+	userUUID := uuid.New()
+	h := newTestService(time.Now())
+
+	access, err := h.CreateAccess(*NewAccessClaims(userUUID, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refresh, err := h.CreateRefresh(*NewRefreshClaims(userUUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{name: "Refresh token", token: refresh.Token, valid: true},
+		{name: "Access token", token: access.Token},
+		{name: "Garbage", token: "garbage"},
+		{
+			name: "Expired refresh token",
+			token: sign(t, RefreshClaims{UUID: uuid.New(), UserId: userUUID, Type: RefreshType, RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			}}),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := h.ValidateRefresh(tc.token)
+			if tc.valid && (err != nil || got.Claims.UserId != userUUID) {
+				t.Errorf("Want the token accepted, got '%v'", err)
+			}
+
+			if !tc.valid && err == nil {
+				t.Errorf("Want the token refused, got claims %+v", got.Claims)
+			}
+		})
+	}
+}
+
+func TestJWTService_SessionReportIsNoSessionToken(t *testing.T) {
+	// the cutoff is ahead so that untyped tokens are still taken for legacy access tokens
+	h := newTestService(time.Now().Add(8 * 24 * time.Hour))
+
+	report, err := h.CreateSessionReport(SessionReportClaims{UserId: uuid.New(), Binding: "binding"}, 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.ValidateSessionReport(report.Token); err != nil {
+		t.Fatalf("Want the report token accepted as a report, got '%v'", err)
+	}
+
+	if got, err := h.ValidateAccess(report.Token); err == nil {
+		t.Errorf("Want the report token refused as an access token, got claims %+v", got.Claims)
+	}
+
+	if got, err := h.ValidateRefresh(report.Token); err == nil {
+		t.Errorf("Want the report token refused as a refresh token, got claims %+v", got.Claims)
+	}
+}
@@ -7,16 +7,25 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ibgl/microservice-users/internal/app"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	"github.com/ibgl/microservice-users/internal/app/household"
+	"github.com/ibgl/microservice-users/internal/app/password"
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
 	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/ibgl/microservice-users/internal/app/webhook"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 )
 
 // App mocked structure
 type AppMock struct {
-	authService user.UserAuthManager
-	logger      *logrus.Logger
-	config      *app.Config
+	authService      user.UserAuthManager
+	householdService household.HouseholdManager
+	webhookService   webhook.WebhookManager
+	rateLimiter      *ratelimit.Limiter
+	passwordPool     *password.Pool
+	logger           *logrus.Logger
+	config           *app.Config
 }
 
 func (h *AppMock) GetConfig() *app.Config {
@@ -31,6 +40,42 @@ func (h *AppMock) GetAuthService() user.UserAuthManager {
 	return h.authService
 }
 
+func (h *AppMock) GetHouseholdService() household.HouseholdManager {
+	return h.householdService
+}
+
+func (h *AppMock) SetHouseholdService(householdService household.HouseholdManager) app.Application {
+	h.householdService = householdService
+	return h
+}
+
+func (h *AppMock) GetWebhookService() webhook.WebhookManager {
+	return h.webhookService
+}
+
+func (h *AppMock) SetWebhookService(webhookService webhook.WebhookManager) app.Application {
+	h.webhookService = webhookService
+	return h
+}
+
+func (h *AppMock) GetRateLimiter() *ratelimit.Limiter {
+	return h.rateLimiter
+}
+
+func (h *AppMock) SetRateLimiter(rateLimiter *ratelimit.Limiter) app.Application {
+	h.rateLimiter = rateLimiter
+	return h
+}
+
+func (h *AppMock) GetPasswordPool() *password.Pool {
+	return h.passwordPool
+}
+
+func (h *AppMock) SetPasswordPool(passwordPool *password.Pool) app.Application {
+	h.passwordPool = passwordPool
+	return h
+}
+
 func (h *AppMock) SetConfig(config *app.Config) app.Application {
 	h.config = config
 	return h
@@ -61,11 +106,46 @@ func (m *AuthServiceMock) SignUp(ctx context.Context, r *user.SignUpRequest) (*u
 	return args.Get(0).(*user.LoginResponse), args.Error(1)
 }
 
+func (m *AuthServiceMock) RequestSignUp(ctx context.Context, r *user.SignUpRequest) error {
+	args := m.Called(ctx, r)
+	return args.Error(0)
+}
+
+func (m *AuthServiceMock) ConfirmSignUp(ctx context.Context, token string) (*user.LoginResponse, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(*user.LoginResponse), args.Error(1)
+}
+
+func (m *AuthServiceMock) SignOut(ctx context.Context, tokenString string) error {
+	args := m.Called(ctx, tokenString)
+	return args.Error(0)
+}
+
+func (m *AuthServiceMock) ReportSignIn(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *AuthServiceMock) ListActivity(ctx context.Context, userUUID uuid.UUID, before time.Time, limit int) ([]*audit.Event, error) {
+	args := m.Called(ctx, userUUID, before, limit)
+	return args.Get(0).([]*audit.Event), args.Error(1)
+}
+
+func (m *AuthServiceMock) ListAuthEvents(ctx context.Context, actorUUID uuid.UUID, q audit.Query) ([]*audit.Event, error) {
+	args := m.Called(ctx, actorUUID, q)
+	return args.Get(0).([]*audit.Event), args.Error(1)
+}
+
 func (m *AuthServiceMock) GetUser(ctx context.Context, userUUID uuid.UUID) (*user.User, error) {
 	args := m.Called(ctx, userUUID)
 	return args.Get(0).(*user.User), args.Error(1)
 }
 
+func (m *AuthServiceMock) GetUsers(ctx context.Context, userUUIDs []uuid.UUID) ([]*user.User, error) {
+	args := m.Called(ctx, userUUIDs)
+	return args.Get(0).([]*user.User), args.Error(1)
+}
+
 func (m *AuthServiceMock) SaveRefresh(ctx context.Context, userUUID uuid.UUID) (*user.User, error) {
 	args := m.Called(ctx, userUUID)
 	return args.Get(0).(*user.User), args.Error(1)
@@ -91,6 +171,146 @@ func (m *AuthServiceMock) GoogleSignIn(ctx context.Context, token string) (*user
 	return args.Get(0).(*user.LoginResponse), args.Error(1)
 }
 
+func (m *AuthServiceMock) Impersonate(ctx context.Context, r *user.ImpersonateRequest) (*user.ImpersonationResponse, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*user.ImpersonationResponse), args.Error(1)
+}
+
+func (m *AuthServiceMock) CreatePersonalAccessToken(ctx context.Context, r *user.CreatePersonalAccessTokenRequest) (*user.CreatedPersonalAccessToken, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*user.CreatedPersonalAccessToken), args.Error(1)
+}
+
+func (m *AuthServiceMock) ListPersonalAccessTokens(ctx context.Context, userUUID uuid.UUID) ([]*user.PersonalAccessToken, error) {
+	args := m.Called(ctx, userUUID)
+	return args.Get(0).([]*user.PersonalAccessToken), args.Error(1)
+}
+
+func (m *AuthServiceMock) RevokePersonalAccessToken(ctx context.Context, userUUID, tokenUUID uuid.UUID) error {
+	args := m.Called(ctx, userUUID, tokenUUID)
+	return args.Error(0)
+}
+
+func (m *AuthServiceMock) CreateClient(ctx context.Context, r *user.CreateClientRequest) (*user.CreatedClient, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*user.CreatedClient), args.Error(1)
+}
+
+func (m *AuthServiceMock) ListClients(ctx context.Context, actorUUID uuid.UUID) ([]*user.Client, error) {
+	args := m.Called(ctx, actorUUID)
+	return args.Get(0).([]*user.Client), args.Error(1)
+}
+
+func (m *AuthServiceMock) DeleteClient(ctx context.Context, actorUUID uuid.UUID, clientId string) error {
+	args := m.Called(ctx, actorUUID, clientId)
+	return args.Error(0)
+}
+
+func (m *AuthServiceMock) ClientCredentials(ctx context.Context, r *user.ClientCredentialsRequest) (*user.ClientTokenResponse, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*user.ClientTokenResponse), args.Error(1)
+}
+
+func (m *AuthServiceMock) ChangePassword(ctx context.Context, r *user.ChangePasswordRequest) error {
+	args := m.Called(ctx, r)
+	return args.Error(0)
+}
+
+func (m *AuthServiceMock) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *AuthServiceMock) ResetPassword(ctx context.Context, r *user.ResetPasswordRequest) error {
+	args := m.Called(ctx, r)
+	return args.Error(0)
+}
+
+// Household mocked structure
+type HouseholdServiceMock struct {
+	mock.Mock
+}
+
+func (m *HouseholdServiceMock) Create(ctx context.Context, userUUID uuid.UUID, name string) (*household.Details, error) {
+	args := m.Called(ctx, userUUID, name)
+	return args.Get(0).(*household.Details), args.Error(1)
+}
+
+func (m *HouseholdServiceMock) List(ctx context.Context, userUUID uuid.UUID) ([]*household.Household, error) {
+	args := m.Called(ctx, userUUID)
+	return args.Get(0).([]*household.Household), args.Error(1)
+}
+
+func (m *HouseholdServiceMock) Get(ctx context.Context, userUUID, householdUUID uuid.UUID) (*household.Details, error) {
+	args := m.Called(ctx, userUUID, householdUUID)
+	return args.Get(0).(*household.Details), args.Error(1)
+}
+
+func (m *HouseholdServiceMock) Invite(ctx context.Context, r *household.InviteRequest) (*household.Invitation, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*household.Invitation), args.Error(1)
+}
+
+func (m *HouseholdServiceMock) ListInvitations(ctx context.Context, userUUID uuid.UUID) ([]*household.Invitation, error) {
+	args := m.Called(ctx, userUUID)
+	return args.Get(0).([]*household.Invitation), args.Error(1)
+}
+
+func (m *HouseholdServiceMock) AcceptInvitation(ctx context.Context, userUUID, invitationUUID uuid.UUID) (*household.Details, error) {
+	args := m.Called(ctx, userUUID, invitationUUID)
+	return args.Get(0).(*household.Details), args.Error(1)
+}
+
+func (m *HouseholdServiceMock) DeclineInvitation(ctx context.Context, userUUID, invitationUUID uuid.UUID) error {
+	args := m.Called(ctx, userUUID, invitationUUID)
+	return args.Error(0)
+}
+
+func (m *HouseholdServiceMock) Leave(ctx context.Context, userUUID, householdUUID uuid.UUID) error {
+	args := m.Called(ctx, userUUID, householdUUID)
+	return args.Error(0)
+}
+
+func (m *HouseholdServiceMock) SetActive(ctx context.Context, userUUID, householdUUID uuid.UUID) error {
+	args := m.Called(ctx, userUUID, householdUUID)
+	return args.Error(0)
+}
+
+// Webhook mocked structure
+type WebhookServiceMock struct {
+	mock.Mock
+}
+
+func (m *WebhookServiceMock) CreateSubscription(ctx context.Context, r *webhook.CreateSubscriptionRequest) (*webhook.Subscription, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*webhook.Subscription), args.Error(1)
+}
+
+func (m *WebhookServiceMock) ListSubscriptions(ctx context.Context, actorUUID uuid.UUID) ([]*webhook.Subscription, error) {
+	args := m.Called(ctx, actorUUID)
+	return args.Get(0).([]*webhook.Subscription), args.Error(1)
+}
+
+func (m *WebhookServiceMock) UpdateSubscription(ctx context.Context, r *webhook.UpdateSubscriptionRequest) (*webhook.Subscription, error) {
+	args := m.Called(ctx, r)
+	return args.Get(0).(*webhook.Subscription), args.Error(1)
+}
+
+func (m *WebhookServiceMock) DeleteSubscription(ctx context.Context, actorUUID, subscriptionUUID uuid.UUID) error {
+	args := m.Called(ctx, actorUUID, subscriptionUUID)
+	return args.Error(0)
+}
+
+func (m *WebhookServiceMock) ListDeliveries(ctx context.Context, actorUUID, subscriptionUUID uuid.UUID, limit int) ([]*webhook.Delivery, error) {
+	args := m.Called(ctx, actorUUID, subscriptionUUID, limit)
+	return args.Get(0).([]*webhook.Delivery), args.Error(1)
+}
+
+func (m *WebhookServiceMock) Redeliver(ctx context.Context, actorUUID, subscriptionUUID, deliveryUUID uuid.UUID) (*webhook.Delivery, error) {
+	args := m.Called(ctx, actorUUID, subscriptionUUID, deliveryUUID)
+	return args.Get(0).(*webhook.Delivery), args.Error(1)
+}
+
 func NewAppMock(
 	config *app.Config,
 ) app.Application {
@@ -107,9 +327,11 @@ func NewAppMock(
 	}
 
 	return &AppMock{
-		authService: &AuthServiceMock{},
-		logger:      loggerMock,
-		config:      config,
+		authService:      &AuthServiceMock{},
+		householdService: &HouseholdServiceMock{},
+		webhookService:   &WebhookServiceMock{},
+		logger:           loggerMock,
+		config:           config,
 	}
 }
 
@@ -0,0 +1,51 @@
+// Package outbox holds the polling and retry timing shared by the outbox workers: the email dispatcher,
+// the event relay and the webhook dispatcher
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff is the delay after the given number of failed attempts, doubling from base up to max
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		return max
+	}
+
+	return delay
+}
+
+// Poll runs the batch every interval until the context is done, a full batch is followed by the next one
+// right away. The batch returns the number of claimed items, its errors are passed to onError.
+func Poll(ctx context.Context, interval time.Duration, batchSize int, batch func(ctx context.Context) (int, error), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		claimed, err := batch(ctx)
+		if err != nil {
+			onError(err)
+		}
+
+		// checked before a full batch skips the wait, a backlog must not hold up the shutdown
+		if ctx.Err() != nil {
+			return
+		}
+
+		if claimed == batchSize && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
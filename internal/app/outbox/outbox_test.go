@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		4:  4 * time.Minute,
+		5:  5 * time.Minute,
+		30: 5 * time.Minute,
+	} {
+		if got := Backoff(30*time.Second, 5*time.Minute, attempts); got != want {
+			t.Errorf("Want backoff '%s' after %d attempts, got '%s'", want, attempts, got)
+		}
+	}
+}
+
+func TestPoll(t *testing.T) {
+	tt := []struct {
+		name      string
+		claimed   int
+		err       error
+		cancelAt  int
+		wantCalls int
+	}{
+		// the context is done while the batches stay full, the poll has to stop anyway
+		{name: "Full batches run back to back", claimed: 10, cancelAt: 5, wantCalls: 5},
+		{name: "Partial batches wait for the interval", claimed: 3, wantCalls: 1},
+		{name: "Failed batches wait for the interval", claimed: 10, err: errors.New("outbox unavailable"), wantCalls: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			calls, failures := 0, 0
+			batch := func(ctx context.Context) (int, error) {
+				calls++
+				if calls == tc.cancelAt {
+					cancel()
+				}
+
+				return tc.claimed, tc.err
+			}
+
+			done := make(chan struct{})
+			go func() {
+				Poll(ctx, time.Hour, 10, batch, func(err error) { failures++ })
+				close(done)
+			}()
+
+			if tc.cancelAt == 0 {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Want the poll to stop once the context is done")
+			}
+
+			if calls != tc.wantCalls {
+				t.Errorf("Want %d batches, got %d", tc.wantCalls, calls)
+			}
+
+			if tc.err != nil && failures != calls {
+				t.Errorf("Want %d errors passed on, got %d", calls, failures)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type BreachedChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// maxLineLength is longer than any "HASH:COUNT" line of the Pwned Passwords files
+const maxLineLength = 128
+
+// HashFileChecker looks passwords up in a local copy of the Pwned Passwords SHA-1 list ordered by hash,
+// one "HASH:COUNT" line per password as produced by the HIBP downloader from the k-anonymity range API.
+// The file is binary searched on disk so that the multi gigabyte list is never loaded into memory
+type HashFileChecker struct {
+	file     *os.File
+	size     int64
+	minCount int
+}
+
+// NewHashFileChecker opens the list, passwords seen less than minCount times are accepted
+func NewHashFileChecker(path string, minCount int) (*HashFileChecker, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	return &HashFileChecker{
+		file:     file,
+		size:     stat.Size(),
+		minCount: minCount,
+	}, nil
+}
+
+func (c *HashFileChecker) Close() error {
+	return c.file.Close()
+}
+
+func (c *HashFileChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	count, err := c.Count(strings.ToUpper(hex.EncodeToString(sum[:])))
+	if err != nil {
+		return false, err
+	}
+
+	return count >= c.minCount, nil
+}
+
+// Count returns how many times the upper case hex SHA-1 hash was seen in breaches
+func (c *HashFileChecker) Count(hash string) (int, error) {
+	// lo and hi are always line starts, hi may be the end of the file
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := c.lineAfter(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if start >= hi {
+			// no line starts in the upper half, the few lines left are scanned
+			return c.scan(lo, hi, hash)
+		}
+
+		lineHash, count, err := parseLine(line)
+		if err != nil {
+			return 0, err
+		}
+
+		switch strings.Compare(lineHash, hash) {
+		case 0:
+			return count, nil
+		case -1:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = start
+		}
+	}
+
+	return 0, nil
+}
+
+// lineAfter returns the first line starting at or after the offset
+func (c *HashFileChecker) lineAfter(offset int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		buf, err := c.read(offset-1, maxLineLength)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return c.size, nil, nil
+		}
+		start = offset + int64(i)
+	}
+
+	if start >= c.size {
+		return c.size, nil, nil
+	}
+
+	buf, err := c.read(start, maxLineLength)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		buf = buf[:i]
+	}
+
+	return start, buf, nil
+}
+
+func (c *HashFileChecker) scan(lo, hi int64, hash string) (int, error) {
+	buf, err := c.read(lo, int(hi-lo))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range bytes.Split(buf, []byte{'\n'}) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		lineHash, count, err := parseLine(line)
+		if err != nil {
+			return 0, err
+		}
+
+		if lineHash == hash {
+			return count, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (c *HashFileChecker) read(offset int64, length int) ([]byte, error) {
+	buf := make([]byte, length)
+	n, err := c.file.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+func parseLine(line []byte) (string, int, error) {
+	hash, count, found := strings.Cut(strings.TrimSpace(string(line)), ":")
+	if !found {
+		return "", 0, fmt.Errorf("invalid breached passwords line %q", line)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid breached passwords line %q", line)
+	}
+
+	return hash, n, nil
+}
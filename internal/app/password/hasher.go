@@ -0,0 +1,228 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+// Hasher is a password hashing algorithm storing its parameters in the hash string
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether the hash was made with other parameters than the current ones
+	NeedsRehash(encoded string) bool
+	// Supports reports whether the hash was made by the algorithm
+	Supports(encoded string) bool
+}
+
+// Hashers hashes with the first hasher and verifies with whichever supports the stored hash,
+// hashes of the other hashers always need a rehash
+type Hashers []Hasher
+
+func (h Hashers) Hash(password string) (string, error) {
+	return h[0].Hash(password)
+}
+
+func (h Hashers) Verify(password, encoded string) (bool, error) {
+	for _, hasher := range h {
+		if hasher.Supports(encoded) {
+			return hasher.Verify(password, encoded)
+		}
+	}
+
+	return false, ErrUnsupportedHash
+}
+
+func (h Hashers) NeedsRehash(encoded string) bool {
+	return !h[0].Supports(encoded) || h[0].NeedsRehash(encoded)
+}
+
+const DefaultBcryptCost = 12
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = DefaultBcryptCost
+	}
+
+	return &BcryptHasher{cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(bytes), err
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+func (h *BcryptHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// Argon2Params are the Argon2id cost parameters, memory is in KiB
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP password storage recommendation
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher produces PHC strings like $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+type Argon2idHasher struct {
+	params Argon2Params
+}
+
+// NewArgon2idHasher creates the hasher, zero parameters are taken from DefaultArgon2Params
+func NewArgon2idHasher(params Argon2Params) *Argon2idHasher {
+	if params.Memory == 0 {
+		params.Memory = DefaultArgon2Params.Memory
+	}
+
+	if params.Iterations == 0 {
+		params.Iterations = DefaultArgon2Params.Iterations
+	}
+
+	if params.Parallelism == 0 {
+		params.Parallelism = DefaultArgon2Params.Parallelism
+	}
+
+	if params.SaltLength == 0 {
+		params.SaltLength = DefaultArgon2Params.SaltLength
+	}
+
+	if params.KeyLength == 0 {
+		params.KeyLength = DefaultArgon2Params.KeyLength
+	}
+
+	return &Argon2idHasher{params}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		params.KeyLength != h.params.KeyLength ||
+		uint32(len(salt)) != h.params.SaltLength
+}
+
+func (h *Argon2idHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, ErrUnsupportedHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// NewHashers hashes new passwords with the algorithm and keeps verifying hashes of the other one
+func NewHashers(algorithm string, bcryptCost int, argon2Params Argon2Params) (Hashers, error) {
+	bcryptHasher := NewBcryptHasher(bcryptCost)
+	argon2idHasher := NewArgon2idHasher(argon2Params)
+
+	switch algorithm {
+	case AlgorithmArgon2id, "":
+		return Hashers{argon2idHasher, bcryptHasher}, nil
+	case AlgorithmBcrypt:
+		return Hashers{bcryptHasher, argon2idHasher}, nil
+	}
+
+	return nil, fmt.Errorf("unknown password hashing algorithm %s", algorithm)
+}
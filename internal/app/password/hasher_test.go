@@ -0,0 +1,74 @@
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashers(t *testing.T) {
+	fastArgon2 := Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+	bcryptHashers, err := NewHashers(AlgorithmBcrypt, bcrypt.MinCost, fastArgon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argon2Hashers, err := NewHashers(AlgorithmArgon2id, bcrypt.MinCost, fastArgon2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strongerArgon2Hashers, err := NewHashers(AlgorithmArgon2id, bcrypt.MinCost, Argon2Params{Memory: 128, Iterations: 1, Parallelism: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bcryptHash, err := bcryptHashers.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argon2Hash, err := argon2Hashers.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name        string
+		hashers     Hashers
+		hash        string
+		password    string
+		valid       bool
+		needsRehash bool
+	}{
+		{name: "bcrypt hash with bcrypt preferred", hashers: bcryptHashers, hash: bcryptHash, password: "secret", valid: true},
+		{name: "wrong password", hashers: bcryptHashers, hash: bcryptHash, password: "Secret", valid: false},
+		{name: "argon2id hash with argon2id preferred", hashers: argon2Hashers, hash: argon2Hash, password: "secret", valid: true},
+		{name: "wrong argon2id password", hashers: argon2Hashers, hash: argon2Hash, password: "secret ", valid: false},
+		{name: "bcrypt hash migrates to argon2id", hashers: argon2Hashers, hash: bcryptHash, password: "secret", valid: true, needsRehash: true},
+		{name: "argon2id hash migrates to bcrypt", hashers: bcryptHashers, hash: argon2Hash, password: "secret", valid: true, needsRehash: true},
+		{name: "argon2id hash with outdated memory", hashers: strongerArgon2Hashers, hash: argon2Hash, password: "secret", valid: true, needsRehash: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			valid, err := tc.hashers.Verify(tc.password, tc.hash)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if valid != tc.valid {
+				t.Errorf("Verify: want '%t', got '%t'", tc.valid, valid)
+			}
+
+			if needsRehash := tc.hashers.NeedsRehash(tc.hash); needsRehash != tc.needsRehash {
+				t.Errorf("NeedsRehash: want '%t', got '%t'", tc.needsRehash, needsRehash)
+			}
+		})
+	}
+
+	if _, err := argon2Hashers.Verify("secret", "plain"); err != ErrUnsupportedHash {
+		t.Errorf("Verify of an unknown format: want '%v', got '%v'", ErrUnsupportedHash, err)
+	}
+}
@@ -0,0 +1,123 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	breached := writeHashFile(t, []string{"Summer2023!", "hunter2hunter2"})
+
+	policy := DefaultPolicy
+	policy.Breached = breached
+
+	tt := []struct {
+		password string
+		want     string
+	}{
+		{password: "Xk9#mQ2$vL", want: ""},
+		{password: "Ab1!", want: "field-password-too-short"},
+		{password: strings.Repeat("Ab1!", 20), want: "field-password-too-long"},
+		{password: "abcdefghijkl", want: "field-password-classes"},
+		{password: "Winwin2023!!", want: "field-password-personal-info"},
+		{password: "Password1", want: "field-password-too-weak"},
+		{password: "Summer2023!", want: "field-password-breached"},
+	}
+
+	for _, tc := range tt {
+		err := policy.Validate(tc.password, "winwin@email.ru", "Win Winner")
+
+		got := ""
+		if err != nil {
+			got = err.(appErr.AppError).Slug()
+		}
+
+		if got != tc.want {
+			t.Errorf("Validate(%q): want '%s', got '%s'", tc.password, tc.want, got)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tt := []struct {
+		password string
+		want     int
+	}{
+		{password: "password", want: 0},
+		{password: "qwerty123", want: 0},
+		{password: "aaaaaaaaaaaa", want: 0},
+		{password: "abcdef", want: 0},
+		{password: "Password1", want: 0},
+		{password: "Budget2024", want: 1},
+		{password: "kx7!rt", want: 2},
+		{password: "correcthorsebatterystaple", want: 4},
+	}
+
+	for _, tc := range tt {
+		if got := Score(tc.password); got != tc.want {
+			t.Errorf("Score(%q): want '%d', got '%d' (%.2f)", tc.password, tc.want, got, Guesses(tc.password))
+		}
+	}
+}
+
+func TestHashFileChecker_Count(t *testing.T) {
+	var passwords []string
+	for i := 0; i < 500; i++ {
+		passwords = append(passwords, fmt.Sprintf("password-%d", i))
+	}
+
+	checker := writeHashFile(t, passwords)
+
+	for i, p := range passwords {
+		breached, err := checker.IsBreached(p)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if !breached {
+			t.Errorf("IsBreached(%q): want true on line %d", p, i)
+		}
+	}
+
+	for _, p := range []string{"", "password-500", "Xk9#mQ2$vL"} {
+		breached, err := checker.IsBreached(p)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if breached {
+			t.Errorf("IsBreached(%q): want false", p)
+		}
+	}
+}
+
+// writeHashFile stores the passwords in the ordered Pwned Passwords format with varying counts
+func writeHashFile(t *testing.T, passwords []string) *HashFileChecker {
+	lines := make([]string, 0, len(passwords))
+	for i, p := range passwords {
+		sum := sha1.Sum([]byte(p))
+		lines = append(lines, fmt.Sprintf("%s:%d\r\n", strings.ToUpper(hex.EncodeToString(sum[:])), i*37+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned-passwords.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checker, err := NewHashFileChecker(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { checker.Close() })
+
+	return checker
+}
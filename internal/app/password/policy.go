@@ -0,0 +1,118 @@
+package password
+
+import (
+	"strings"
+	"unicode"
+
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+)
+
+// Policy is the set of rules new passwords must satisfy, zero values switch a rule off
+type Policy struct {
+	MinLength int
+	// MaxLength is counted in bytes, bcrypt ignores everything after 72 bytes
+	MaxLength int
+	// MinClasses is the number of different character classes: lower, upper, digits and symbols
+	MinClasses int
+	// MinScore is the lowest accepted Score from 0 to 4
+	MinScore           int
+	ForbidPersonalInfo bool
+	Breached           BreachedChecker
+}
+
+var DefaultPolicy = Policy{
+	MinLength:          8,
+	MaxLength:          72,
+	MinClasses:         2,
+	MinScore:           2,
+	ForbidPersonalInfo: true,
+}
+
+// Validate checks the password against every rule and returns the first violation,
+// personal are the email and the name of the user
+func (p Policy) Validate(password string, personal ...string) error {
+	if len([]rune(password)) < p.MinLength {
+		return appErr.NewIncorrectInputError("Password is too short", "field-password-too-short")
+	}
+
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		return appErr.NewIncorrectInputError("Password is too long", "field-password-too-long")
+	}
+
+	if Classes(password) < p.MinClasses {
+		return appErr.NewIncorrectInputError("Password needs more character classes", "field-password-classes")
+	}
+
+	if p.ForbidPersonalInfo && containsPersonalInfo(password, personal) {
+		return appErr.NewIncorrectInputError("Password contains the email or the name", "field-password-personal-info")
+	}
+
+	if Score(password, personal...) < p.MinScore {
+		return appErr.NewIncorrectInputError("Password is too weak", "field-password-too-weak")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return appErr.NewAppError(err.Error(), "password-check-error")
+		}
+
+		if breached {
+			return appErr.NewIncorrectInputError("Password was found in a data breach", "field-password-breached")
+		}
+	}
+
+	return nil
+}
+
+// Classes counts the character classes used in the password
+func Classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+// personalTokens splits emails and names into lower case parts worth checking, short parts are ignored
+// as they appear in too many passwords by chance
+func personalTokens(personal []string) []string {
+	var tokens []string
+	for _, value := range personal {
+		value = strings.ToLower(value)
+		if local, _, found := strings.Cut(value, "@"); found {
+			value = local
+		}
+
+		for _, token := range strings.FieldsFunc(value, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(token)) >= 3 {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+
+	return tokens
+}
+
+func containsPersonalInfo(password string, personal []string) bool {
+	lower := strings.ToLower(password)
+	for _, token := range personalTokens(personal) {
+		if strings.Contains(lower, token) {
+			return true
+		}
+	}
+
+	return false
+}
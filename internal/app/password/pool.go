@@ -0,0 +1,152 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrBusy = errors.New("password hashing queue is full")
+
+// Pool runs hashing on at most workers goroutines at a time, up to queue callers wait for a free worker
+// for no longer than maxWait and the rest are refused with ErrBusy, so that a burst of sign ins can not
+// take every core from cheap requests
+type Pool struct {
+	hashers Hashers
+	workers chan struct{}
+	waiting chan struct{}
+	maxWait time.Duration
+
+	mu    sync.Mutex
+	stats PoolStats
+}
+
+type PoolStats struct {
+	Workers   int
+	QueueSize int
+	Running   int
+	Queued    int
+	Started   uint64
+	Rejected  uint64
+	Canceled  uint64
+	// WaitTotal is the summed queue wait of the started jobs
+	WaitTotal time.Duration
+	WaitMax   time.Duration
+}
+
+// WaitAverage is the mean queue wait of the started jobs
+func (s PoolStats) WaitAverage() time.Duration {
+	if s.Started == 0 {
+		return 0
+	}
+
+	return s.WaitTotal / time.Duration(s.Started)
+}
+
+func NewPool(hashers Hashers, workers, queue int, maxWait time.Duration) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	if queue < 0 {
+		queue = 0
+	}
+
+	return &Pool{
+		hashers: hashers,
+		workers: make(chan struct{}, workers),
+		waiting: make(chan struct{}, workers+queue),
+		maxWait: maxWait,
+		stats: PoolStats{
+			Workers:   workers,
+			QueueSize: queue,
+		},
+	}
+}
+
+func (p *Pool) Hash(ctx context.Context, password string) (string, error) {
+	var hash string
+	err := p.run(ctx, func() error {
+		var err error
+		hash, err = p.hashers.Hash(password)
+		return err
+	})
+
+	return hash, err
+}
+
+func (p *Pool) Verify(ctx context.Context, password, encoded string) (bool, error) {
+	var ok bool
+	err := p.run(ctx, func() error {
+		var err error
+		ok, err = p.hashers.Verify(password, encoded)
+		return err
+	})
+
+	return ok, err
+}
+
+// NeedsRehash only parses the hash and does not take a worker
+func (p *Pool) NeedsRehash(encoded string) bool {
+	return p.hashers.NeedsRehash(encoded)
+}
+
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Running = len(p.workers)
+	stats.Queued = len(p.waiting) - stats.Running
+
+	return stats
+}
+
+func (p *Pool) run(ctx context.Context, job func() error) error {
+	select {
+	case p.waiting <- struct{}{}:
+	default:
+		p.record(func(s *PoolStats) { s.Rejected++ })
+		return ErrBusy
+	}
+	defer func() { <-p.waiting }()
+
+	start := time.Now()
+
+	var timeout <-chan time.Time
+	if p.maxWait > 0 {
+		timer := time.NewTimer(p.maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case p.workers <- struct{}{}:
+	case <-ctx.Done():
+		p.record(func(s *PoolStats) { s.Canceled++ })
+		return ctx.Err()
+	case <-timeout:
+		p.record(func(s *PoolStats) { s.Rejected++ })
+		return ErrBusy
+	}
+	defer func() { <-p.workers }()
+
+	wait := time.Since(start)
+	p.record(func(s *PoolStats) {
+		s.Started++
+		s.WaitTotal += wait
+		if wait > s.WaitMax {
+			s.WaitMax = wait
+		}
+	})
+
+	return job()
+}
+
+func (p *Pool) record(update func(s *PoolStats)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	update(&p.stats)
+}
@@ -0,0 +1,109 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingHasher holds every hash until release is closed
+type blockingHasher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHasher) Hash(password string) (string, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return "$blocking$" + password, nil
+}
+
+func (h *blockingHasher) Verify(password, encoded string) (bool, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return encoded == "$blocking$"+password, nil
+}
+
+func (h *blockingHasher) NeedsRehash(encoded string) bool {
+	return false
+}
+
+func (h *blockingHasher) Supports(encoded string) bool {
+	return true
+}
+
+func TestPool(t *testing.T) {
+	hasher := &blockingHasher{started: make(chan struct{}, 10), release: make(chan struct{})}
+	pool := NewPool(Hashers{hasher}, 1, 1, time.Hour)
+
+	running := make(chan error)
+	go func() {
+		_, err := pool.Hash(context.Background(), "secret")
+		running <- err
+	}()
+	<-hasher.started
+
+	queued := make(chan error)
+	go func() {
+		_, err := pool.Verify(context.Background(), "secret", "$blocking$secret")
+		queued <- err
+	}()
+
+	waitFor(t, func() bool { return pool.Stats().Queued == 1 })
+
+	if _, err := pool.Hash(context.Background(), "secret"); !errors.Is(err, ErrBusy) {
+		t.Errorf("full queue got %v, want %v", err, ErrBusy)
+	}
+
+	close(hasher.release)
+	if err := <-running; err != nil {
+		t.Errorf("running job got %v", err)
+	}
+	if err := <-queued; err != nil {
+		t.Errorf("queued job got %v", err)
+	}
+
+	stats := pool.Stats()
+	if stats.Started != 2 || stats.Rejected != 1 || stats.Running != 0 || stats.Queued != 0 {
+		t.Errorf("got stats %+v", stats)
+	}
+
+	if stats.WaitMax <= 0 || stats.WaitAverage() > stats.WaitMax {
+		t.Errorf("got wait average %v and max %v", stats.WaitAverage(), stats.WaitMax)
+	}
+}
+
+func TestPoolWaitLimits(t *testing.T) {
+	hasher := &blockingHasher{started: make(chan struct{}, 10), release: make(chan struct{})}
+	defer close(hasher.release)
+
+	pool := NewPool(Hashers{hasher}, 1, 5, 20*time.Millisecond)
+	go pool.Hash(context.Background(), "secret")
+	<-hasher.started
+
+	if _, err := pool.Hash(context.Background(), "secret"); !errors.Is(err, ErrBusy) {
+		t.Errorf("max wait got %v, want %v", err, ErrBusy)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pool.Verify(ctx, "secret", "$blocking$secret"); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context got %v, want %v", err, context.Canceled)
+	}
+
+	stats := pool.Stats()
+	if stats.Started != 1 || stats.Rejected != 1 || stats.Canceled != 1 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
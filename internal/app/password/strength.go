@@ -0,0 +1,211 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are the most used passwords ordered by popularity, a match costs its rank in guesses
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111", "1234567", "dragon",
+	"123123", "baseball", "abc123", "football", "monkey", "letmein", "696969", "shadow", "master", "666666",
+	"qwertyuiop", "123321", "mustang", "1234567890", "michael", "654321", "superman", "1qaz2wsx", "7777777", "121212",
+	"000000", "qazwsx", "123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou", "2000", "charlie",
+	"robert", "thomas", "hockey", "ranger", "daniel", "starwars", "klaster", "112233", "george", "computer",
+	"michelle", "jessica", "pepper", "1111", "zxcvbn", "555555", "11111111", "131313", "freedom", "777777",
+	"pass", "maggie", "159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321", "dallas",
+	"austin", "thunder", "taylor", "matrix", "welcome", "admin", "login", "secret", "qwerty123", "passw0rd",
+	"hello", "monday", "friday", "winter", "spring", "autumn", "money", "family", "budget", "default",
+}
+
+var commonPasswordRanks = func() map[string]int {
+	ranks := make(map[string]int, len(commonPasswords))
+	for i, p := range commonPasswords {
+		ranks[p] = i + 1
+	}
+
+	return ranks
+}()
+
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./", "1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p"}
+
+// Score estimates the password strength from 0 (guessable in a thousand guesses) to 4 (more than 10^10 guesses)
+// in the spirit of zxcvbn: the password is split into the cheapest sequence of dictionary words, personal info,
+// repeats, sequences, keyboard walks and brute forced characters
+func Score(password string, personal ...string) int {
+	guesses := Guesses(password, personal...)
+
+	switch {
+	case guesses < 3:
+		return 0
+	case guesses < 6:
+		return 1
+	case guesses < 8:
+		return 2
+	case guesses < 10:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Guesses returns log10 of the estimated number of guesses needed to find the password
+func Guesses(password string, personal ...string) float64 {
+	runes := []rune(password)
+	lower := []rune(strings.ToLower(password))
+	if len(runes) == 0 {
+		return 0
+	}
+
+	// personal info is the first thing an attacker tries
+	personalRanks := map[string]int{}
+	for _, token := range personalTokens(personal) {
+		personalRanks[token] = 1
+	}
+
+	// a brute forced character costs ten guesses like in zxcvbn, the full alphabet overrates short random strings
+	bruteforce := 1.0
+
+	// best[i] is the cheapest estimate for the first i characters
+	best := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = math.Inf(1)
+	}
+
+	for i := 0; i < len(runes); i++ {
+		if math.IsInf(best[i], 1) {
+			continue
+		}
+
+		relax := func(end int, cost float64) {
+			if best[i]+cost < best[end] {
+				best[end] = best[i] + cost
+			}
+		}
+
+		relax(i+1, bruteforce)
+
+		for j := i + 1; j <= len(runes); j++ {
+			word := string(lower[i:j])
+			length := float64(j - i)
+
+			rank, ok := personalRanks[word]
+			if !ok {
+				rank, ok = commonPasswordRanks[word]
+			}
+
+			if ok {
+				cost := math.Log10(float64(rank))
+				if string(runes[i:j]) != word {
+					// capitalised variants double the guesses
+					cost += math.Log10(2)
+				}
+				relax(j, math.Max(cost, 0.5))
+			}
+
+			if j-i < 3 {
+				continue
+			}
+
+			if isRepeat(lower[i:j]) {
+				relax(j, math.Log10(float64(cardinality(runes[i:i+1]))*length))
+			}
+
+			if isSequence(lower[i:j]) {
+				relax(j, math.Log10(26*length))
+			}
+
+			if j-i >= 4 && isKeyboardWalk(word) {
+				relax(j, math.Log10(44*length))
+			}
+		}
+	}
+
+	return best[len(runes)]
+}
+
+// cardinality is the size of the alphabet the repeated character is drawn from
+func cardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+
+	return size
+}
+
+func isRepeat(runes []rune) bool {
+	for _, r := range runes[1:] {
+		if r != runes[0] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isSequence matches steadily ascending or descending characters like "abcd" or "9753"
+func isSequence(runes []rune) bool {
+	delta := runes[1] - runes[0]
+	if delta == 0 || delta > 2 || delta < -2 {
+		return false
+	}
+
+	for i := 2; i < len(runes); i++ {
+		if runes[i]-runes[i-1] != delta {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isKeyboardWalk(word string) bool {
+	for _, row := range keyboardRows {
+		if strings.Contains(row, word) || strings.Contains(reverse(row), word) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
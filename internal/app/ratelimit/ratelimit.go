@@ -0,0 +1,185 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type KeyBy struct {
+	v string
+}
+
+var (
+	UNKNOWN = KeyBy{"UNKNOWN"}
+	IP      = KeyBy{"ip"}
+	USER    = KeyBy{"user"}
+)
+
+func (c KeyBy) String() string {
+	return c.v
+}
+
+func KeyByFromString(value string) (KeyBy, error) {
+	switch value {
+	case IP.String():
+		return IP, nil
+	case USER.String():
+		return USER, nil
+	}
+
+	return UNKNOWN, errors.New("Invalid rate limit key value")
+}
+
+// Policy is a token bucket holding Limit requests which is refilled completely in Period
+type Policy struct {
+	Limit  int
+	Period time.Duration
+	KeyBy  KeyBy
+}
+
+// interval is the time needed to refill a single token
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time left until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time left until the next token, zero for allowed requests
+	RetryAfter time.Duration
+}
+
+// Bucket is the stored state of a token bucket
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Take refills the bucket for the time passed since the last update and takes a token if there is one,
+// a zero bucket is treated as a full one
+func (b Bucket) Take(p Policy, now time.Time) (Bucket, Result) {
+	tokens := float64(p.Limit)
+	if !b.UpdatedAt.IsZero() {
+		elapsed := now.Sub(b.UpdatedAt)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+
+		tokens = math.Min(float64(p.Limit), b.Tokens+float64(elapsed)/float64(p.interval()))
+	}
+
+	result := Result{Limit: p.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(p.interval()))
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((float64(p.Limit) - tokens) * float64(p.interval()))
+
+	return Bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+type Store interface {
+	// Take atomically takes a token from the bucket stored under the key
+	Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error)
+}
+
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+}
+
+func NewLimiter(store Store, policies map[string]Policy) *Limiter {
+	return &Limiter{
+		store:    store,
+		policies: policies,
+	}
+}
+
+// Policy returns the policy configured for the route
+func (l *Limiter) Policy(route string) (Policy, bool) {
+	p, ok := l.policies[route]
+	return p, ok
+}
+
+// Allow takes a token from the route bucket of the subject, subjects are client IPs or user ids
+func (l *Limiter) Allow(ctx context.Context, route, subject string) (Result, error) {
+	p, ok := l.policies[route]
+	if !ok {
+		return Result{Allowed: true}, nil
+	}
+
+	return l.store.Take(ctx, route+":"+subject, p, time.Now())
+}
+
+var DefaultPolicies = map[string]Policy{
+	"signIn":        {Limit: 20, Period: time.Minute, KeyBy: IP},
+	"signUp":        {Limit: 5, Period: 10 * time.Minute, KeyBy: IP},
+	"refresh":       {Limit: 30, Period: time.Minute, KeyBy: IP},
+	"google-signIn": {Limit: 20, Period: time.Minute, KeyBy: IP},
+	"oauth-token":   {Limit: 60, Period: time.Minute, KeyBy: IP},
+
+	"password":        {Limit: 10, Period: 15 * time.Minute, KeyBy: USER},
+	"password-forgot": {Limit: 5, Period: 15 * time.Minute, KeyBy: IP},
+	"password-reset":  {Limit: 10, Period: 15 * time.Minute, KeyBy: IP},
+	"signUp-confirm":  {Limit: 10, Period: 15 * time.Minute, KeyBy: IP},
+	"signIn-report":   {Limit: 10, Period: 15 * time.Minute, KeyBy: IP},
+}
+
+// ParsePolicies reads comma separated "route=limit/period/key" entries, e.g. "signUp=5/10m/ip,tokens=60/1m/user",
+// on top of the defaults
+func ParsePolicies(value string) (map[string]Policy, error) {
+	policies := make(map[string]Policy, len(DefaultPolicies))
+	for route, p := range DefaultPolicies {
+		policies[route] = p
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, spec, found := strings.Cut(entry, "=")
+		parts := strings.Split(spec, "/")
+		if !found || route == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("invalid rate limit policy %q", entry)
+		}
+
+		limit, err := strconv.Atoi(parts[0])
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid rate limit in policy %q", entry)
+		}
+
+		// zero limit switches the route limit off
+		if limit == 0 {
+			delete(policies, route)
+			continue
+		}
+
+		period, err := time.ParseDuration(parts[1])
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid rate limit period in policy %q", entry)
+		}
+
+		keyBy, err := KeyByFromString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit key in policy %q", entry)
+		}
+
+		policies[route] = Policy{Limit: limit, Period: period, KeyBy: keyBy}
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket_Take(t *testing.T) {
+	policy := Policy{Limit: 2, Period: 10 * time.Second, KeyBy: IP}
+	start := time.Now()
+
+	tt := []struct {
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{at: 0, allowed: true, remaining: 1},
+		{at: time.Second, allowed: true, remaining: 0},
+		{at: 2 * time.Second, allowed: false, remaining: 0, retryAfter: 3 * time.Second},
+		{at: 7 * time.Second, allowed: true, remaining: 0},
+		{at: time.Minute, allowed: true, remaining: 1},
+	}
+
+	bucket := Bucket{}
+	for _, tc := range tt {
+		var result Result
+		bucket, result = bucket.Take(policy, start.Add(tc.at))
+
+		if result.Allowed != tc.allowed || result.Remaining != tc.remaining || result.RetryAfter != tc.retryAfter {
+			t.Errorf("Take at %s: want allowed '%t' remaining '%d' retry '%s', got '%t' '%d' '%s'",
+				tc.at, tc.allowed, tc.remaining, tc.retryAfter, result.Allowed, result.Remaining, result.RetryAfter)
+		}
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies("signUp=10/1h/ip, tokens=60/1m/user, refresh=0/1m/ip")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if want := (Policy{Limit: 10, Period: time.Hour, KeyBy: IP}); policies["signUp"] != want {
+		t.Errorf("signUp: want '%v', got '%v'", want, policies["signUp"])
+	}
+
+	if want := (Policy{Limit: 60, Period: time.Minute, KeyBy: USER}); policies["tokens"] != want {
+		t.Errorf("tokens: want '%v', got '%v'", want, policies["tokens"])
+	}
+
+	if _, ok := policies["refresh"]; ok {
+		t.Errorf("refresh: want the policy switched off")
+	}
+
+	if policies["signIn"] != DefaultPolicies["signIn"] {
+		t.Errorf("signIn: want the default policy, got '%v'", policies["signIn"])
+	}
+
+	for _, invalid := range []string{"signUp", "signUp=5/1m", "signUp=x/1m/ip", "signUp=5/x/ip", "signUp=5/1m/host"} {
+		if _, err := ParsePolicies(invalid); err == nil {
+			t.Errorf("ParsePolicies(%q): want an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package role
+
+import "errors"
+
+type Role struct {
+	v string
+}
+
+var (
+	UNKNOWN = Role{"UNKNOWN"}
+	USER    = Role{"user"}
+	ADMIN   = Role{"admin"}
+)
+
+func (c Role) String() string {
+	return c.v
+}
+
+func FromString(value string) (Role, error) {
+	switch value {
+	case USER.String():
+		return USER, nil
+	case ADMIN.String():
+		return ADMIN, nil
+	}
+
+	return UNKNOWN, errors.New("Invalid role value")
+}
@@ -0,0 +1,118 @@
+package scope
+
+import (
+	"errors"
+	"strings"
+)
+
+type Scope struct {
+	v string
+}
+
+var (
+	UNKNOWN          = Scope{"UNKNOWN"}
+	PROFILE_READ     = Scope{"profile:read"}
+	SETTINGS_WRITE   = Scope{"settings:write"}
+	HOUSEHOLDS_WRITE = Scope{"households:write"}
+	TOKENS_WRITE     = Scope{"tokens:write"}
+	ADMIN            = Scope{"admin"}
+)
+
+func (c Scope) String() string {
+	return c.v
+}
+
+func FromString(value string) (Scope, error) {
+	switch value {
+	case PROFILE_READ.String():
+		return PROFILE_READ, nil
+	case SETTINGS_WRITE.String():
+		return SETTINGS_WRITE, nil
+	case HOUSEHOLDS_WRITE.String():
+		return HOUSEHOLDS_WRITE, nil
+	case TOKENS_WRITE.String():
+		return TOKENS_WRITE, nil
+	case ADMIN.String():
+		return ADMIN, nil
+	}
+
+	return UNKNOWN, errors.New("Invalid scope value")
+}
+
+// Full is granted to first-party sessions obtained by signing in
+func Full() []Scope {
+	return []Scope{PROFILE_READ, SETTINGS_WRITE, HOUSEHOLDS_WRITE, TOKENS_WRITE, ADMIN}
+}
+
+// Legacy is granted to sessions signed in before scopes were introduced, admin routes need a new sign in
+func Legacy() []Scope {
+	return []Scope{PROFILE_READ, SETTINGS_WRITE, HOUSEHOLDS_WRITE, TOKENS_WRITE}
+}
+
+// Impersonation is granted to admins acting as a user, they can not manage credentials or act as admins
+func Impersonation() []Scope {
+	return []Scope{PROFILE_READ, SETTINGS_WRITE, HOUSEHOLDS_WRITE}
+}
+
+// Delegable lists scopes which may be granted to personal access tokens and service accounts
+func Delegable() []Scope {
+	return []Scope{PROFILE_READ, SETTINGS_WRITE, HOUSEHOLDS_WRITE}
+}
+
+func IsDelegable(s Scope) bool {
+	return Contains(Delegable(), s)
+}
+
+func Contains(scopes []Scope, s Scope) bool {
+	for _, granted := range scopes {
+		if granted == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FromStrings converts a list of scope values failing on the first unknown one
+func FromStrings(values []string) ([]Scope, error) {
+	scopes := make([]Scope, 0, len(values))
+	for _, value := range values {
+		s, err := FromString(value)
+		if err != nil {
+			return []Scope{}, err
+		}
+
+		scopes = append(scopes, s)
+	}
+
+	return scopes, nil
+}
+
+func ToStrings(scopes []Scope) []string {
+	values := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		values = append(values, s.String())
+	}
+
+	return values
+}
+
+// Join formats scopes as a space-delimited claim value (RFC 8693 section 4.2)
+func Join(scopes []Scope) string {
+	return strings.Join(ToStrings(scopes), " ")
+}
+
+// Parse reads a space-delimited claim value skipping unknown scopes
+func Parse(value string) []Scope {
+	scopes := []Scope{}
+	for _, field := range strings.Fields(value) {
+		s, err := FromString(field)
+		if err != nil {
+			continue
+		}
+
+		scopes = append(scopes, s)
+	}
+
+	return scopes
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+)
+
+const maxAuditUserAgentLength = 512
+
+// recordEvent appends to the audit trail with the client of the request, a failed write is logged and does
+// not fail the audited action
+func (h *AuthService) recordEvent(ctx context.Context, eventType audit.Type, userUUID uuid.UUID, email string) {
+	h.recordActorEvent(ctx, eventType, userUUID, uuid.Nil, email)
+}
+
+func (h *AuthService) recordActorEvent(ctx context.Context, eventType audit.Type, userUUID, actorUUID uuid.UUID, email string) {
+	if h.authEventRepository == nil {
+		return
+	}
+
+	info := ClientInfoFromContext(ctx)
+	userAgent := info.UserAgent
+	if len(userAgent) > maxAuditUserAgentLength {
+		userAgent = userAgent[:maxAuditUserAgentLength]
+	}
+
+	event := &audit.Event{
+		UUID:      uuid.New(),
+		Type:      eventType,
+		UserUUID:  userUUID,
+		ActorUUID: actorUUID,
+		Email:     email,
+		IP:        info.IP,
+		UserAgent: userAgent,
+		RequestID: info.RequestID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := h.authEventRepository.Add(ctx, event); err != nil {
+		log.Printf("auth event %s for %s not recorded: %v", eventType, userUUID, err)
+	}
+}
+
+// ListActivity returns the own auth events of the user, newest first
+func (h *AuthService) ListActivity(ctx context.Context, userUUID uuid.UUID, before time.Time, limit int) ([]*audit.Event, error) {
+	return h.authEventRepository.Find(ctx, audit.Query{
+		UserUUID: userUUID,
+		Before:   before,
+		Limit:    limit,
+	})
+}
+
+// ListAuthEvents lets admins query the auth events of every user
+func (h *AuthService) ListAuthEvents(ctx context.Context, actorUUID uuid.UUID, q audit.Query) ([]*audit.Event, error) {
+	if err := h.requireAdmin(ctx, actorUUID); err != nil {
+		return []*audit.Event{}, err
+	}
+
+	return h.authEventRepository.Find(ctx, q)
+}
@@ -2,16 +2,23 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
 	"github.com/ibgl/microservice-users/internal/app/currency"
 	"github.com/ibgl/microservice-users/internal/app/day"
 	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
 	appJwt "github.com/ibgl/microservice-users/internal/app/jwt"
+	"github.com/ibgl/microservice-users/internal/app/password"
+	"github.com/ibgl/microservice-users/internal/app/role"
+	"github.com/ibgl/microservice-users/internal/app/scope"
 	"google.golang.org/api/idtoken"
 )
 
@@ -21,19 +28,46 @@ type LoginResponse struct {
 }
 
 type Token struct {
-	Value  string
-	UserId uuid.UUID
+	Value           string
+	UserId          uuid.UUID
+	ActorId         uuid.UUID
+	PersonalTokenId uuid.UUID
+	ClientId        string
+	HouseholdId     uuid.UUID
+	Scopes          []scope.Scope
+}
+
+// IsService reports whether the token was issued to a service account rather than a user
+func (t Token) IsService() bool {
+	return t.ClientId != ""
+}
+
+// IsImpersonated reports whether the token was issued to an admin acting as the user
+func (t Token) IsImpersonated() bool {
+	return t.ActorId != uuid.Nil
+}
+
+// IsPersonal reports whether the token is a personal access token rather than a JWT
+func (t Token) IsPersonal() bool {
+	return t.PersonalTokenId != uuid.Nil
+}
+
+// HasScope reports whether the token was granted the scope
+func (t Token) HasScope(s scope.Scope) bool {
+	return scope.Contains(t.Scopes, s)
 }
 
 type SignInRequest struct {
-	Email    string
-	Password string
+	Email        string
+	Password     string
+	CaptchaToken string
 }
 
 type SignUpRequest struct {
-	Email    string
-	Password string
-	Name     string
+	Email        string
+	Password     string
+	Name         string
+	CaptchaToken string
 }
 
 type UpdateSettingsRequest struct {
@@ -44,57 +78,288 @@ type UpdateSettingsRequest struct {
 }
 
 type AuthService struct {
-	userRepository    UserRepository
-	jwtService        *appJwt.JWTService
-	refreshRepository RefreshJWTRepository
-	maxUserSessions   int
-	googleKey         string
+	userRepository                UserRepository
+	jwtService                    *appJwt.JWTService
+	refreshRepository             RefreshJWTRepository
+	impersonationRepository       ImpersonationRepository
+	personalAccessTokenRepository PersonalAccessTokenRepository
+	clientRepository              ClientRepository
+	loginThrottler                *LoginThrottler
+	passwordHasher                PasswordHasher
+	passwordPolicy                password.Policy
+	passwordResetRepository       PasswordResetRepository
+	pendingSignUpRepository       PendingSignUpRepository
+	authEventRepository           audit.Repository
+	captchaVerifier               CaptchaVerifier
+	captchaAfterFailures          int
+	emailDomainFilter             EmailDomainFilter
+	transactor                    Transactor
+	mailer                        MailSender
+	eventRepository               event.Repository
+	maxUserSessions               int
+	googleKey                     string
+	frontendUrl                   string
+
+	dummyHashMu sync.Mutex
+	dummyHash   string
 }
 
 type RefreshJWTRepository interface {
-	Add(ctx context.Context, refresh *appJwt.RefreshJWT) error
+	Add(ctx context.Context, refresh *appJwt.RefreshJWT, deviceFingerprint string) error
 	Exists(ctx context.Context, uuid, userUUID uuid.UUID, token string) (bool, error)
 	Delete(ctx context.Context, uuid uuid.UUID) error
 	DeleteForUserUUID(ctx context.Context, userUUID uuid.UUID) error
+	DeleteOthersForUserUUID(ctx context.Context, userUUID, keepUUID uuid.UUID) error
 	CountForUser(ctx context.Context, userUUID uuid.UUID) (int, error)
+	HasDevice(ctx context.Context, userUUID uuid.UUID, deviceFingerprint string) (bool, error)
+	HasDevices(ctx context.Context, userUUID uuid.UUID) (bool, error)
+}
+
+// Transactor runs the callback in a transaction carried by its context, repositories and the mailer join it
+type Transactor interface {
+	Transactional(ctx context.Context, cb func(ctx context.Context) error) error
 }
 
-func NewAuthService(ur UserRepository, jwt *appJwt.JWTService, rfr RefreshJWTRepository, mus int, googleKey string) *AuthService {
+// AuthServiceConfig holds the collaborators and settings of the auth service, nil collaborators other than
+// the password hasher switch their feature off (no lockout, captcha, domain filter, emails or events)
+type AuthServiceConfig struct {
+	LoginThrottler       *LoginThrottler
+	PasswordHasher       PasswordHasher
+	PasswordPolicy       password.Policy
+	CaptchaVerifier      CaptchaVerifier
+	CaptchaAfterFailures int
+	EmailDomainFilter    EmailDomainFilter
+	Transactor           Transactor
+	Mailer               MailSender
+	Events               event.Repository
+	MaxUserSessions      int
+	GoogleKey            string
+	FrontendUrl          string
+}
+
+func NewAuthService(
+	ur UserRepository,
+	jwt *appJwt.JWTService,
+	rfr RefreshJWTRepository,
+	ir ImpersonationRepository,
+	patr PersonalAccessTokenRepository,
+	cr ClientRepository,
+	prr PasswordResetRepository,
+	psr PendingSignUpRepository,
+	aer audit.Repository,
+	config *AuthServiceConfig,
+) *AuthService {
 	return &AuthService{
-		userRepository:    ur,
-		jwtService:        jwt,
-		refreshRepository: rfr,
-		maxUserSessions:   mus,
-		googleKey:         googleKey,
+		userRepository:                ur,
+		jwtService:                    jwt,
+		refreshRepository:             rfr,
+		impersonationRepository:       ir,
+		personalAccessTokenRepository: patr,
+		clientRepository:              cr,
+		loginThrottler:                config.LoginThrottler,
+		passwordHasher:                config.PasswordHasher,
+		passwordPolicy:                config.PasswordPolicy,
+		passwordResetRepository:       prr,
+		pendingSignUpRepository:       psr,
+		authEventRepository:           aer,
+		captchaVerifier:               config.CaptchaVerifier,
+		captchaAfterFailures:          config.CaptchaAfterFailures,
+		emailDomainFilter:             config.EmailDomainFilter,
+		transactor:                    config.Transactor,
+		mailer:                        config.Mailer,
+		eventRepository:               config.Events,
+		maxUserSessions:               config.MaxUserSessions,
+		googleKey:                     config.GoogleKey,
+		frontendUrl:                   strings.TrimRight(config.FrontendUrl, "/"),
+	}
+}
+
+// transactional commits the changes of the callback together with the emails and events it queues
+func (h *AuthService) transactional(ctx context.Context, cb func(ctx context.Context) error) error {
+	if h.transactor == nil {
+		return cb(ctx)
 	}
+
+	return h.transactor.Transactional(ctx, cb)
 }
 
 func (h *AuthService) ValidateToken(ctx context.Context, token string) (Token, error) {
+	if IsPersonalAccessToken(token) {
+		return h.validatePersonalAccessToken(ctx, token)
+	}
+
 	access, err := h.jwtService.ValidateAccess(token)
 	if err != nil {
 		return Token{}, err
 	}
 
+	scopes := scope.Parse(access.Claims.Scope)
+	if access.Claims.IsLegacy() && access.Claims.ClientId == "" && access.Claims.Act == nil {
+		// first-party tokens issued before scopes were introduced
+		scopes = scope.Legacy()
+	}
+
 	return Token{
-		Value:  access.Token,
-		UserId: access.Claims.UserId,
+		Value:       access.Token,
+		UserId:      access.Claims.UserId,
+		ActorId:     access.Claims.ActorId(),
+		ClientId:    access.Claims.ClientId,
+		HouseholdId: access.Claims.ActiveHousehold(),
+		Scopes:      scopes,
 	}, nil
 }
 
+func (h *AuthService) requireAdmin(ctx context.Context, userUUID uuid.UUID) error {
+	actor, err := h.userRepository.FindById(ctx, userUUID)
+	if err != nil {
+		return appErr.NewAuthorizationError(err.Error(), "invalid-token")
+	}
+
+	if !actor.IsAdmin() {
+		return appErr.NewForbiddenError("Admin role required", "admin-required")
+	}
+
+	return nil
+}
+
 func (h *AuthService) SignIn(ctx context.Context, r *SignInRequest) (*LoginResponse, error) {
+	ip := ClientInfoFromContext(ctx).IP
+	if err := h.loginThrottler.Check(ctx, r.Email, ip); err != nil {
+		return &LoginResponse{}, err
+	}
+
+	if err := h.verifySignInCaptcha(ctx, r); err != nil {
+		return &LoginResponse{}, err
+	}
+
 	userFound, err := h.userRepository.FindByEmail(ctx, r.Email)
 	if err != nil {
-		return &LoginResponse{}, appErr.NewIncorrectInputError("User not found", "invalid-credentials")
+		// unknown emails are checked against a dummy hash so that they take as long as wrong passwords
+		if err := h.checkDummyPassword(ctx, r.Password); err != nil {
+			return &LoginResponse{}, err
+		}
+
+		return &LoginResponse{}, h.signInFailed(ctx, r.Email, ip, uuid.Nil)
+	}
+
+	valid, err := h.checkPassword(ctx, r.Password, userFound.Hash)
+	if err != nil {
+		return &LoginResponse{}, err
+	}
+
+	if !valid {
+		return &LoginResponse{}, h.signInFailed(ctx, r.Email, ip, userFound.UUID)
+	}
+
+	if err := h.loginThrottler.RegisterSuccess(ctx, r.Email); err != nil {
+		return &LoginResponse{}, err
+	}
+
+	h.rehashPassword(ctx, userFound, r.Password)
+	h.recordEvent(ctx, audit.SIGN_IN, userFound.UUID, userFound.Email)
+
+	return h.createTokens(ctx, userFound, true)
+}
+
+// checkPassword reports a mismatch as false, errors are left for hashing failures like an overloaded pool
+func (h *AuthService) checkPassword(ctx context.Context, plain, hash string) (bool, error) {
+	ok, err := h.passwordHasher.Verify(ctx, plain, hash)
+	if errors.Is(err, password.ErrUnsupportedHash) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, hashingError(err, "could-not-authorize-user")
+	}
+
+	return ok, nil
+}
+
+// checkDummyPassword spends the time of a password check, the dummy hash is made once with the current
+// hasher so that it costs the same as the hashes of registered users
+func (h *AuthService) checkDummyPassword(ctx context.Context, plain string) error {
+	h.dummyHashMu.Lock()
+	if h.dummyHash == "" {
+		hash, err := h.hashPassword(ctx, GeneratePassword(), "could-not-authorize-user")
+		if err != nil {
+			h.dummyHashMu.Unlock()
+			return err
+		}
+
+		h.dummyHash = hash
+	}
+	hash := h.dummyHash
+	h.dummyHashMu.Unlock()
+
+	_, err := h.checkPassword(ctx, plain, hash)
+	return err
+}
+
+func (h *AuthService) hashPassword(ctx context.Context, plain, slug string) (string, error) {
+	hash, err := h.passwordHasher.Hash(ctx, plain)
+	if err != nil {
+		return "", hashingError(err, slug)
+	}
+
+	return hash, nil
+}
+
+func hashingError(err error, slug string) error {
+	if errors.Is(err, password.ErrBusy) {
+		return appErr.NewUnavailableError(err.Error(), "server-busy", time.Second)
+	}
+
+	return appErr.NewAppError(err.Error(), slug)
+}
+
+// rehashPassword upgrades a hash made with an outdated algorithm or cost while the plain password is at hand,
+// a failure only postpones the upgrade to the next sign in
+func (h *AuthService) rehashPassword(ctx context.Context, u *User, plain string) {
+	if !h.passwordHasher.NeedsRehash(u.Hash) {
+		return
+	}
+
+	hash, err := h.passwordHasher.Hash(ctx, plain)
+	if err == nil {
+		err = h.userRepository.UpdateHash(ctx, u.UUID, hash)
+	}
+
+	if err != nil {
+		log.Printf("password rehash failed for %s: %v", u.UUID, err)
+		return
+	}
+
+	u.Hash = hash
+}
+
+// checkEmailDomain refuses new accounts with emails of denied or disposable domains, registered users are not affected
+func (h *AuthService) checkEmailDomain(email string) error {
+	if h.emailDomainFilter == nil || h.emailDomainFilter.Allowed(email) {
+		return nil
 	}
 
-	if !CheckPasswordHash(r.Password, userFound.Hash) {
-		return &LoginResponse{}, appErr.NewIncorrectInputError("User not found", "invalid-credentials")
+	return appErr.NewIncorrectInputError("Email domain is not allowed", "field-email-domain-not-allowed")
+}
+
+func (h *AuthService) signInFailed(ctx context.Context, email, ip string, userUUID uuid.UUID) error {
+	h.recordEvent(ctx, audit.SIGN_IN_FAILED, userUUID, email)
+
+	if err := h.loginThrottler.RegisterFailure(ctx, email, ip); err != nil {
+		return err
 	}
 
-	return h.createTokens(ctx, userFound)
+	return appErr.NewIncorrectInputError("User not found", "invalid-credentials")
 }
 
 func (h *AuthService) SignUp(ctx context.Context, r *SignUpRequest) (*LoginResponse, error) {
+	if err := h.verifyCaptcha(ctx, r.CaptchaToken); err != nil {
+		return &LoginResponse{}, err
+	}
+
+	if err := h.checkEmailDomain(r.Email); err != nil {
+		return &LoginResponse{}, err
+	}
+
 	_, err := h.userRepository.FindByEmail(ctx, r.Email)
 	if err != nil {
 		if !appErr.IsNotFound(err) {
@@ -104,9 +369,13 @@ func (h *AuthService) SignUp(ctx context.Context, r *SignUpRequest) (*LoginRespo
 		return &LoginResponse{}, appErr.NewIncorrectInputError("Email already in use", "field-email-invalid")
 	}
 
-	hash, err := HashPassword(r.Password)
+	if err := h.passwordPolicy.Validate(r.Password, r.Email, r.Name); err != nil {
+		return &LoginResponse{}, err
+	}
+
+	hash, err := h.hashPassword(ctx, r.Password, "create-user-error")
 	if err != nil {
-		return &LoginResponse{}, appErr.NewAppError(err.Error(), "create-user-error")
+		return &LoginResponse{}, err
 	}
 
 	user := NewUser(
@@ -114,28 +383,47 @@ func (h *AuthService) SignUp(ctx context.Context, r *SignUpRequest) (*LoginRespo
 		r.Email,
 		r.Name,
 		hash,
+		role.USER,
 		DefaultUserSettings(),
 		time.Now(),
 		time.Now(),
 	)
 
-	err = h.userRepository.Transactional(ctx, func(r UserRepository) error {
-		return h.userRepository.Add(ctx, user)
-	})
-
+	err = h.addUser(ctx, user)
 	if err != nil {
 		return &LoginResponse{}, appErr.NewAppError(err.Error(), "user-saving-error")
 	}
 
-	return h.createTokens(ctx, user)
+	h.recordEvent(ctx, audit.SIGN_UP, user.UUID, user.Email)
+
+	return h.createTokens(ctx, user, false)
+}
+
+// addUser saves a new user together with its user.created event
+func (h *AuthService) addUser(ctx context.Context, user *User) error {
+	return h.transactional(ctx, func(ctx context.Context) error {
+		return h.userRepository.Transactional(ctx, func(r UserRepository) error {
+			if err := r.Add(ctx, user); err != nil {
+				return err
+			}
+
+			return h.emitUser(ctx, event.USER_CREATED, user)
+		})
+	})
 }
 
-func (h *AuthService) createTokens(ctx context.Context, user *User) (*LoginResponse, error) {
+// createTokens starts a session on the device of the client, signIn tells sign ins of existing users, which are
+// mailed about when the device is new to them, from sign ups and refreshes
+func (h *AuthService) createTokens(ctx context.Context, user *User, signIn bool) (*LoginResponse, error) {
 	accessClaims := appJwt.NewAccessClaims(
 		user.UUID,
 		user.Email,
 		user.Name,
 	)
+	accessClaims.Scope = scope.Join(scope.Full())
+	if user.ActiveHouseholdUUID != uuid.Nil {
+		accessClaims.HouseholdId = user.ActiveHouseholdUUID.String()
+	}
 
 	refreshClaims := appJwt.NewRefreshClaims(
 		user.UUID,
@@ -143,23 +431,29 @@ func (h *AuthService) createTokens(ctx context.Context, user *User) (*LoginRespo
 
 	var access *appJwt.AccessJWT
 	var refresh *appJwt.RefreshJWT
-	var err error
+	var accessErr, refreshErr error
 	var wg sync.WaitGroup
 
+	wg.Add(2)
+
 	go func() {
-		access, err = h.jwtService.CreateAccess(*accessClaims)
+		access, accessErr = h.jwtService.CreateAccess(*accessClaims)
 		wg.Done()
 	}()
 
 	go func() {
-		refresh, err = h.jwtService.CreateRefresh(*refreshClaims)
+		refresh, refreshErr = h.jwtService.CreateRefresh(*refreshClaims)
 		wg.Done()
 	}()
 
-	wg.Add(2)
+	wg.Wait()
 
-	if err != nil {
-		return &LoginResponse{}, appErr.NewAuthorizationError(err.Error(), "could-not-authorize-user")
+	if accessErr != nil {
+		return &LoginResponse{}, appErr.NewAuthorizationError(accessErr.Error(), "could-not-authorize-user")
+	}
+
+	if refreshErr != nil {
+		return &LoginResponse{}, appErr.NewAuthorizationError(refreshErr.Error(), "could-not-authorize-user")
 	}
 
 	refreshCount, err := h.refreshRepository.CountForUser(ctx, refreshClaims.UserId)
@@ -167,26 +461,47 @@ func (h *AuthService) createTokens(ctx context.Context, user *User) (*LoginRespo
 		return &LoginResponse{}, appErr.NewAuthorizationError(err.Error(), "could-not-authorize-user")
 	}
 
+	fingerprint := DeviceFingerprint(ClientInfoFromContext(ctx))
+	newDevice := false
+	if signIn {
+		newDevice, err = h.isNewDevice(ctx, user.UUID, fingerprint, refreshCount)
+		if err != nil {
+			return &LoginResponse{}, appErr.NewAuthorizationError(err.Error(), "could-not-authorize-user")
+		}
+	}
+
 	if refreshCount > h.maxUserSessions {
-		err = h.refreshRepository.DeleteForUserUUID(ctx, refreshClaims.UserId)
+		err = h.transactional(ctx, func(ctx context.Context) error {
+			if err := h.refreshRepository.DeleteForUserUUID(ctx, refreshClaims.UserId); err != nil {
+				return err
+			}
+
+			return h.emitSessionRevoked(ctx, refreshClaims.UserId, uuid.Nil, event.ReasonSessionLimit)
+		})
 		if err != nil {
 			return &LoginResponse{}, appErr.NewAuthorizationError(err.Error(), "could-not-authorize-user")
 		}
 	}
 
-	err = h.refreshRepository.Add(ctx, refresh)
+	err = h.refreshRepository.Add(ctx, refresh, fingerprint)
 	if err != nil {
 		return &LoginResponse{}, appErr.NewAuthorizationError(err.Error(), "could-not-authorize-user")
 	}
 
+	if newDevice {
+		h.notifyNewDevice(ctx, user)
+	}
+
 	return &LoginResponse{
 		Access: Token{
-			access.Token,
-			access.Claims.UserId,
+			Value:       access.Token,
+			UserId:      access.Claims.UserId,
+			HouseholdId: user.ActiveHouseholdUUID,
+			Scopes:      scope.Full(),
 		},
 		Refresh: Token{
-			refresh.Token,
-			refresh.Claims.UserId,
+			Value:  refresh.Token,
+			UserId: refresh.Claims.UserId,
 		},
 	}, nil
 }
@@ -195,6 +510,19 @@ func (h *AuthService) GetUser(ctx context.Context, userUUID uuid.UUID) (*User, e
 	return h.userRepository.FindById(ctx, userUUID)
 }
 
+// GetUsers returns the existing users among at most MaxBatchUsers uuids, duplicates are looked up once
+func (h *AuthService) GetUsers(ctx context.Context, userUUIDs []uuid.UUID) ([]*User, error) {
+	if len(userUUIDs) > MaxBatchUsers {
+		return []*User{}, appErr.NewIncorrectInputError("Too many users requested", "field-uuids-too-many")
+	}
+
+	if len(userUUIDs) == 0 {
+		return []*User{}, nil
+	}
+
+	return h.userRepository.FindByIds(ctx, userUUIDs)
+}
+
 func (h *AuthService) SaveRefresh(ctx context.Context, userUUID uuid.UUID) (*User, error) {
 	return h.userRepository.FindById(ctx, userUUID)
 }
@@ -224,7 +552,41 @@ func (h *AuthService) Refresh(ctx context.Context, tokenString string) (*LoginRe
 		return &LoginResponse{}, appErr.NewAuthorizationError(err.Error(), "invalid-token")
 	}
 
-	return h.createTokens(ctx, user)
+	h.recordEvent(ctx, audit.REFRESH, user.UUID, user.Email)
+
+	return h.createTokens(ctx, user, false)
+}
+
+// SignOut revokes the refresh token, tokens which are invalid or already revoked have nothing left to revoke
+func (h *AuthService) SignOut(ctx context.Context, tokenString string) error {
+	refresh, err := h.jwtService.ValidateRefresh(tokenString)
+	if err != nil {
+		return nil
+	}
+
+	exists, err := h.refreshRepository.Exists(ctx, refresh.Claims.UUID, refresh.Claims.UserId, tokenString)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return nil
+	}
+
+	err = h.transactional(ctx, func(ctx context.Context) error {
+		if err := h.refreshRepository.Delete(ctx, refresh.Claims.UUID); err != nil {
+			return err
+		}
+
+		return h.emitSessionRevoked(ctx, refresh.Claims.UserId, refresh.Claims.UUID, event.ReasonSignOut)
+	})
+	if err != nil {
+		return err
+	}
+
+	h.recordEvent(ctx, audit.SIGN_OUT, refresh.Claims.UserId, "")
+
+	return nil
 }
 
 func (h *AuthService) UpdateSettings(ctx context.Context, request *UpdateSettingsRequest) (*User, error) {
@@ -245,7 +607,22 @@ func (h *AuthService) UpdateSettings(ctx context.Context, request *UpdateSetting
 		return &User{}, err
 	}
 
-	return h.userRepository.UpdateSettings(ctx, request.UserUUID, &settings)
+	var updated *User
+	err = h.transactional(ctx, func(ctx context.Context) error {
+		updated, err = h.userRepository.UpdateSettings(ctx, request.UserUUID, &settings)
+		if err != nil {
+			return err
+		}
+
+		return h.emitUser(ctx, event.USER_UPDATED, updated)
+	})
+	if err != nil {
+		return updated, err
+	}
+
+	h.recordEvent(ctx, audit.SETTINGS_UPDATED, updated.UUID, updated.Email)
+
+	return updated, nil
 }
 
 func (h *AuthService) GoogleSignIn(ctx context.Context, tokenString string) (*LoginResponse, error) {
@@ -260,16 +637,33 @@ func (h *AuthService) GoogleSignIn(ctx context.Context, tokenString string) (*Lo
 		log.Printf("user found %v %v", authUser, err)
 		settings := authUser.Settings
 		settings.ProfilePictureUrl = fmt.Sprintf("%s", validTok.Claims["picture"])
-		h.userRepository.UpdateSettings(ctx, authUser.UUID, &settings)
+		if settings != authUser.Settings {
+			err := h.transactional(ctx, func(ctx context.Context) error {
+				updated, err := h.userRepository.UpdateSettings(ctx, authUser.UUID, &settings)
+				if err != nil {
+					return err
+				}
+
+				return h.emitUser(ctx, event.USER_UPDATED, updated)
+			})
+			if err != nil {
+				log.Printf("profile picture update failed for %s: %v", authUser.UUID, err)
+			}
+		}
+		h.recordEvent(ctx, audit.GOOGLE_SIGN_IN, authUser.UUID, authUser.Email)
 
-		return h.createTokens(ctx, authUser)
+		return h.createTokens(ctx, authUser, true)
 	} else if !appErr.IsNotFound(err) {
 		return &LoginResponse{}, err
 	}
 
-	hash, err := HashPassword(GeneratePassword())
+	if err := h.checkEmailDomain(fmt.Sprintf("%s", validTok.Claims["email"])); err != nil {
+		return &LoginResponse{}, err
+	}
+
+	hash, err := h.hashPassword(ctx, GeneratePassword(), "create-user-error")
 	if err != nil {
-		return &LoginResponse{}, appErr.NewAppError(err.Error(), "create-user-error")
+		return &LoginResponse{}, err
 	}
 
 	settings := DefaultUserSettings()
@@ -280,15 +674,19 @@ func (h *AuthService) GoogleSignIn(ctx context.Context, tokenString string) (*Lo
 		fmt.Sprintf("%s", validTok.Claims["email"]),
 		fmt.Sprintf("%s", validTok.Claims["name"]),
 		hash,
+		role.USER,
 		DefaultUserSettings(),
 		time.Now(),
 		time.Now(),
 	)
 
-	err = h.userRepository.Add(ctx, authUser)
+	err = h.addUser(ctx, authUser)
 	if err != nil {
 		return &LoginResponse{}, appErr.NewAppError(err.Error(), "user-saving-error")
 	}
 
-	return h.createTokens(ctx, authUser)
+	h.recordEvent(ctx, audit.SIGN_UP, authUser.UUID, authUser.Email)
+	h.recordEvent(ctx, audit.GOOGLE_SIGN_IN, authUser.UUID, authUser.Email)
+
+	return h.createTokens(ctx, authUser, false)
 }
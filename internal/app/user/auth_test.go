@@ -0,0 +1,248 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	appJwt "github.com/ibgl/microservice-users/internal/app/jwt"
+	"github.com/ibgl/microservice-users/internal/app/role"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+)
+
+// memorySessions keeps the refresh tokens of the sessions like the pgsql repository
+type memorySessions struct {
+	RefreshJWTRepository
+	tokens map[uuid.UUID]*appJwt.RefreshJWT
+}
+
+func newMemorySessions() *memorySessions {
+	return &memorySessions{tokens: map[uuid.UUID]*appJwt.RefreshJWT{}}
+}
+
+func (r *memorySessions) Add(ctx context.Context, refresh *appJwt.RefreshJWT, deviceFingerprint string) error {
+	r.tokens[refresh.Claims.UUID] = refresh
+	return nil
+}
+
+func (r *memorySessions) Exists(ctx context.Context, refreshUUID, userUUID uuid.UUID, token string) (bool, error) {
+	refresh, ok := r.tokens[refreshUUID]
+	return ok && refresh.Claims.UserId == userUUID && refresh.Token == token, nil
+}
+
+func (r *memorySessions) Delete(ctx context.Context, refreshUUID uuid.UUID) error {
+	delete(r.tokens, refreshUUID)
+	return nil
+}
+
+func (r *memorySessions) DeleteForUserUUID(ctx context.Context, userUUID uuid.UUID) error {
+	for refreshUUID, refresh := range r.tokens {
+		if refresh.Claims.UserId == userUUID {
+			delete(r.tokens, refreshUUID)
+		}
+	}
+
+	return nil
+}
+
+func (r *memorySessions) CountForUser(ctx context.Context, userUUID uuid.UUID) (int, error) {
+	count := 0
+	for _, refresh := range r.tokens {
+		if refresh.Claims.UserId == userUUID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+type auditRepository struct {
+	audit.Repository
+	events []*audit.Event
+}
+
+func (r *auditRepository) Add(ctx context.Context, e *audit.Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func newTestJwtService() *appJwt.JWTService {
+	return appJwt.NewJwtService(&appJwt.JWTConfig{
+		Secret:           "secret",
+		AccessTTL:        600,
+		RefreshTTL:       86400,
+		ImpersonationTTL: 900,
+	})
+}
+
+func TestAuthService_ValidateTokenLegacyScopes(t *testing.T) {
+	userUUID := uuid.New()
+	jwtService := newTestJwtService()
+	h := &AuthService{jwtService: jwtService}
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, appJwt.AccessClaims{
+		UserId:           userUUID,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := h.ValidateToken(context.Background(), legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.UserId != userUUID || !got.HasScope(scope.PROFILE_READ) || got.HasScope(scope.ADMIN) {
+		t.Errorf("Want the legacy scopes without admin, got %+v", got)
+	}
+
+	refresh, err := jwtService.CreateRefresh(*appJwt.NewRefreshClaims(userUUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.ValidateToken(context.Background(), refresh.Token); err == nil {
+		t.Error("Want a refresh token refused as an access token")
+	}
+
+	report, err := jwtService.CreateSessionReport(appJwt.SessionReportClaims{UserId: userUUID}, SignInReportTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.ValidateToken(context.Background(), report.Token); err == nil {
+		t.Error("Want a session report token refused as an access token")
+	}
+}
+
+func TestAuthService_createTokensHouseholdClaim(t *testing.T) {
+	u := NewUser(uuid.New(), "user@example.com", "User", "hash", role.USER, DefaultUserSettings(), time.Now(), time.Now())
+	h := &AuthService{jwtService: newTestJwtService(), refreshRepository: newMemorySessions(), maxUserSessions: 5}
+
+	tt := []struct {
+		name      string
+		household uuid.UUID
+	}{
+		{name: "Active household", household: uuid.New()},
+		{name: "No household", household: uuid.Nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			u.ActiveHouseholdUUID = tc.household
+
+			tokens, err := h.createTokens(context.Background(), u, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			access, err := h.ValidateToken(context.Background(), tokens.Access.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if access.HouseholdId != tc.household || tokens.Access.HouseholdId != tc.household {
+				t.Errorf("Want hid '%s', got '%s'", tc.household, access.HouseholdId)
+			}
+		})
+	}
+}
+
+func TestAuthService_SignInUnknownEmail(t *testing.T) {
+	u := NewUser(uuid.New(), "user@example.com", "User", "hashed:secret", role.USER, DefaultUserSettings(), time.Now(), time.Now())
+
+	tt := []struct {
+		name  string
+		email string
+	}{
+		{name: "Wrong password", email: u.Email},
+		{name: "Unknown email", email: "unknown@example.com"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hasher := &plainHasher{}
+			h := &AuthService{
+				userRepository: &hashRepository{user: u},
+				loginThrottler: NewLoginThrottler(failuresRepository{}, DefaultAccountLockoutPolicy, DefaultIPLockoutPolicy),
+				passwordHasher: hasher,
+			}
+
+			_, err := h.SignIn(context.Background(), &SignInRequest{Email: tc.email, Password: "guess"})
+			assertSlug(t, err, "invalid-credentials")
+
+			if hasher.verified != 1 {
+				t.Errorf("Want one hash verification, got %d", hasher.verified)
+			}
+		})
+	}
+}
+
+func TestAuthService_SignOut(t *testing.T) {
+	userUUID := uuid.New()
+	jwtService := newTestJwtService()
+
+	stored, err := jwtService.CreateRefresh(*appJwt.NewRefreshClaims(userUUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := jwtService.CreateRefresh(*appJwt.NewRefreshClaims(userUUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access, err := jwtService.CreateAccess(*appJwt.NewAccessClaims(userUUID, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name    string
+		token   string
+		revoked bool
+	}{
+		{name: "Stored session is revoked", token: stored.Token, revoked: true},
+		{name: "Revoked session is ignored", token: revoked.Token},
+		{name: "Access token is ignored", token: access.Token},
+		{name: "Garbage is ignored", token: "garbage"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions := newMemorySessions()
+			sessions.Add(context.Background(), stored, "")
+			events := &eventsRepository{}
+			audits := &auditRepository{}
+			h := &AuthService{jwtService: jwtService, refreshRepository: sessions, eventRepository: events, authEventRepository: audits}
+
+			if err := h.SignOut(context.Background(), tc.token); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tc.revoked {
+				if len(sessions.tokens) != 1 || len(events.events) != 0 || len(audits.events) != 0 {
+					t.Errorf("Want nothing revoked or recorded, got events %+v and audit %+v", events.events, audits.events)
+				}
+				return
+			}
+
+			if len(sessions.tokens) != 0 {
+				t.Errorf("Want the session deleted, got %d sessions", len(sessions.tokens))
+			}
+
+			if len(events.events) != 1 || events.events[0].Type != event.SESSION_REVOKED || events.events[0].UserUUID != userUUID {
+				t.Errorf("Want one session.revoked event, got %+v", events.events)
+			}
+
+			if len(audits.events) != 1 || audits.events[0].Type != audit.SIGN_OUT || audits.events[0].UserUUID != userUUID {
+				t.Errorf("Want one sign out audit event, got %+v", audits.events)
+			}
+		})
+	}
+}
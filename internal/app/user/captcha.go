@@ -0,0 +1,53 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+)
+
+// CaptchaVerifier checks a captcha widget token solved by the client
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// verifyCaptcha passes everything when no verifier is configured
+func (h *AuthService) verifyCaptcha(ctx context.Context, token string) error {
+	if h.captchaVerifier == nil {
+		return nil
+	}
+
+	if token == "" {
+		return appErr.NewIncorrectInputError("Captcha is required", "captcha-required")
+	}
+
+	ok, err := h.captchaVerifier.Verify(ctx, token, ClientInfoFromContext(ctx).IP)
+	if err != nil {
+		return appErr.NewUnavailableError(err.Error(), "captcha-unavailable", 5*time.Second)
+	}
+
+	if !ok {
+		return appErr.NewIncorrectInputError("Captcha is invalid", "captcha-invalid")
+	}
+
+	return nil
+}
+
+// verifySignInCaptcha asks for a captcha once the account has captchaAfterFailures failed sign ins
+func (h *AuthService) verifySignInCaptcha(ctx context.Context, r *SignInRequest) error {
+	if h.captchaVerifier == nil || h.captchaAfterFailures <= 0 {
+		return nil
+	}
+
+	failures, err := h.loginThrottler.Failures(ctx, r.Email)
+	if err != nil {
+		return err
+	}
+
+	if failures < h.captchaAfterFailures {
+		return nil
+	}
+
+	return h.verifyCaptcha(ctx, r.CaptchaToken)
+}
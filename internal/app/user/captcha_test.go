@@ -0,0 +1,73 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+)
+
+type failuresRepository map[string]int
+
+func (r failuresRepository) Get(ctx context.Context, key string) (*LoginAttempts, error) {
+	return &LoginAttempts{Key: key, Failures: r[key], LastFailureAt: time.Now()}, nil
+}
+
+func (r failuresRepository) Increment(ctx context.Context, key string, now time.Time, policy LockoutPolicy) (*LoginAttempts, error) {
+	r[key]++
+	return r.Get(ctx, key)
+}
+
+func (r failuresRepository) Reset(ctx context.Context, key string) error {
+	delete(r, key)
+	return nil
+}
+
+type tokenVerifier string
+
+func (v tokenVerifier) Verify(ctx context.Context, token, remoteIP string) (bool, error) {
+	return token == string(v), nil
+}
+
+func TestAuthService_verifySignInCaptcha(t *testing.T) {
+	attempts := failuresRepository{accountAttemptsKey("failing@example.com"): 3}
+	throttler := NewLoginThrottler(attempts, DefaultAccountLockoutPolicy, DefaultIPLockoutPolicy)
+
+	tt := []struct {
+		name     string
+		verifier CaptchaVerifier
+		email    string
+		token    string
+		want     string
+	}{
+		{name: "Captcha is off without a verifier", email: "failing@example.com"},
+		{name: "Few failures need no captcha", verifier: tokenVerifier("solved"), email: "new@example.com"},
+		{name: "Many failures need a captcha", verifier: tokenVerifier("solved"), email: "failing@example.com", want: "captcha-required"},
+		{name: "Wrong captcha is refused", verifier: tokenVerifier("solved"), email: "failing@example.com", token: "guess", want: "captcha-invalid"},
+		{name: "Solved captcha passes", verifier: tokenVerifier("solved"), email: "failing@example.com", token: "solved"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &AuthService{
+				loginThrottler:       throttler,
+				captchaVerifier:      tc.verifier,
+				captchaAfterFailures: 3,
+			}
+
+			err := h.verifySignInCaptcha(context.Background(), &SignInRequest{Email: tc.email, CaptchaToken: tc.token})
+
+			got := ""
+			if appError, ok := err.(appErr.AppError); ok {
+				got = appError.Slug()
+			} else if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	appJwt "github.com/ibgl/microservice-users/internal/app/jwt"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+)
+
+const ClientIdPrefix = "svc_"
+
+// Client is a service account authenticating with the OAuth client credentials grant
+type Client struct {
+	ClientId   string
+	Name       string
+	SecretHash string
+	Scopes     []scope.Scope
+	CreatedBy  uuid.UUID
+	CreatedAt  time.Time
+}
+
+func (c *Client) Allows(s scope.Scope) bool {
+	return scope.Contains(c.Scopes, s)
+}
+
+type ClientRepository interface {
+	Add(ctx context.Context, client *Client) error
+	FindById(ctx context.Context, clientId string) (*Client, error)
+	List(ctx context.Context) ([]*Client, error)
+	Delete(ctx context.Context, clientId string) error
+}
+
+type CreateClientRequest struct {
+	ActorUUID uuid.UUID
+	Name      string
+	Scopes    []string
+}
+
+// CreatedClient carries the plain client secret, it is never available again after creation
+type CreatedClient struct {
+	Client *Client
+	Secret string
+}
+
+type ClientCredentialsRequest struct {
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type ClientTokenResponse struct {
+	Access    Token
+	ExpiresIn time.Duration
+	Scopes    []scope.Scope
+}
+
+func (h *AuthService) CreateClient(ctx context.Context, r *CreateClientRequest) (*CreatedClient, error) {
+	if err := h.requireAdmin(ctx, r.ActorUUID); err != nil {
+		return &CreatedClient{}, err
+	}
+
+	scopes, err := scope.FromStrings(r.Scopes)
+	if err != nil || len(scopes) == 0 {
+		return &CreatedClient{}, appErr.NewIncorrectInputError("Invalid scopes", "field-scopes-invalid")
+	}
+
+	for _, s := range scopes {
+		if !scope.IsDelegable(s) {
+			return &CreatedClient{}, appErr.NewIncorrectInputError("Scope can not be granted to clients", "field-scopes-invalid")
+		}
+	}
+
+	id := make([]byte, 12)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return &CreatedClient{}, appErr.NewAppError(err.Error(), "client-saving-error")
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return &CreatedClient{}, appErr.NewAppError(err.Error(), "client-saving-error")
+	}
+
+	plainSecret := base64.RawURLEncoding.EncodeToString(secret)
+	hash, err := h.hashPassword(ctx, plainSecret, "client-saving-error")
+	if err != nil {
+		return &CreatedClient{}, err
+	}
+
+	client := &Client{
+		ClientId:   ClientIdPrefix + hex.EncodeToString(id),
+		Name:       r.Name,
+		SecretHash: hash,
+		Scopes:     scopes,
+		CreatedBy:  r.ActorUUID,
+		CreatedAt:  time.Now(),
+	}
+
+	err = h.clientRepository.Add(ctx, client)
+	if err != nil {
+		return &CreatedClient{}, appErr.NewAppError(err.Error(), "client-saving-error")
+	}
+
+	return &CreatedClient{
+		Client: client,
+		Secret: plainSecret,
+	}, nil
+}
+
+func (h *AuthService) ListClients(ctx context.Context, actorUUID uuid.UUID) ([]*Client, error) {
+	if err := h.requireAdmin(ctx, actorUUID); err != nil {
+		return []*Client{}, err
+	}
+
+	return h.clientRepository.List(ctx)
+}
+
+func (h *AuthService) DeleteClient(ctx context.Context, actorUUID uuid.UUID, clientId string) error {
+	if err := h.requireAdmin(ctx, actorUUID); err != nil {
+		return err
+	}
+
+	return h.clientRepository.Delete(ctx, clientId)
+}
+
+// ClientCredentials implements the OAuth 2.0 client credentials grant (RFC 6749 section 4.4)
+func (h *AuthService) ClientCredentials(ctx context.Context, r *ClientCredentialsRequest) (*ClientTokenResponse, error) {
+	client, err := h.clientRepository.FindById(ctx, r.ClientId)
+	if err != nil {
+		if appErr.IsNotFound(err) {
+			return &ClientTokenResponse{}, appErr.NewAuthorizationError("Client not found", "invalid-client")
+		}
+
+		return &ClientTokenResponse{}, err
+	}
+
+	valid, err := h.checkPassword(ctx, r.ClientSecret, client.SecretHash)
+	if err != nil {
+		return &ClientTokenResponse{}, err
+	}
+
+	if !valid {
+		return &ClientTokenResponse{}, appErr.NewAuthorizationError("Invalid client secret", "invalid-client")
+	}
+
+	scopes := client.Scopes
+	if len(r.Scopes) > 0 {
+		scopes, err = scope.FromStrings(r.Scopes)
+		if err != nil {
+			return &ClientTokenResponse{}, appErr.NewIncorrectInputError(err.Error(), "invalid-scope")
+		}
+
+		for _, s := range scopes {
+			if !client.Allows(s) {
+				return &ClientTokenResponse{}, appErr.NewIncorrectInputError("Scope is not allowed for client", "invalid-scope")
+			}
+		}
+	}
+
+	claims := appJwt.NewClientAccessClaims(client.ClientId)
+	claims.Scope = scope.Join(scopes)
+
+	access, err := h.jwtService.CreateAccess(*claims)
+	if err != nil {
+		return &ClientTokenResponse{}, appErr.NewAuthorizationError(err.Error(), "could-not-authorize-client")
+	}
+
+	return &ClientTokenResponse{
+		Access: Token{
+			Value:    access.Token,
+			ClientId: access.Claims.ClientId,
+			Scopes:   scopes,
+		},
+		ExpiresIn: h.jwtService.AccessTTL(),
+		Scopes:    scopes,
+	}, nil
+}
@@ -0,0 +1,23 @@
+package user
+
+import "context"
+
+type clientInfoKey struct{}
+
+// ClientInfo describes the client of the current request, ports put it into the request context
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	RequestID string
+	// Locale is the preferred language of the client, emails are rendered in it
+	Locale string
+}
+
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
@@ -0,0 +1,144 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	appJwt "github.com/ibgl/microservice-users/internal/app/jwt"
+)
+
+const SignInReportTTL = 7 * 24 * time.Hour
+
+// passwordResetRequiredHash is never produced by a hasher, users with it can only sign in after a password reset
+const passwordResetRequiredHash = "!"
+
+// DeviceFingerprint identifies the device of the client by its user agent without version numbers, so that
+// browser updates are not new devices, and by the /24 (IPv4) or /48 (IPv6) network of its IP
+func DeviceFingerprint(info ClientInfo) string {
+	userAgent := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(info.UserAgent)))
+
+	sum := sha256.Sum256([]byte(userAgent + "|" + ipPrefix(info.IP)))
+	return hex.EncodeToString(sum[:])
+}
+
+func ipPrefix(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+
+	return parsed.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+// isNewDevice compares the fingerprint with the sessions of the user. Users whose sessions all predate
+// fingerprints have nothing to compare with and are not reported.
+func (h *AuthService) isNewDevice(ctx context.Context, userUUID uuid.UUID, fingerprint string, sessions int) (bool, error) {
+	known, err := h.refreshRepository.HasDevice(ctx, userUUID, fingerprint)
+	if err != nil || known {
+		return false, err
+	}
+
+	if sessions == 0 {
+		return true, nil
+	}
+
+	fingerprinted, err := h.refreshRepository.HasDevices(ctx, userUUID)
+	if err != nil {
+		return false, err
+	}
+
+	return fingerprinted, nil
+}
+
+// notifyNewDevice mails the user about a sign in from a new device with a link to report it, a failed
+// mail is logged and does not fail the sign in
+func (h *AuthService) notifyNewDevice(ctx context.Context, u *User) {
+	report, err := h.jwtService.CreateSessionReport(appJwt.SessionReportClaims{
+		UserId:  u.UUID,
+		Binding: passwordBinding(u.Hash),
+	}, SignInReportTTL)
+	if err != nil {
+		log.Printf("new sign in of %s not notified: %v", u.UUID, err)
+		return
+	}
+
+	info := ClientInfoFromContext(ctx)
+	err = h.mailer.Send(ctx, u.Email, "new-sign-in", info.Locale, map[string]interface{}{
+		"Name":   u.Name,
+		"Time":   time.Now().UTC().Format(time.RFC1123),
+		"IP":     info.IP,
+		"Device": info.UserAgent,
+		"Link":   fmt.Sprintf("%s/signIn/report?token=%s", h.frontendUrl, report.Token),
+	})
+	if err != nil {
+		log.Printf("new sign in of %s not notified: %v", u.UUID, err)
+	}
+}
+
+// ReportSignIn handles the "this wasn't me" link: every session of the user is revoked, the password stops
+// working and a reset link is mailed. The link is bound to the password, so it is void once it was reset.
+func (h *AuthService) ReportSignIn(ctx context.Context, token string) error {
+	report, err := h.jwtService.ValidateSessionReport(token)
+	if err != nil {
+		return appErr.NewIncorrectInputError("Sign in report token is invalid", "sign-in-report-token-invalid")
+	}
+
+	u, err := h.userRepository.FindById(ctx, report.Claims.UserId)
+	if err != nil {
+		if appErr.IsNotFound(err) {
+			return appErr.NewIncorrectInputError("Sign in report token is invalid", "sign-in-report-token-invalid")
+		}
+
+		return err
+	}
+
+	if u.Hash == passwordResetRequiredHash || report.Claims.Binding != passwordBinding(u.Hash) {
+		return appErr.NewIncorrectInputError("Sign in report token is invalid", "sign-in-report-token-invalid")
+	}
+
+	err = h.transactional(ctx, func(ctx context.Context) error {
+		if err := h.refreshRepository.DeleteForUserUUID(ctx, u.UUID); err != nil {
+			return err
+		}
+
+		if err := h.emitSessionRevoked(ctx, u.UUID, uuid.Nil, event.ReasonSignInReported); err != nil {
+			return err
+		}
+
+		if err := h.userRepository.UpdateHash(ctx, u.UUID, passwordResetRequiredHash); err != nil {
+			return err
+		}
+
+		return h.RequestPasswordReset(ctx, u.Email)
+	})
+	if err != nil {
+		return err
+	}
+
+	h.recordEvent(ctx, audit.SIGN_IN_REPORTED, u.UUID, u.Email)
+
+	return nil
+}
+
+func passwordBinding(hash string) string {
+	return hashMailToken(hash)[:16]
+}
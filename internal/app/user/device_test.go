@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDeviceFingerprint(t *testing.T) {
+	chrome := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"
+	updated := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36"
+	firefox := "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0"
+
+	tt := []struct {
+		name string
+		a, b ClientInfo
+		same bool
+	}{
+		{name: "Same network", a: ClientInfo{IP: "10.0.0.1", UserAgent: chrome}, b: ClientInfo{IP: "10.0.0.200", UserAgent: chrome}, same: true},
+		{name: "Browser update", a: ClientInfo{IP: "10.0.0.1", UserAgent: chrome}, b: ClientInfo{IP: "10.0.0.1", UserAgent: updated}, same: true},
+		{name: "Other network", a: ClientInfo{IP: "10.0.0.1", UserAgent: chrome}, b: ClientInfo{IP: "10.0.1.1", UserAgent: chrome}},
+		{name: "Other browser", a: ClientInfo{IP: "10.0.0.1", UserAgent: chrome}, b: ClientInfo{IP: "10.0.0.1", UserAgent: firefox}},
+		{name: "Same IPv6 network", a: ClientInfo{IP: "2001:db8:1:2::1", UserAgent: chrome}, b: ClientInfo{IP: "2001:db8:1:ff::2", UserAgent: chrome}, same: true},
+		{name: "Other IPv6 network", a: ClientInfo{IP: "2001:db8:1::1", UserAgent: chrome}, b: ClientInfo{IP: "2001:db8:2::1", UserAgent: chrome}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if same := DeviceFingerprint(tc.a) == DeviceFingerprint(tc.b); same != tc.same {
+				t.Errorf("Want same '%t', got '%t'", tc.same, same)
+			}
+		})
+	}
+}
+
+type devicesRepository struct {
+	RefreshJWTRepository
+	fingerprints []string
+}
+
+func (r devicesRepository) HasDevice(ctx context.Context, userUUID uuid.UUID, deviceFingerprint string) (bool, error) {
+	for _, f := range r.fingerprints {
+		if f == deviceFingerprint {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r devicesRepository) HasDevices(ctx context.Context, userUUID uuid.UUID) (bool, error) {
+	for _, f := range r.fingerprints {
+		if f != "" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func TestAuthService_isNewDevice(t *testing.T) {
+	tt := []struct {
+		name         string
+		fingerprints []string
+		fingerprint  string
+		want         bool
+	}{
+		{name: "Known device", fingerprints: []string{"a", "b"}, fingerprint: "b"},
+		{name: "New device", fingerprints: []string{"a"}, fingerprint: "b", want: true},
+		{name: "No sessions", fingerprint: "b", want: true},
+		{name: "Sessions before fingerprints", fingerprints: []string{""}, fingerprint: "b"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &AuthService{refreshRepository: devicesRepository{fingerprints: tc.fingerprints}}
+
+			got, err := h.isNewDevice(context.Background(), uuid.New(), tc.fingerprint, len(tc.fingerprints))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tc.want {
+				t.Errorf("Want '%t', got '%t'", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package user
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/event"
+)
+
+// emit appends a domain event to the outbox in the transaction of the context, so that other services learn of
+// the change exactly when it commits
+func (h *AuthService) emit(ctx context.Context, t event.Type, userUUID uuid.UUID, data interface{}) error {
+	if h.eventRepository == nil {
+		return nil
+	}
+
+	e, err := event.New(t, userUUID, data)
+	if err != nil {
+		return err
+	}
+
+	return h.eventRepository.Add(ctx, e)
+}
+
+func (h *AuthService) emitUser(ctx context.Context, t event.Type, u *User) error {
+	return h.emit(ctx, t, u.UUID, event.UserPayload{
+		UUID:              u.UUID,
+		Email:             u.Email,
+		Name:              u.Name,
+		Role:              u.Role.String(),
+		Currency:          u.Settings.Currency.String(),
+		FirstDayOfWeek:    u.Settings.FirstDayOfWeek.String(),
+		ProfilePictureUrl: u.Settings.ProfilePictureUrl,
+		CreatedAt:         u.CreatedAt,
+		UpdatedAt:         u.UpdatedAt,
+	})
+}
+
+// emitSessionRevoked tells about a revoked session, or about every session of the user for uuid.Nil
+func (h *AuthService) emitSessionRevoked(ctx context.Context, userUUID, sessionUUID uuid.UUID, reason string) error {
+	payload := event.SessionPayload{UserUUID: userUUID, Reason: reason}
+	if sessionUUID != uuid.Nil {
+		payload.SessionUUID = &sessionUUID
+	}
+
+	return h.emit(ctx, event.SESSION_REVOKED, userUUID, payload)
+}
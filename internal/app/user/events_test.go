@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/role"
+)
+
+type settingsRepository struct {
+	UserRepository
+	user *User
+}
+
+func (r settingsRepository) FindById(ctx context.Context, userUUID uuid.UUID) (*User, error) {
+	return r.user, nil
+}
+
+func (r settingsRepository) UpdateSettings(ctx context.Context, userUUID uuid.UUID, settings *UserSettings) (*User, error) {
+	updated := *r.user
+	updated.Settings = *settings
+	return &updated, nil
+}
+
+type eventsRepository struct {
+	event.Repository
+	events []*event.Event
+}
+
+func (r *eventsRepository) Add(ctx context.Context, e *event.Event) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestAuthService_UpdateSettingsEmitsEvent(t *testing.T) {
+	u := NewUser(uuid.New(), "user@example.com", "User", "hash", role.USER, DefaultUserSettings(), time.Now(), time.Now())
+	events := &eventsRepository{}
+	h := &AuthService{userRepository: settingsRepository{user: u}, eventRepository: events}
+
+	_, err := h.UpdateSettings(context.Background(), &UpdateSettingsRequest{UserUUID: u.UUID, Currency: "USD", FirstDayOfWeek: "SUN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events.events) != 1 || events.events[0].Type != event.USER_UPDATED || events.events[0].UserUUID != u.UUID {
+		t.Fatalf("Want one user.updated event, got %+v", events.events)
+	}
+
+	var payload event.UserPayload
+	if err := json.Unmarshal(events.events[0].Data, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.Currency != "USD" || payload.FirstDayOfWeek != "SUN" || payload.Email != u.Email {
+		t.Errorf("Want the updated user in the payload, got %+v", payload)
+	}
+}
+
+func TestAuthService_emitSessionRevoked(t *testing.T) {
+	userUUID, sessionUUID := uuid.New(), uuid.New()
+
+	tt := []struct {
+		name    string
+		session uuid.UUID
+		want    string
+	}{
+		{name: "One session", session: sessionUUID, want: `{"user_uuid":"` + userUUID.String() + `","session_uuid":"` + sessionUUID.String() + `","reason":"sign-out"}`},
+		{name: "Every session", session: uuid.Nil, want: `{"user_uuid":"` + userUUID.String() + `","reason":"sign-out"}`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			events := &eventsRepository{}
+			h := &AuthService{eventRepository: events}
+
+			if err := h.emitSessionRevoked(context.Background(), userUUID, tc.session, event.ReasonSignOut); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(events.events) != 1 || string(events.events[0].Data) != tc.want {
+				t.Errorf("Want payload '%s', got %+v", tc.want, events.events)
+			}
+		})
+	}
+}
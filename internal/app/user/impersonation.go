@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	appJwt "github.com/ibgl/microservice-users/internal/app/jwt"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+)
+
+type ImpersonateRequest struct {
+	ActorUUID  uuid.UUID
+	TargetUUID uuid.UUID
+	Reason     string
+}
+
+// ImpersonationResponse holds an access token only, impersonation sessions can not be refreshed
+type ImpersonationResponse struct {
+	Access    Token
+	ExpiresAt time.Time
+}
+
+type Impersonation struct {
+	UUID       uuid.UUID
+	ActorUUID  uuid.UUID
+	TargetUUID uuid.UUID
+	Reason     string
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+}
+
+type ImpersonationRepository interface {
+	Add(ctx context.Context, impersonation *Impersonation) error
+}
+
+func (h *AuthService) Impersonate(ctx context.Context, r *ImpersonateRequest) (*ImpersonationResponse, error) {
+	if err := h.requireAdmin(ctx, r.ActorUUID); err != nil {
+		return &ImpersonationResponse{}, err
+	}
+
+	if r.ActorUUID == r.TargetUUID {
+		return &ImpersonationResponse{}, appErr.NewIncorrectInputError("Can not impersonate yourself", "impersonation-self")
+	}
+
+	target, err := h.userRepository.FindById(ctx, r.TargetUUID)
+	if err != nil {
+		return &ImpersonationResponse{}, err
+	}
+
+	accessClaims := appJwt.NewAccessClaims(
+		target.UUID,
+		target.Email,
+		target.Name,
+	)
+	accessClaims.Scope = scope.Join(scope.Impersonation())
+	if target.ActiveHouseholdUUID != uuid.Nil {
+		accessClaims.HouseholdId = target.ActiveHouseholdUUID.String()
+	}
+
+	impersonationUUID := uuid.New()
+	access, err := h.jwtService.CreateImpersonationAccess(*accessClaims, r.ActorUUID, impersonationUUID)
+	if err != nil {
+		return &ImpersonationResponse{}, appErr.NewAuthorizationError(err.Error(), "could-not-authorize-user")
+	}
+
+	err = h.impersonationRepository.Add(ctx, &Impersonation{
+		UUID:       impersonationUUID,
+		ActorUUID:  r.ActorUUID,
+		TargetUUID: target.UUID,
+		Reason:     r.Reason,
+		ExpiresAt:  access.Claims.ExpiresAt.Time,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return &ImpersonationResponse{}, appErr.NewAppError(err.Error(), "impersonation-saving-error")
+	}
+
+	h.recordActorEvent(ctx, audit.IMPERSONATION_STARTED, target.UUID, r.ActorUUID, target.Email)
+
+	return &ImpersonationResponse{
+		Access: Token{
+			Value:   access.Token,
+			UserId:  access.Claims.UserId,
+			ActorId: r.ActorUUID,
+			Scopes:  scope.Impersonation(),
+		},
+		ExpiresAt: access.Claims.ExpiresAt.Time,
+	}, nil
+}
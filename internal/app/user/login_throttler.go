@@ -0,0 +1,168 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+)
+
+// LoginAttempts is the failed sign in counter for an account or a client IP
+type LoginAttempts struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+func (a *LoginAttempts) RetryAfter(now time.Time) time.Duration {
+	if a.LockedUntil.After(now) {
+		return a.LockedUntil.Sub(now)
+	}
+
+	return 0
+}
+
+type LoginAttemptRepository interface {
+	// Get returns zero attempts for unknown keys
+	Get(ctx context.Context, key string) (*LoginAttempts, error)
+	// Increment registers a failure and locks the key for the delay of the policy in the same step, so that
+	// concurrent failures can not lose the lock. Counters with the last failure older than the window start over.
+	Increment(ctx context.Context, key string, now time.Time, policy LockoutPolicy) (*LoginAttempts, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// LockoutPolicy allows FreeAttempts failures and then locks for BaseDelay doubling with every next failure
+type LockoutPolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	Window       time.Duration
+}
+
+var (
+	DefaultAccountLockoutPolicy = LockoutPolicy{
+		FreeAttempts: 5,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+	DefaultIPLockoutPolicy = LockoutPolicy{
+		FreeAttempts: 50,
+		BaseDelay:    10 * time.Second,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+)
+
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if failures < p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+
+	return delay
+}
+
+type LoginThrottler struct {
+	repository    LoginAttemptRepository
+	accountPolicy LockoutPolicy
+	ipPolicy      LockoutPolicy
+}
+
+func NewLoginThrottler(r LoginAttemptRepository, accountPolicy, ipPolicy LockoutPolicy) *LoginThrottler {
+	return &LoginThrottler{
+		repository:    r,
+		accountPolicy: accountPolicy,
+		ipPolicy:      ipPolicy,
+	}
+}
+
+func accountAttemptsKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptsKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (h *LoginThrottler) keys(email, ip string) []string {
+	keys := []string{accountAttemptsKey(email)}
+	if ip != "" {
+		keys = append(keys, ipAttemptsKey(ip))
+	}
+
+	return keys
+}
+
+func (h *LoginThrottler) policy(key string) LockoutPolicy {
+	if strings.HasPrefix(key, "ip:") {
+		return h.ipPolicy
+	}
+
+	return h.accountPolicy
+}
+
+// Check refuses sign in while the account or the client IP is locked
+func (h *LoginThrottler) Check(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+
+	for _, key := range h.keys(email, ip) {
+		attempts, err := h.repository.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if wait := attempts.RetryAfter(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return appErr.NewTooManyRequestsError("Too many failed sign in attempts", "too-many-attempts", retryAfter)
+	}
+
+	return nil
+}
+
+// Failures returns the current failed attempts count for the account, failures out of the window of the
+// account policy are not counted like RegisterFailure starts the counter over then
+func (h *LoginThrottler) Failures(ctx context.Context, email string) (int, error) {
+	attempts, err := h.repository.Get(ctx, accountAttemptsKey(email))
+	if err != nil {
+		return 0, err
+	}
+
+	if time.Since(attempts.LastFailureAt) > h.accountPolicy.Window {
+		return 0, nil
+	}
+
+	return attempts.Failures, nil
+}
+
+func (h *LoginThrottler) RegisterFailure(ctx context.Context, email, ip string) error {
+	now := time.Now()
+
+	for _, key := range h.keys(email, ip) {
+		if _, err := h.repository.Increment(ctx, key, now, h.policy(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterSuccess clears the account counter, the IP counter keeps running so that
+// an attacker can not reset it by signing into an own account
+func (h *LoginThrottler) RegisterSuccess(ctx context.Context, email string) error {
+	return h.repository.Reset(ctx, accountAttemptsKey(email))
+}
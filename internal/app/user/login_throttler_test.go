@@ -0,0 +1,90 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy_Delay(t *testing.T) {
+	policy := LockoutPolicy{
+		FreeAttempts: 3,
+		BaseDelay:    10 * time.Second,
+		MaxDelay:     time.Minute,
+		Window:       time.Hour,
+	}
+
+	tt := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 2, want: 0},
+		{failures: 3, want: 10 * time.Second},
+		{failures: 4, want: 20 * time.Second},
+		{failures: 5, want: 40 * time.Second},
+		{failures: 6, want: time.Minute},
+		{failures: 100, want: time.Minute},
+	}
+
+	for _, tc := range tt {
+		if got := policy.Delay(tc.failures); got != tc.want {
+			t.Errorf("Delay(%d): want '%s', got '%s'", tc.failures, tc.want, got)
+		}
+	}
+}
+
+// attemptsRepository returns the stored attempts as they are, the window is left to the throttler
+type attemptsRepository map[string]*LoginAttempts
+
+func (r attemptsRepository) Get(ctx context.Context, key string) (*LoginAttempts, error) {
+	if attempts, ok := r[key]; ok {
+		return attempts, nil
+	}
+
+	return &LoginAttempts{Key: key}, nil
+}
+
+func (r attemptsRepository) Increment(ctx context.Context, key string, now time.Time, policy LockoutPolicy) (*LoginAttempts, error) {
+	return nil, nil
+}
+
+func (r attemptsRepository) Reset(ctx context.Context, key string) error {
+	delete(r, key)
+	return nil
+}
+
+func TestLoginThrottler_Failures(t *testing.T) {
+	tt := []struct {
+		name          string
+		lastFailureAt time.Duration
+		want          int
+	}{
+		{name: "Failures in the window", lastFailureAt: -time.Minute, want: 4},
+		{name: "Failures out of the window", lastFailureAt: -2 * time.Hour, want: 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := attemptsRepository{accountAttemptsKey("user@example.com"): {
+				Failures:      4,
+				LastFailureAt: time.Now().Add(tc.lastFailureAt),
+			}}
+			throttler := NewLoginThrottler(attempts, DefaultAccountLockoutPolicy, DefaultIPLockoutPolicy)
+
+			got, err := throttler.Failures(context.Background(), "user@example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tc.want {
+				t.Errorf("Want %d failures, got %d", tc.want, got)
+			}
+		})
+	}
+
+	throttler := NewLoginThrottler(attemptsRepository{}, DefaultAccountLockoutPolicy, DefaultIPLockoutPolicy)
+	if got, _ := throttler.Failures(context.Background(), "new@example.com"); got != 0 {
+		t.Errorf("Want no failures for an unknown account, got %d", got)
+	}
+}
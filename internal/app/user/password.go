@@ -0,0 +1,252 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+)
+
+const PasswordResetTTL = time.Hour
+
+type PasswordReset struct {
+	UUID      uuid.UUID
+	UserUUID  uuid.UUID
+	Hash      string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (r *PasswordReset) IsUsable(now time.Time) bool {
+	return r.UsedAt == nil && now.Before(r.ExpiresAt)
+}
+
+type PasswordResetRepository interface {
+	Add(ctx context.Context, reset *PasswordReset) error
+	FindByHash(ctx context.Context, hash string) (*PasswordReset, error)
+	MarkUsed(ctx context.Context, uuid uuid.UUID, at time.Time) error
+}
+
+// MailSender renders the named email template in the locale and queues it for the recipient, inside a
+// transaction of the context the email is only sent once it commits
+type MailSender interface {
+	Send(ctx context.Context, recipient, template, locale string, data interface{}) error
+}
+
+type ChangePasswordRequest struct {
+	UserUUID        uuid.UUID
+	CurrentPassword string
+	NewPassword     string
+	// RefreshToken is the session the password is changed from, it stays signed in
+	RefreshToken string
+}
+
+type ResetPasswordRequest struct {
+	Token    string
+	Password string
+}
+
+// hashMailToken is the stored form of tokens sent in links, a leaked table can not be used to follow them
+func hashMailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateMailToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (h *AuthService) validatePassword(password string, u *User) error {
+	return h.passwordPolicy.Validate(password, u.Email, u.Name)
+}
+
+// ChangePassword sets a new password and signs out every other session of the user
+func (h *AuthService) ChangePassword(ctx context.Context, r *ChangePasswordRequest) error {
+	u, err := h.userRepository.FindById(ctx, r.UserUUID)
+	if err != nil {
+		return err
+	}
+
+	valid, err := h.checkPassword(ctx, r.CurrentPassword, u.Hash)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return appErr.NewIncorrectInputError("Current password is invalid", "field-current-password-invalid")
+	}
+
+	if err := h.validatePassword(r.NewPassword, u); err != nil {
+		return err
+	}
+
+	hash, err := h.hashPassword(ctx, r.NewPassword, "password-saving-error")
+	if err != nil {
+		return err
+	}
+
+	current, err := h.currentSession(ctx, u.UUID, r.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	err = h.transactional(ctx, func(ctx context.Context) error {
+		if err := h.userRepository.UpdateHash(ctx, u.UUID, hash); err != nil {
+			return err
+		}
+
+		if err := h.refreshRepository.DeleteOthersForUserUUID(ctx, u.UUID, current); err != nil {
+			return err
+		}
+
+		return h.emitSessionRevoked(ctx, u.UUID, uuid.Nil, event.ReasonPasswordChanged)
+	})
+	if err != nil {
+		return err
+	}
+
+	h.recordEvent(ctx, audit.PASSWORD_CHANGED, u.UUID, u.Email)
+
+	return nil
+}
+
+// currentSession is the stored session of the refresh token, uuid.Nil when the token is missing, invalid
+// or belongs to another user
+func (h *AuthService) currentSession(ctx context.Context, userUUID uuid.UUID, token string) (uuid.UUID, error) {
+	if token == "" {
+		return uuid.Nil, nil
+	}
+
+	refresh, err := h.jwtService.ValidateRefresh(token)
+	if err != nil || refresh.Claims.UserId != userUUID {
+		return uuid.Nil, nil
+	}
+
+	exists, err := h.refreshRepository.Exists(ctx, refresh.Claims.UUID, userUUID, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if !exists {
+		return uuid.Nil, nil
+	}
+
+	return refresh.Claims.UUID, nil
+}
+
+// RequestPasswordReset mails a reset link, unknown emails are silently ignored so that
+// the endpoint can not be used to find registered addresses
+func (h *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := h.userRepository.FindByEmail(ctx, email)
+	if err != nil {
+		if appErr.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	token, err := generateMailToken()
+	if err != nil {
+		return appErr.NewAppError(err.Error(), "password-reset-error")
+	}
+
+	now := time.Now()
+	reset := &PasswordReset{
+		UUID:      uuid.New(),
+		UserUUID:  u.UUID,
+		Hash:      hashMailToken(token),
+		ExpiresAt: now.Add(PasswordResetTTL),
+		CreatedAt: now,
+	}
+
+	err = h.transactional(ctx, func(ctx context.Context) error {
+		if err := h.passwordResetRepository.Add(ctx, reset); err != nil {
+			return err
+		}
+
+		return h.mailer.Send(ctx, u.Email, "password-reset", ClientInfoFromContext(ctx).Locale, map[string]interface{}{
+			"Name":             u.Name,
+			"Link":             fmt.Sprintf("%s/password/reset?token=%s", h.frontendUrl, token),
+			"ExpiresInMinutes": int(PasswordResetTTL.Minutes()),
+		})
+	})
+	if err != nil {
+		return appErr.NewAppError(err.Error(), "password-reset-error")
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a mailed token and signs out every session of the user
+func (h *AuthService) ResetPassword(ctx context.Context, r *ResetPasswordRequest) error {
+	reset, err := h.passwordResetRepository.FindByHash(ctx, hashMailToken(r.Token))
+	if err != nil {
+		if appErr.IsNotFound(err) {
+			return appErr.NewIncorrectInputError("Password reset token is invalid", "password-reset-token-invalid")
+		}
+
+		return err
+	}
+
+	now := time.Now()
+	if !reset.IsUsable(now) {
+		return appErr.NewIncorrectInputError("Password reset token is invalid", "password-reset-token-invalid")
+	}
+
+	u, err := h.userRepository.FindById(ctx, reset.UserUUID)
+	if err != nil {
+		return err
+	}
+
+	if err := h.validatePassword(r.Password, u); err != nil {
+		return err
+	}
+
+	hash, err := h.hashPassword(ctx, r.Password, "password-saving-error")
+	if err != nil {
+		return err
+	}
+
+	err = h.transactional(ctx, func(ctx context.Context) error {
+		// marked first, of two concurrent requests with the same token the second one finds it used
+		if err := h.passwordResetRepository.MarkUsed(ctx, reset.UUID, now); err != nil {
+			if appErr.IsNotFound(err) {
+				return appErr.NewIncorrectInputError("Password reset token is invalid", "password-reset-token-invalid")
+			}
+
+			return err
+		}
+
+		if err := h.userRepository.UpdateHash(ctx, u.UUID, hash); err != nil {
+			return err
+		}
+
+		if err := h.refreshRepository.DeleteForUserUUID(ctx, u.UUID); err != nil {
+			return err
+		}
+
+		return h.emitSessionRevoked(ctx, u.UUID, uuid.Nil, event.ReasonPasswordReset)
+	})
+	if err != nil {
+		return err
+	}
+
+	h.recordEvent(ctx, audit.PASSWORD_RESET, u.UUID, u.Email)
+
+	return nil
+}
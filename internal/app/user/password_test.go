@@ -0,0 +1,263 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	appJwt "github.com/ibgl/microservice-users/internal/app/jwt"
+	"github.com/ibgl/microservice-users/internal/app/password"
+	"github.com/ibgl/microservice-users/internal/app/role"
+)
+
+// plainHasher keeps the passwords readable and counts the verifications
+type plainHasher struct {
+	verified int
+}
+
+func (h *plainHasher) Hash(ctx context.Context, plain string) (string, error) {
+	return "hashed:" + plain, nil
+}
+
+func (h *plainHasher) Verify(ctx context.Context, plain, hash string) (bool, error) {
+	h.verified++
+	return hash == "hashed:"+plain, nil
+}
+
+func (h *plainHasher) NeedsRehash(hash string) bool {
+	return !strings.HasPrefix(hash, "hashed:")
+}
+
+type hashRepository struct {
+	UserRepository
+	user *User
+}
+
+func (r *hashRepository) FindById(ctx context.Context, userUUID uuid.UUID) (*User, error) {
+	if r.user.UUID != userUUID {
+		return &User{}, appErr.NewNotFoundError("User not found", "user-not-found")
+	}
+
+	return r.user, nil
+}
+
+func (r *hashRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
+	if r.user.Email != email {
+		return &User{}, appErr.NewNotFoundError("User not found", "user-not-found")
+	}
+
+	return r.user, nil
+}
+
+func (r *hashRepository) UpdateHash(ctx context.Context, userUUID uuid.UUID, hash string) error {
+	r.user.Hash = hash
+	return nil
+}
+
+// memoryResets marks a reset used once like the conditional update of the pgsql repository
+type memoryResets struct {
+	PasswordResetRepository
+	resets map[string]*PasswordReset
+}
+
+func (r *memoryResets) FindByHash(ctx context.Context, hash string) (*PasswordReset, error) {
+	reset, ok := r.resets[hash]
+	if !ok {
+		return &PasswordReset{}, appErr.NewNotFoundError("Password reset not found", "password-reset-token-invalid")
+	}
+
+	found := *reset
+	return &found, nil
+}
+
+func (r *memoryResets) MarkUsed(ctx context.Context, resetUUID uuid.UUID, at time.Time) error {
+	for _, reset := range r.resets {
+		if reset.UUID == resetUUID && reset.UsedAt == nil {
+			reset.UsedAt = &at
+			return nil
+		}
+	}
+
+	return appErr.NewNotFoundError("Password reset not found", "password-reset-token-invalid")
+}
+
+// staleResets finds the reset as it was read before a concurrent request used it
+type staleResets struct {
+	*memoryResets
+	reset *PasswordReset
+}
+
+func (r staleResets) FindByHash(ctx context.Context, hash string) (*PasswordReset, error) {
+	return r.reset, nil
+}
+
+func (r *memorySessions) DeleteOthersForUserUUID(ctx context.Context, userUUID, keepUUID uuid.UUID) error {
+	for refreshUUID, refresh := range r.tokens {
+		if refresh.Claims.UserId == userUUID && refreshUUID != keepUUID {
+			delete(r.tokens, refreshUUID)
+		}
+	}
+
+	return nil
+}
+
+func assertSlug(t *testing.T, err error, slug string) {
+	t.Helper()
+
+	if slug == "" {
+		if err != nil {
+			t.Fatalf("Want no error, got '%v'", err)
+		}
+		return
+	}
+
+	appError, ok := err.(appErr.AppError)
+	if !ok || appError.Slug() != slug {
+		t.Fatalf("Want slug '%s', got '%v'", slug, err)
+	}
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	const newPassword = "Xk9#mQ2$vL"
+	other := uuid.New()
+
+	tt := []struct {
+		name            string
+		currentPassword string
+		refresh         func(h *AuthService, u *User) string
+		slug            string
+		wantSessions    int
+	}{
+		{
+			name:            "Other sessions are signed out",
+			currentPassword: "old",
+			refresh: func(h *AuthService, u *User) string {
+				return addSession(t, h, u.UUID).Token
+			},
+			wantSessions: 1,
+		},
+		{
+			name:            "Every session is signed out without a refresh token",
+			currentPassword: "old",
+			refresh:         func(h *AuthService, u *User) string { return "" },
+		},
+		{
+			name:            "Every session is signed out with a refresh token of another user",
+			currentPassword: "old",
+			refresh: func(h *AuthService, u *User) string {
+				return addSession(t, h, other).Token
+			},
+		},
+		{
+			name:            "Every session is signed out with an invalid refresh token",
+			currentPassword: "old",
+			refresh:         func(h *AuthService, u *User) string { return "garbage" },
+		},
+		{
+			name:            "Wrong current password keeps the sessions",
+			currentPassword: "wrong",
+			refresh:         func(h *AuthService, u *User) string { return "" },
+			slug:            "field-current-password-invalid",
+			wantSessions:    2,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			u := NewUser(uuid.New(), "user@example.com", "User", "hashed:old", role.USER, DefaultUserSettings(), time.Now(), time.Now())
+			sessions := newMemorySessions()
+			events := &eventsRepository{}
+			h := &AuthService{
+				userRepository:    &hashRepository{user: u},
+				jwtService:        newTestJwtService(),
+				refreshRepository: sessions,
+				passwordHasher:    &plainHasher{},
+				passwordPolicy:    password.DefaultPolicy,
+				eventRepository:   events,
+			}
+
+			addSession(t, h, u.UUID)
+			addSession(t, h, u.UUID)
+
+			err := h.ChangePassword(context.Background(), &ChangePasswordRequest{
+				UserUUID:        u.UUID,
+				CurrentPassword: tc.currentPassword,
+				NewPassword:     newPassword,
+				RefreshToken:    tc.refresh(h, u),
+			})
+			assertSlug(t, err, tc.slug)
+
+			count, _ := sessions.CountForUser(context.Background(), u.UUID)
+			if count != tc.wantSessions {
+				t.Errorf("Want %d sessions, got %d", tc.wantSessions, count)
+			}
+
+			if tc.slug != "" {
+				if u.Hash != "hashed:old" || len(events.events) != 0 {
+					t.Errorf("Want the password and the sessions unchanged, got hash '%s' and %+v", u.Hash, events.events)
+				}
+				return
+			}
+
+			if u.Hash != "hashed:"+newPassword {
+				t.Errorf("Want the new hash, got '%s'", u.Hash)
+			}
+
+			if len(events.events) != 1 || events.events[0].Type != event.SESSION_REVOKED ||
+				!strings.Contains(string(events.events[0].Data), event.ReasonPasswordChanged) {
+				t.Errorf("Want one session.revoked event, got %+v", events.events)
+			}
+		})
+	}
+}
+
+func TestAuthService_ResetPasswordOnce(t *testing.T) {
+	const token = "reset-token"
+	u := NewUser(uuid.New(), "user@example.com", "User", "hashed:old", role.USER, DefaultUserSettings(), time.Now(), time.Now())
+	events := &eventsRepository{}
+	resets := &memoryResets{resets: map[string]*PasswordReset{
+		hashMailToken(token): {UUID: uuid.New(), UserUUID: u.UUID, ExpiresAt: time.Now().Add(PasswordResetTTL)},
+	}}
+	h := &AuthService{
+		userRepository:          &hashRepository{user: u},
+		refreshRepository:       newMemorySessions(),
+		passwordResetRepository: resets,
+		passwordHasher:          &plainHasher{},
+		passwordPolicy:          password.DefaultPolicy,
+		eventRepository:         events,
+	}
+
+	// a concurrent request found the reset before the first one marked it used
+	stale, _ := resets.FindByHash(context.Background(), hashMailToken(token))
+
+	if err := h.ResetPassword(context.Background(), &ResetPasswordRequest{Token: token, Password: "Xk9#mQ2$vL"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.passwordResetRepository = staleResets{memoryResets: resets, reset: stale}
+	err := h.ResetPassword(context.Background(), &ResetPasswordRequest{Token: token, Password: "Zp4!wR7&nB"})
+	assertSlug(t, err, "password-reset-token-invalid")
+
+	if u.Hash != "hashed:Xk9#mQ2$vL" || len(events.events) != 1 {
+		t.Errorf("Want the first password and one session.revoked event, got hash '%s' and %+v", u.Hash, events.events)
+	}
+}
+
+func addSession(t *testing.T, h *AuthService, userUUID uuid.UUID) *appJwt.RefreshJWT {
+	t.Helper()
+
+	refresh, err := h.jwtService.CreateRefresh(*appJwt.NewRefreshClaims(userUUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.refreshRepository.Add(context.Background(), refresh, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	return refresh
+}
@@ -0,0 +1,171 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+)
+
+const (
+	PersonalAccessTokenPrefix     = "pat_"
+	DefaultPersonalAccessTokenTTL = 30 * 24 * time.Hour
+	MaxPersonalAccessTokenTTL     = 365 * 24 * time.Hour
+
+	// last used timestamp is written at most once per interval to spare the database on busy scripts
+	personalAccessTokenTouchInterval = time.Minute
+)
+
+type PersonalAccessToken struct {
+	UUID       uuid.UUID
+	UserUUID   uuid.UUID
+	Name       string
+	Hash       string
+	Scopes     []scope.Scope
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (t *PersonalAccessToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	UserUUID  uuid.UUID
+	Name      string
+	Scopes    []string
+	ExpiresIn time.Duration
+}
+
+// CreatedPersonalAccessToken carries the plain token value, it is never available again after creation
+type CreatedPersonalAccessToken struct {
+	Token *PersonalAccessToken
+	Value string
+}
+
+type PersonalAccessTokenRepository interface {
+	Add(ctx context.Context, token *PersonalAccessToken) error
+	FindByHash(ctx context.Context, hash string) (*PersonalAccessToken, error)
+	ListForUser(ctx context.Context, userUUID uuid.UUID) ([]*PersonalAccessToken, error)
+	Delete(ctx context.Context, uuid, userUUID uuid.UUID) error
+	TouchLastUsed(ctx context.Context, uuid uuid.UUID, at time.Time) error
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+func HashPersonalAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func GeneratePersonalAccessToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (h *AuthService) CreatePersonalAccessToken(ctx context.Context, r *CreatePersonalAccessTokenRequest) (*CreatedPersonalAccessToken, error) {
+	scopes, err := scope.FromStrings(r.Scopes)
+	if err != nil || len(scopes) == 0 {
+		return &CreatedPersonalAccessToken{}, appErr.NewIncorrectInputError("Invalid scopes", "field-scopes-invalid")
+	}
+
+	for _, s := range scopes {
+		if !scope.IsDelegable(s) {
+			return &CreatedPersonalAccessToken{}, appErr.NewIncorrectInputError("Scope can not be granted to personal tokens", "field-scopes-invalid")
+		}
+	}
+
+	ttl := r.ExpiresIn
+	if ttl == 0 {
+		ttl = DefaultPersonalAccessTokenTTL
+	}
+
+	if ttl < 0 || ttl > MaxPersonalAccessTokenTTL {
+		return &CreatedPersonalAccessToken{}, appErr.NewIncorrectInputError("Invalid token lifetime", "field-expires-in-invalid")
+	}
+
+	value, err := GeneratePersonalAccessToken()
+	if err != nil {
+		return &CreatedPersonalAccessToken{}, appErr.NewAppError(err.Error(), "token-saving-error")
+	}
+
+	now := time.Now()
+	token := &PersonalAccessToken{
+		UUID:      uuid.New(),
+		UserUUID:  r.UserUUID,
+		Name:      r.Name,
+		Hash:      HashPersonalAccessToken(value),
+		Scopes:    scopes,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	err = h.personalAccessTokenRepository.Add(ctx, token)
+	if err != nil {
+		return &CreatedPersonalAccessToken{}, appErr.NewAppError(err.Error(), "token-saving-error")
+	}
+
+	return &CreatedPersonalAccessToken{
+		Token: token,
+		Value: value,
+	}, nil
+}
+
+func (h *AuthService) ListPersonalAccessTokens(ctx context.Context, userUUID uuid.UUID) ([]*PersonalAccessToken, error) {
+	return h.personalAccessTokenRepository.ListForUser(ctx, userUUID)
+}
+
+func (h *AuthService) RevokePersonalAccessToken(ctx context.Context, userUUID, tokenUUID uuid.UUID) error {
+	if err := h.personalAccessTokenRepository.Delete(ctx, tokenUUID, userUUID); err != nil {
+		return err
+	}
+
+	h.recordEvent(ctx, audit.TOKEN_REVOKED, userUUID, "")
+
+	return nil
+}
+
+func (h *AuthService) validatePersonalAccessToken(ctx context.Context, value string) (Token, error) {
+	pat, err := h.personalAccessTokenRepository.FindByHash(ctx, HashPersonalAccessToken(value))
+	if err != nil {
+		if appErr.IsNotFound(err) {
+			return Token{}, appErr.NewAuthorizationError("Personal access token not found", "invalid-token")
+		}
+
+		return Token{}, err
+	}
+
+	now := time.Now()
+	if pat.IsExpired(now) {
+		return Token{}, appErr.NewAuthorizationError("Personal access token expired", "invalid-token")
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) > personalAccessTokenTouchInterval {
+		err = h.personalAccessTokenRepository.TouchLastUsed(ctx, pat.UUID, now)
+		if err != nil {
+			return Token{}, err
+		}
+	}
+
+	return Token{
+		Value:           value,
+		UserId:          pat.UserUUID,
+		PersonalTokenId: pat.UUID,
+		Scopes:          pat.Scopes,
+	}, nil
+}
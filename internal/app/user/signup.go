@@ -0,0 +1,158 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/role"
+)
+
+const PendingSignUpTTL = 24 * time.Hour
+
+// PendingSignUp keeps a sign up with the already hashed password until the email is confirmed
+type PendingSignUp struct {
+	UUID      uuid.UUID
+	Email     string
+	Name      string
+	Hash      string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type PendingSignUpRepository interface {
+	Add(ctx context.Context, signUp *PendingSignUp) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*PendingSignUp, error)
+	Delete(ctx context.Context, uuid uuid.UUID) error
+}
+
+// RequestSignUp is the enumeration safe sign up, it answers the same for new and registered emails and
+// the outcome is only told by mail: a confirmation link for new emails, a sign in hint for registered ones
+func (h *AuthService) RequestSignUp(ctx context.Context, r *SignUpRequest) error {
+	if err := h.verifyCaptcha(ctx, r.CaptchaToken); err != nil {
+		return err
+	}
+
+	if err := h.checkEmailDomain(r.Email); err != nil {
+		return err
+	}
+
+	if err := h.passwordPolicy.Validate(r.Password, r.Email, r.Name); err != nil {
+		return err
+	}
+
+	// hashed before the lookup so that registered emails take as long as new ones
+	hash, err := h.hashPassword(ctx, r.Password, "create-user-error")
+	if err != nil {
+		return err
+	}
+
+	existing, err := h.userRepository.FindByEmail(ctx, r.Email)
+	if err == nil {
+		err := h.mailer.Send(ctx, existing.Email, "account-exists", ClientInfoFromContext(ctx).Locale, map[string]interface{}{
+			"Name":       existing.Name,
+			"SignInLink": h.frontendUrl + "/signIn",
+			"ResetLink":  h.frontendUrl + "/password/forgot",
+		})
+		if err != nil {
+			return appErr.NewAppError(err.Error(), "sign-up-error")
+		}
+
+		return nil
+	}
+
+	if !appErr.IsNotFound(err) {
+		return err
+	}
+
+	token, err := generateMailToken()
+	if err != nil {
+		return appErr.NewAppError(err.Error(), "sign-up-error")
+	}
+
+	now := time.Now()
+	signUp := &PendingSignUp{
+		UUID:      uuid.New(),
+		Email:     r.Email,
+		Name:      r.Name,
+		Hash:      hash,
+		TokenHash: hashMailToken(token),
+		ExpiresAt: now.Add(PendingSignUpTTL),
+		CreatedAt: now,
+	}
+
+	err = h.transactional(ctx, func(ctx context.Context) error {
+		if err := h.pendingSignUpRepository.Add(ctx, signUp); err != nil {
+			return err
+		}
+
+		return h.mailer.Send(ctx, signUp.Email, "sign-up-confirm", ClientInfoFromContext(ctx).Locale, map[string]interface{}{
+			"Name":           signUp.Name,
+			"Link":           fmt.Sprintf("%s/signUp/confirm?token=%s", h.frontendUrl, token),
+			"ExpiresInHours": int(PendingSignUpTTL.Hours()),
+		})
+	})
+	if err != nil {
+		return appErr.NewAppError(err.Error(), "sign-up-error")
+	}
+
+	return nil
+}
+
+// ConfirmSignUp creates the user of a mailed confirmation token and signs them in
+func (h *AuthService) ConfirmSignUp(ctx context.Context, token string) (*LoginResponse, error) {
+	signUp, err := h.pendingSignUpRepository.FindByTokenHash(ctx, hashMailToken(token))
+	if err != nil {
+		if appErr.IsNotFound(err) {
+			return &LoginResponse{}, appErr.NewIncorrectInputError("Sign up token is invalid", "sign-up-token-invalid")
+		}
+
+		return &LoginResponse{}, err
+	}
+
+	if !time.Now().Before(signUp.ExpiresAt) {
+		return &LoginResponse{}, appErr.NewIncorrectInputError("Sign up token is invalid", "sign-up-token-invalid")
+	}
+
+	// another pending sign up of the email may have been confirmed first
+	_, err = h.userRepository.FindByEmail(ctx, signUp.Email)
+	if err == nil {
+		if err := h.pendingSignUpRepository.Delete(ctx, signUp.UUID); err != nil {
+			return &LoginResponse{}, err
+		}
+
+		return &LoginResponse{}, appErr.NewIncorrectInputError("Sign up token is invalid", "sign-up-token-invalid")
+	}
+
+	if !appErr.IsNotFound(err) {
+		return &LoginResponse{}, err
+	}
+
+	user := NewUser(
+		uuid.New(),
+		signUp.Email,
+		signUp.Name,
+		signUp.Hash,
+		role.USER,
+		DefaultUserSettings(),
+		time.Now(),
+		time.Now(),
+	)
+
+	err = h.addUser(ctx, user)
+	if err != nil {
+		return &LoginResponse{}, appErr.NewAppError(err.Error(), "user-saving-error")
+	}
+
+	if err := h.pendingSignUpRepository.Delete(ctx, signUp.UUID); err != nil {
+		return &LoginResponse{}, err
+	}
+
+	h.recordEvent(ctx, audit.SIGN_UP, user.UUID, user.Email)
+
+	return h.createTokens(ctx, user, false)
+}
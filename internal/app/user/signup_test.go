@@ -0,0 +1,76 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/password"
+	"github.com/ibgl/microservice-users/internal/app/role"
+)
+
+type sentMail struct {
+	recipient string
+	template  string
+}
+
+type memoryMailer struct {
+	sent []sentMail
+}
+
+func (m *memoryMailer) Send(ctx context.Context, recipient, template, locale string, data interface{}) error {
+	m.sent = append(m.sent, sentMail{recipient: recipient, template: template})
+	return nil
+}
+
+type pendingSignUps struct {
+	PendingSignUpRepository
+	added []*PendingSignUp
+}
+
+func (r *pendingSignUps) Add(ctx context.Context, signUp *PendingSignUp) error {
+	r.added = append(r.added, signUp)
+	return nil
+}
+
+func TestAuthService_RequestSignUp(t *testing.T) {
+	u := NewUser(uuid.New(), "user@example.com", "User", "hashed:secret", role.USER, DefaultUserSettings(), time.Now(), time.Now())
+
+	tt := []struct {
+		name        string
+		email       string
+		wantMail    string
+		wantPending int
+	}{
+		{name: "New email gets a confirmation link", email: "new@example.com", wantMail: "sign-up-confirm", wantPending: 1},
+		{name: "Registered email gets the account exists mail", email: u.Email, wantMail: "account-exists"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mailer := &memoryMailer{}
+			signUps := &pendingSignUps{}
+			h := &AuthService{
+				userRepository:          &hashRepository{user: u},
+				pendingSignUpRepository: signUps,
+				passwordHasher:          &plainHasher{},
+				passwordPolicy:          password.DefaultPolicy,
+				mailer:                  mailer,
+			}
+
+			err := h.RequestSignUp(context.Background(), &SignUpRequest{Email: tc.email, Password: "Xk9#mQ2$vL", Name: "Someone"})
+			if err != nil {
+				t.Fatalf("Want no error, got '%v'", err)
+			}
+
+			if len(mailer.sent) != 1 || mailer.sent[0].template != tc.wantMail || mailer.sent[0].recipient != tc.email {
+				t.Errorf("Want one '%s' mail to '%s', got %+v", tc.wantMail, tc.email, mailer.sent)
+			}
+
+			if len(signUps.added) != tc.wantPending {
+				t.Errorf("Want %d pending sign ups, got %d", tc.wantPending, len(signUps.added))
+			}
+		})
+	}
+}
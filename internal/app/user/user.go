@@ -6,19 +6,22 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
 	"github.com/ibgl/microservice-users/internal/app/currency"
 	"github.com/ibgl/microservice-users/internal/app/day"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ibgl/microservice-users/internal/app/role"
 )
 
 type User struct {
-	UUID      uuid.UUID
-	Email     string
-	Name      string
-	Hash      string
-	Settings  UserSettings
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	UUID                uuid.UUID
+	Email               string
+	Name                string
+	Hash                string
+	Role                role.Role
+	Settings            UserSettings
+	ActiveHouseholdUUID uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
 }
 
 type UserSettings struct {
@@ -47,6 +50,7 @@ func NewUser(
 	Email string,
 	Name string,
 	Hash string,
+	Role role.Role,
 	Settings UserSettings,
 	CreatedAt time.Time,
 	UpdatedAt time.Time,
@@ -56,42 +60,73 @@ func NewUser(
 		Email:     Email,
 		Name:      Name,
 		Hash:      Hash,
+		Role:      Role,
 		Settings:  Settings,
 		CreatedAt: CreatedAt,
 		UpdatedAt: UpdatedAt,
 	}
 }
 
+func (u *User) IsAdmin() bool {
+	return u.Role == role.ADMIN
+}
+
 const charset = "abcdefghijklmnopqrstuvwxyz" +
 	"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789" + "$#@&^*()"
 
+// MaxBatchUsers is the most users GetUsers looks up at once
+const MaxBatchUsers = 100
+
 type UserAuthManager interface {
 	SignIn(ctx context.Context, r *SignInRequest) (*LoginResponse, error)
 	SignUp(ctx context.Context, r *SignUpRequest) (*LoginResponse, error)
+	RequestSignUp(ctx context.Context, r *SignUpRequest) error
+	ConfirmSignUp(ctx context.Context, token string) (*LoginResponse, error)
 	GetUser(ctx context.Context, userUUID uuid.UUID) (*User, error)
+	GetUsers(ctx context.Context, userUUIDs []uuid.UUID) ([]*User, error)
 	SaveRefresh(ctx context.Context, userUUID uuid.UUID) (*User, error)
 	Refresh(ctx context.Context, tokenString string) (*LoginResponse, error)
+	SignOut(ctx context.Context, tokenString string) error
+	ReportSignIn(ctx context.Context, token string) error
 	UpdateSettings(ctx context.Context, request *UpdateSettingsRequest) (*User, error)
 	ValidateToken(ctx context.Context, token string) (Token, error)
 	GoogleSignIn(ctx context.Context, token string) (*LoginResponse, error)
+	Impersonate(ctx context.Context, r *ImpersonateRequest) (*ImpersonationResponse, error)
+	CreatePersonalAccessToken(ctx context.Context, r *CreatePersonalAccessTokenRequest) (*CreatedPersonalAccessToken, error)
+	ListPersonalAccessTokens(ctx context.Context, userUUID uuid.UUID) ([]*PersonalAccessToken, error)
+	RevokePersonalAccessToken(ctx context.Context, userUUID, tokenUUID uuid.UUID) error
+	CreateClient(ctx context.Context, r *CreateClientRequest) (*CreatedClient, error)
+	ListClients(ctx context.Context, actorUUID uuid.UUID) ([]*Client, error)
+	DeleteClient(ctx context.Context, actorUUID uuid.UUID, clientId string) error
+	ClientCredentials(ctx context.Context, r *ClientCredentialsRequest) (*ClientTokenResponse, error)
+	ChangePassword(ctx context.Context, r *ChangePasswordRequest) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, r *ResetPasswordRequest) error
+	ListActivity(ctx context.Context, userUUID uuid.UUID, before time.Time, limit int) ([]*audit.Event, error)
+	ListAuthEvents(ctx context.Context, actorUUID uuid.UUID, q audit.Query) ([]*audit.Event, error)
 }
 
 type UserRepository interface {
 	FindById(ctx context.Context, uuid uuid.UUID) (*User, error)
+	FindByIds(ctx context.Context, uuids []uuid.UUID) ([]*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	Add(ctx context.Context, user *User) error
 	UpdateSettings(ctx context.Context, userUUID uuid.UUID, settings *UserSettings) (*User, error)
+	SetActiveHousehold(ctx context.Context, userUUID, householdUUID uuid.UUID) error
+	UpdateHash(ctx context.Context, userUUID uuid.UUID, hash string) error
 	Transactional(ctx context.Context, cb func(r UserRepository) error) error
 }
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 12)
-	return string(bytes), err
+// PasswordHasher hashes passwords, hashes made with outdated parameters verify but need a rehash
+type PasswordHasher interface {
+	Hash(ctx context.Context, password string) (string, error)
+	Verify(ctx context.Context, password, hash string) (bool, error)
+	NeedsRehash(hash string) bool
 }
 
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+// EmailDomainFilter decides which email domains may be used for new accounts
+type EmailDomainFilter interface {
+	Allowed(email string) bool
 }
 
 func GeneratePassword() string {
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/outbox"
+)
+
+const (
+	// HeaderId is the event UUID, receivers dedupe by it since redeliveries and retries repeat it
+	HeaderId        = "Webhook-Id"
+	HeaderEvent     = "Webhook-Event"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+
+	maxResponseBodyLength = 1024
+)
+
+// Sign is the "sha256=<hex>" HMAC-SHA256 of "<timestamp>.<body>" with the subscription secret, receivers compute
+// it the same way and reject old timestamps against replays
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type DeliveryPolicy struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Lease       time.Duration
+	Timeout     time.Duration
+}
+
+var DefaultDeliveryPolicy = DeliveryPolicy{
+	Interval:    2 * time.Second,
+	BatchSize:   20,
+	MaxAttempts: 10,
+	BaseDelay:   30 * time.Second,
+	MaxDelay:    6 * time.Hour,
+	Lease:       5 * time.Minute,
+	Timeout:     10 * time.Second,
+}
+
+// Backoff is the delay after the given number of failed attempts, doubling from BaseDelay up to MaxDelay
+func (p DeliveryPolicy) Backoff(attempts int) time.Duration {
+	return outbox.Backoff(p.BaseDelay, p.MaxDelay, attempts)
+}
+
+// Dispatcher posts the pending deliveries to the subscriptions, answers other than 2xx are failures and
+// redirects are not followed
+type Dispatcher struct {
+	deliveryRepository DeliveryRepository
+	repository         Repository
+	client             *http.Client
+	policy             DeliveryPolicy
+}
+
+func NewDispatcher(dr DeliveryRepository, r Repository, policy DeliveryPolicy) *Dispatcher {
+	return &Dispatcher{
+		deliveryRepository: dr,
+		repository:         r,
+		client: &http.Client{
+			Timeout: policy.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		policy: policy,
+	}
+}
+
+// Run dispatches every interval until the context is done, a full batch is followed by the next one right away
+func (d *Dispatcher) Run(ctx context.Context, onError func(error)) {
+	outbox.Poll(ctx, d.policy.Interval, d.policy.BatchSize, func(ctx context.Context) (int, error) {
+		return d.Dispatch(ctx, onError)
+	}, onError)
+}
+
+// Dispatch sends one batch and returns the number of claimed deliveries. Failed attempts are passed to onError
+// and retried with backoff, the returned error is a storage failure.
+func (d *Dispatcher) Dispatch(ctx context.Context, onError func(error)) (int, error) {
+	deliveries, err := d.deliveryRepository.Claim(ctx, time.Now(), d.policy.Lease, d.policy.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions := map[uuid.UUID]*Subscription{}
+	for _, delivery := range deliveries {
+		s, ok := subscriptions[delivery.SubscriptionUUID]
+		if !ok {
+			s, err = d.repository.FindById(ctx, delivery.SubscriptionUUID)
+			if appErr.IsNotFound(err) {
+				// deleted meanwhile, its deliveries went with it
+				continue
+			}
+
+			if err != nil {
+				return len(deliveries), err
+			}
+
+			subscriptions[delivery.SubscriptionUUID] = s
+		}
+
+		if !s.Active {
+			delivery.Status = FAILED
+			delivery.LastError = "subscription is disabled"
+		} else {
+			d.attempt(ctx, s, delivery, onError)
+		}
+
+		if err := d.deliveryRepository.Save(ctx, delivery); err != nil {
+			return len(deliveries), err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, s *Subscription, delivery *Delivery, onError func(error)) {
+	code, body, err := d.post(ctx, s, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+
+	if err == nil {
+		delivery.Status = DELIVERED
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	onError(fmt.Errorf("webhook %s to %s: %w", delivery.UUID, s.URL, err))
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.policy.MaxAttempts {
+		delivery.Status = FAILED
+	} else {
+		delivery.NextAttemptAt = now.Add(d.policy.Backoff(delivery.Attempts))
+	}
+}
+
+// post returns the response code and the start of the response body, the code is 0 without a response
+func (d *Dispatcher) post(ctx context.Context, s *Subscription, delivery *Delivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", event.CloudEventsContentType)
+	req.Header.Set("User-Agent", "users-webhooks/1.0")
+	req.Header.Set(HeaderId, delivery.EventUUID.String())
+	req.Header.Set(HeaderEvent, delivery.EventType.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(s.Secret, timestamp, delivery.Payload))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBodyLength))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, string(body), fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, string(body), nil
+}
@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/user"
+)
+
+const SecretPrefix = "whsec_"
+
+type Service struct {
+	repository         Repository
+	deliveryRepository DeliveryRepository
+	userRepository     user.UserRepository
+	source             string
+	allowHTTP          bool
+}
+
+// NewService manages subscriptions and, as an event.Publisher, queues the deliveries of relayed events.
+// allowHTTP admits plain http endpoints, which is only meant for local development.
+func NewService(r Repository, dr DeliveryRepository, ur user.UserRepository, source string, allowHTTP bool) *Service {
+	return &Service{
+		repository:         r,
+		deliveryRepository: dr,
+		userRepository:     ur,
+		source:             source,
+		allowHTTP:          allowHTTP,
+	}
+}
+
+func (h *Service) requireAdmin(ctx context.Context, actorUUID uuid.UUID) error {
+	actor, err := h.userRepository.FindById(ctx, actorUUID)
+	if err != nil {
+		return appErr.NewAuthorizationError(err.Error(), "invalid-token")
+	}
+
+	if !actor.IsAdmin() {
+		return appErr.NewForbiddenError("Admin role required", "admin-required")
+	}
+
+	return nil
+}
+
+func (h *Service) validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || !(u.Scheme == "https" || (h.allowHTTP && u.Scheme == "http")) {
+		return appErr.NewIncorrectInputError("Invalid webhook url", "field-url-invalid")
+	}
+
+	return nil
+}
+
+func parseEvents(values []string) ([]event.Type, error) {
+	types := make([]event.Type, 0, len(values))
+	for _, value := range values {
+		t, err := event.FromString(value)
+		if err != nil {
+			return nil, appErr.NewIncorrectInputError("Invalid event type", "field-events-invalid")
+		}
+
+		types = append(types, t)
+	}
+
+	return types, nil
+}
+
+func (h *Service) CreateSubscription(ctx context.Context, r *CreateSubscriptionRequest) (*Subscription, error) {
+	if err := h.requireAdmin(ctx, r.ActorUUID); err != nil {
+		return &Subscription{}, err
+	}
+
+	if err := h.validateURL(r.URL); err != nil {
+		return &Subscription{}, err
+	}
+
+	events, err := parseEvents(r.Events)
+	if err != nil {
+		return &Subscription{}, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return &Subscription{}, appErr.NewAppError(err.Error(), "webhook-saving-error")
+	}
+
+	now := time.Now()
+	s := &Subscription{
+		UUID:      uuid.New(),
+		URL:       r.URL,
+		Secret:    SecretPrefix + base64.RawURLEncoding.EncodeToString(secret),
+		Events:    events,
+		Active:    true,
+		CreatedBy: r.ActorUUID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := h.repository.Add(ctx, s); err != nil {
+		return &Subscription{}, appErr.NewAppError(err.Error(), "webhook-saving-error")
+	}
+
+	return s, nil
+}
+
+func (h *Service) ListSubscriptions(ctx context.Context, actorUUID uuid.UUID) ([]*Subscription, error) {
+	if err := h.requireAdmin(ctx, actorUUID); err != nil {
+		return []*Subscription{}, err
+	}
+
+	return h.repository.List(ctx)
+}
+
+func (h *Service) UpdateSubscription(ctx context.Context, r *UpdateSubscriptionRequest) (*Subscription, error) {
+	if err := h.requireAdmin(ctx, r.ActorUUID); err != nil {
+		return &Subscription{}, err
+	}
+
+	if err := h.validateURL(r.URL); err != nil {
+		return &Subscription{}, err
+	}
+
+	events, err := parseEvents(r.Events)
+	if err != nil {
+		return &Subscription{}, err
+	}
+
+	s, err := h.repository.FindById(ctx, r.SubscriptionUUID)
+	if err != nil {
+		return &Subscription{}, err
+	}
+
+	s.URL = r.URL
+	s.Events = events
+	s.Active = r.Active
+	s.UpdatedAt = time.Now()
+
+	if err := h.repository.Update(ctx, s); err != nil {
+		return &Subscription{}, appErr.NewAppError(err.Error(), "webhook-saving-error")
+	}
+
+	return s, nil
+}
+
+// DeleteSubscription removes the subscription with its delivery log
+func (h *Service) DeleteSubscription(ctx context.Context, actorUUID, subscriptionUUID uuid.UUID) error {
+	if err := h.requireAdmin(ctx, actorUUID); err != nil {
+		return err
+	}
+
+	return h.repository.Delete(ctx, subscriptionUUID)
+}
+
+// ListDeliveries returns the delivery log of the subscription, newest first
+func (h *Service) ListDeliveries(ctx context.Context, actorUUID, subscriptionUUID uuid.UUID, limit int) ([]*Delivery, error) {
+	if err := h.requireAdmin(ctx, actorUUID); err != nil {
+		return []*Delivery{}, err
+	}
+
+	if _, err := h.repository.FindById(ctx, subscriptionUUID); err != nil {
+		return []*Delivery{}, err
+	}
+
+	if limit <= 0 {
+		limit = DefaultDeliveriesLimit
+	}
+
+	if limit > MaxDeliveriesLimit {
+		limit = MaxDeliveriesLimit
+	}
+
+	return h.deliveryRepository.ListForSubscription(ctx, subscriptionUUID, limit)
+}
+
+// Redeliver queues the payload of a logged delivery again, whatever its outcome was
+func (h *Service) Redeliver(ctx context.Context, actorUUID, subscriptionUUID, deliveryUUID uuid.UUID) (*Delivery, error) {
+	if err := h.requireAdmin(ctx, actorUUID); err != nil {
+		return &Delivery{}, err
+	}
+
+	d, err := h.deliveryRepository.FindById(ctx, deliveryUUID)
+	if err != nil {
+		return &Delivery{}, err
+	}
+
+	if d.SubscriptionUUID != subscriptionUUID {
+		return &Delivery{}, appErr.NewNotFoundError("Delivery not found", "webhook-delivery-not-found")
+	}
+
+	redelivery := newDelivery(subscriptionUUID, d.EventUUID, d.EventType, d.Payload)
+	redelivery.RedeliveryOf = d.UUID
+
+	if err := h.deliveryRepository.Add(ctx, redelivery); err != nil {
+		return &Delivery{}, appErr.NewAppError(err.Error(), "webhook-saving-error")
+	}
+
+	return redelivery, nil
+}
+
+// Publish queues a delivery of the event for every active subscription it matches
+func (h *Service) Publish(ctx context.Context, e *event.Event) error {
+	subscriptions, err := h.repository.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, s := range subscriptions {
+		if !s.Matches(e.Type) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(event.NewCloudEvent(e, h.source))
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := h.deliveryRepository.Add(ctx, newDelivery(s.UUID, e.UUID, e.Type, payload)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newDelivery(subscriptionUUID, eventUUID uuid.UUID, eventType event.Type, payload []byte) *Delivery {
+	now := time.Now()
+	return &Delivery{
+		UUID:             uuid.New(),
+		SubscriptionUUID: subscriptionUUID,
+		EventUUID:        eventUUID,
+		EventType:        eventType,
+		Payload:          payload,
+		Status:           PENDING,
+		NextAttemptAt:    now,
+		CreatedAt:        now,
+	}
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/event"
+)
+
+type DeliveryStatus struct {
+	v string
+}
+
+var (
+	UNKNOWN   = DeliveryStatus{"UNKNOWN"}
+	PENDING   = DeliveryStatus{"pending"}
+	DELIVERED = DeliveryStatus{"delivered"}
+	FAILED    = DeliveryStatus{"failed"}
+)
+
+func (s DeliveryStatus) String() string {
+	return s.v
+}
+
+func StatusFromString(value string) (DeliveryStatus, error) {
+	for _, s := range []DeliveryStatus{PENDING, DELIVERED, FAILED} {
+		if s.String() == value {
+			return s, nil
+		}
+	}
+
+	return UNKNOWN, errors.New("Invalid delivery status value")
+}
+
+// Subscription is a partner endpoint receiving the events it filters, an empty filter receives every event.
+// The secret signs the deliveries, so it is kept in plain text.
+type Subscription struct {
+	UUID      uuid.UUID
+	URL       string
+	Secret    string
+	Events    []event.Type
+	Active    bool
+	CreatedBy uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (s *Subscription) Matches(t event.Type) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+
+	for _, e := range s.Events {
+		if e == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delivery is one event sent to one subscription, it is the delivery log entry with the outcome of the last
+// attempt. Redeliveries are new deliveries pointing to the one they repeat.
+type Delivery struct {
+	UUID             uuid.UUID
+	SubscriptionUUID uuid.UUID
+	EventUUID        uuid.UUID
+	EventType        event.Type
+	Payload          []byte
+	Status           DeliveryStatus
+	Attempts         int
+	NextAttemptAt    time.Time
+	ResponseCode     int
+	ResponseBody     string
+	LastError        string
+	RedeliveryOf     uuid.UUID
+	CreatedAt        time.Time
+	DeliveredAt      *time.Time
+}
+
+type CreateSubscriptionRequest struct {
+	ActorUUID uuid.UUID
+	URL       string
+	Events    []string
+}
+
+type UpdateSubscriptionRequest struct {
+	ActorUUID        uuid.UUID
+	SubscriptionUUID uuid.UUID
+	URL              string
+	Events           []string
+	Active           bool
+}
+
+type WebhookManager interface {
+	CreateSubscription(ctx context.Context, r *CreateSubscriptionRequest) (*Subscription, error)
+	ListSubscriptions(ctx context.Context, actorUUID uuid.UUID) ([]*Subscription, error)
+	UpdateSubscription(ctx context.Context, r *UpdateSubscriptionRequest) (*Subscription, error)
+	DeleteSubscription(ctx context.Context, actorUUID, subscriptionUUID uuid.UUID) error
+	ListDeliveries(ctx context.Context, actorUUID, subscriptionUUID uuid.UUID, limit int) ([]*Delivery, error)
+	Redeliver(ctx context.Context, actorUUID, subscriptionUUID, deliveryUUID uuid.UUID) (*Delivery, error)
+}
+
+type Repository interface {
+	Add(ctx context.Context, s *Subscription) error
+	FindById(ctx context.Context, uuid uuid.UUID) (*Subscription, error)
+	List(ctx context.Context) ([]*Subscription, error)
+	ListActive(ctx context.Context) ([]*Subscription, error)
+	Update(ctx context.Context, s *Subscription) error
+	Delete(ctx context.Context, uuid uuid.UUID) error
+}
+
+type DeliveryRepository interface {
+	// Add skips a delivery of an event the subscription already has unless it is a redelivery, so that events
+	// published again by the relay are not delivered twice
+	Add(ctx context.Context, d *Delivery) error
+	FindById(ctx context.Context, uuid uuid.UUID) (*Delivery, error)
+	ListForSubscription(ctx context.Context, subscriptionUUID uuid.UUID, limit int) ([]*Delivery, error)
+	// Claim returns up to limit due pending deliveries and postpones them by the lease
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+	// Save stores the outcome of an attempt
+	Save(ctx context.Context, d *Delivery) error
+}
+
+const (
+	DefaultDeliveriesLimit = 50
+	MaxDeliveriesLimit     = 500
+)
@@ -0,0 +1,358 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	appErr "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/role"
+	"github.com/ibgl/microservice-users/internal/app/user"
+)
+
+type memoryRepository struct {
+	Repository
+	subscriptions []*Subscription
+}
+
+func (r *memoryRepository) Add(ctx context.Context, s *Subscription) error {
+	r.subscriptions = append(r.subscriptions, s)
+	return nil
+}
+
+func (r *memoryRepository) FindById(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	for _, s := range r.subscriptions {
+		if s.UUID == id {
+			return s, nil
+		}
+	}
+
+	return &Subscription{}, appErr.NewNotFoundError("Webhook not found", "webhook-not-found")
+}
+
+func (r *memoryRepository) ListActive(ctx context.Context) ([]*Subscription, error) {
+	active := []*Subscription{}
+	for _, s := range r.subscriptions {
+		if s.Active {
+			active = append(active, s)
+		}
+	}
+
+	return active, nil
+}
+
+type memoryDeliveryRepository struct {
+	mu         sync.Mutex
+	deliveries []*Delivery
+}
+
+func (r *memoryDeliveryRepository) Add(ctx context.Context, d *Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.deliveries {
+		if d.RedeliveryOf == uuid.Nil && existing.RedeliveryOf == uuid.Nil && existing.SubscriptionUUID == d.SubscriptionUUID && existing.EventUUID == d.EventUUID {
+			return nil
+		}
+	}
+
+	r.deliveries = append(r.deliveries, d)
+	return nil
+}
+
+func (r *memoryDeliveryRepository) FindById(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.deliveries {
+		if d.UUID == id {
+			copied := *d
+			return &copied, nil
+		}
+	}
+
+	return &Delivery{}, appErr.NewNotFoundError("Delivery not found", "webhook-delivery-not-found")
+}
+
+func (r *memoryDeliveryRepository) ListForSubscription(ctx context.Context, subscriptionUUID uuid.UUID, limit int) ([]*Delivery, error) {
+	return nil, nil
+}
+
+func (r *memoryDeliveryRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []*Delivery{}
+	for _, d := range r.deliveries {
+		if len(claimed) < limit && d.Status == PENDING && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = now.Add(lease)
+			copied := *d
+			claimed = append(claimed, &copied)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (r *memoryDeliveryRepository) Save(ctx context.Context, d *Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.deliveries {
+		if existing.UUID == d.UUID {
+			copied := *d
+			r.deliveries[i] = &copied
+		}
+	}
+
+	return nil
+}
+
+type adminRepository struct {
+	user.UserRepository
+	admin *user.User
+}
+
+func (r adminRepository) FindById(ctx context.Context, userUUID uuid.UUID) (*user.User, error) {
+	return r.admin, nil
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	status   int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	w.WriteHeader(rc.status)
+	w.Write([]byte("ok " + strconv.Itoa(rc.status)))
+}
+
+func newTestService() (*Service, *memoryRepository, *memoryDeliveryRepository, uuid.UUID) {
+	admin := user.NewUser(uuid.New(), "admin@example.com", "Admin", "hash", role.ADMIN, user.DefaultUserSettings(), time.Now(), time.Now())
+	r := &memoryRepository{}
+	dr := &memoryDeliveryRepository{}
+
+	return NewService(r, dr, adminRepository{admin: admin}, event.DefaultSource, true), r, dr, admin.UUID
+}
+
+func publish(t *testing.T, h *Service, eventType event.Type) *event.Event {
+	e, err := event.New(eventType, uuid.New(), event.UserPayload{Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Publish(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestService_CreateSubscription(t *testing.T) {
+	h, _, _, admin := newTestService()
+	h.allowHTTP = false
+
+	tt := []struct {
+		name   string
+		url    string
+		events []string
+		slug   string
+	}{
+		{name: "Valid", url: "https://partner.example.com/hooks", events: []string{"user.created"}},
+		{name: "Every event", url: "https://partner.example.com/hooks"},
+		{name: "Plain http", url: "http://partner.example.com/hooks", slug: "field-url-invalid"},
+		{name: "No host", url: "https:///hooks", slug: "field-url-invalid"},
+		{name: "Unknown event", url: "https://partner.example.com/hooks", events: []string{"user.deleted"}, slug: "field-events-invalid"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := h.CreateSubscription(context.Background(), &CreateSubscriptionRequest{ActorUUID: admin, URL: tc.url, Events: tc.events})
+			if tc.slug != "" {
+				if appError, ok := err.(appErr.AppError); !ok || appError.Slug() != tc.slug {
+					t.Fatalf("Want error '%s', got '%v'", tc.slug, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !s.Active || len(s.Secret) <= len(SecretPrefix) || s.Secret[:len(SecretPrefix)] != SecretPrefix {
+				t.Errorf("Want an active subscription with a secret, got %+v", s)
+			}
+		})
+	}
+}
+
+func TestService_Publish(t *testing.T) {
+	h, _, dr, admin := newTestService()
+	ctx := context.Background()
+
+	created, _ := h.CreateSubscription(ctx, &CreateSubscriptionRequest{ActorUUID: admin, URL: "https://a.example.com", Events: []string{"user.created"}})
+	every, _ := h.CreateSubscription(ctx, &CreateSubscriptionRequest{ActorUUID: admin, URL: "https://b.example.com"})
+	disabled, _ := h.CreateSubscription(ctx, &CreateSubscriptionRequest{ActorUUID: admin, URL: "https://c.example.com"})
+	disabled.Active = false
+
+	e := publish(t, h, event.USER_CREATED)
+	publish(t, h, event.USER_UPDATED)
+
+	// the relay publishes again after a failure further down the fanout
+	if err := h.Publish(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[uuid.UUID]int{created.UUID: 1, every.UUID: 2}
+	got := map[uuid.UUID]int{}
+	for _, d := range dr.deliveries {
+		got[d.SubscriptionUUID]++
+	}
+
+	if len(got) != len(want) || got[created.UUID] != want[created.UUID] || got[every.UUID] != want[every.UUID] {
+		t.Fatalf("Want deliveries %v, got %v", want, got)
+	}
+
+	var payload event.CloudEvent
+	if err := json.Unmarshal(dr.deliveries[0].Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.ID != e.UUID.String() || payload.Type != event.USER_CREATED.String() {
+		t.Errorf("Want the CloudEvent of the event as payload, got %+v", payload)
+	}
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	rc := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	h, r, dr, admin := newTestService()
+	s, err := h.CreateSubscription(context.Background(), &CreateSubscriptionRequest{ActorUUID: admin, URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := publish(t, h, event.USER_CREATED)
+
+	dispatcher := NewDispatcher(dr, r, DefaultDeliveryPolicy)
+	claimed, err := dispatcher.Dispatch(context.Background(), func(err error) { t.Error(err) })
+	if err != nil || claimed != 1 {
+		t.Fatalf("Want one delivery claimed, got %d (%v)", claimed, err)
+	}
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("Want one request received, got %d", len(rc.requests))
+	}
+
+	req, body := rc.requests[0], rc.bodies[0]
+	timestamp, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	if req.Header.Get(HeaderSignature) != Sign(s.Secret, timestamp, body) {
+		t.Errorf("Want the body signed with the secret, got '%s'", req.Header.Get(HeaderSignature))
+	}
+
+	if req.Header.Get(HeaderId) != e.UUID.String() || req.Header.Get(HeaderEvent) != "user.created" {
+		t.Errorf("Want the event headers, got %v", req.Header)
+	}
+
+	d := dr.deliveries[0]
+	if d.Status != DELIVERED || d.Attempts != 1 || d.ResponseCode != http.StatusOK || d.ResponseBody != "ok 200" || d.DeliveredAt == nil {
+		t.Errorf("Want the delivery logged as delivered, got %+v", d)
+	}
+}
+
+func TestDispatcher_DispatchRetries(t *testing.T) {
+	rc := &receiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	h, r, dr, admin := newTestService()
+	s, _ := h.CreateSubscription(context.Background(), &CreateSubscriptionRequest{ActorUUID: admin, URL: server.URL})
+	publish(t, h, event.USER_UPDATED)
+
+	policy := DefaultDeliveryPolicy
+	policy.MaxAttempts = 3
+	dispatcher := NewDispatcher(dr, r, policy)
+
+	failures := 0
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		before := time.Now()
+		if _, err := dispatcher.Dispatch(context.Background(), func(err error) { failures++ }); err != nil {
+			t.Fatal(err)
+		}
+
+		d := dr.deliveries[0]
+		if d.Attempts != attempt || d.ResponseCode != http.StatusServiceUnavailable || d.LastError == "" {
+			t.Fatalf("Want the failed attempt %d logged, got %+v", attempt, d)
+		}
+
+		if attempt < policy.MaxAttempts && (d.Status != PENDING || d.NextAttemptAt.Before(before.Add(policy.Backoff(attempt)))) {
+			t.Fatalf("Want a retry after %s, got %+v", policy.Backoff(attempt), d)
+		}
+
+		// the backoff has passed
+		d.NextAttemptAt = time.Now()
+	}
+
+	if failures != policy.MaxAttempts || dr.deliveries[0].Status != FAILED {
+		t.Fatalf("Want the delivery failed after %d attempts, got %+v", policy.MaxAttempts, dr.deliveries[0])
+	}
+
+	rc.status = http.StatusNoContent
+	redelivery, err := h.Redeliver(context.Background(), admin, s.UUID, dr.deliveries[0].UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher.Dispatch(context.Background(), func(err error) { t.Error(err) })
+
+	d, _ := dr.FindById(context.Background(), redelivery.UUID)
+	if d.Status != DELIVERED || d.RedeliveryOf != dr.deliveries[0].UUID || d.ResponseCode != http.StatusNoContent {
+		t.Errorf("Want the redelivery delivered, got %+v", d)
+	}
+
+	if len(rc.bodies) != policy.MaxAttempts+1 || string(rc.bodies[0]) != string(rc.bodies[policy.MaxAttempts]) {
+		t.Errorf("Want the same payload redelivered, got %d requests", len(rc.bodies))
+	}
+
+	if _, err := h.Redeliver(context.Background(), admin, uuid.New(), d.UUID); !appErr.IsNotFound(err) {
+		t.Errorf("Want a delivery of another subscription not found, got %v", err)
+	}
+}
+
+func TestDispatcher_DispatchDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { followed = true }))
+	defer target.Close()
+
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer server.Close()
+
+	h, r, dr, admin := newTestService()
+	h.CreateSubscription(context.Background(), &CreateSubscriptionRequest{ActorUUID: admin, URL: server.URL})
+	publish(t, h, event.USER_CREATED)
+
+	NewDispatcher(dr, r, DefaultDeliveryPolicy).Dispatch(context.Background(), func(err error) {})
+
+	if followed || dr.deliveries[0].Status != PENDING || dr.deliveries[0].ResponseCode != http.StatusFound {
+		t.Errorf("Want a redirect to be a failed attempt, got %+v", dr.deliveries[0])
+	}
+}
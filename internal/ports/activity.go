@@ -0,0 +1,159 @@
+package ports
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+)
+
+type AuthEventResponse struct {
+	UUID      uuid.UUID  `json:"uuid"`
+	Type      string     `json:"type"`
+	UserUUID  *uuid.UUID `json:"user_uuid,omitempty"`
+	ActorUUID *uuid.UUID `json:"actor_uuid,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	RequestID string     `json:"request_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type AuthEventListResponse struct {
+	Events []AuthEventResponse `json:"events"`
+	// NextBefore is the before parameter of the next page, empty on the last page
+	NextBefore string `json:"next_before,omitempty"`
+}
+
+func (e *AuthEventListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+func optionalUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	return &id
+}
+
+func newAuthEventListResponse(events []*audit.Event, limit int) *AuthEventListResponse {
+	response := &AuthEventListResponse{Events: make([]AuthEventResponse, 0, len(events))}
+	for _, e := range events {
+		response.Events = append(response.Events, AuthEventResponse{
+			UUID:      e.UUID,
+			Type:      e.Type.String(),
+			UserUUID:  optionalUUID(e.UserUUID),
+			ActorUUID: optionalUUID(e.ActorUUID),
+			Email:     e.Email,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			RequestID: e.RequestID,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+
+	if len(events) > 0 && len(events) == limit {
+		response.NextBefore = events[len(events)-1].CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	return response
+}
+
+// parseActivityPage reads the before (RFC 3339) and limit query parameters
+func parseActivityPage(r *http.Request) (time.Time, int, error) {
+	var before time.Time
+	if value := r.URL.Query().Get("before"); value != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, 0, apperrors.NewIncorrectInputError(err.Error(), "field-before-invalid")
+		}
+		before = parsed
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > audit.MaxLimit {
+			return time.Time{}, 0, apperrors.NewIncorrectInputError("Invalid limit", "field-limit-invalid")
+		}
+		limit = parsed
+	}
+
+	return before, limit, nil
+}
+
+func (h *HttpServer) myActivity(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.PROFILE_READ, w, r)
+	if !ok {
+		return
+	}
+
+	before, limit, err := parseActivityPage(r)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	events, err := h.app.GetAuthService().ListActivity(r.Context(), access.UserId, before, limit)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	render.Render(w, r, newAuthEventListResponse(events, audit.Query{Limit: limit}.Normalized().Limit))
+}
+
+// listAuthEvents filters by the user_uuid, email and comma separated type query parameters
+func (h *HttpServer) listAuthEvents(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	before, limit, err := parseActivityPage(r)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	q := audit.Query{
+		Email:  r.URL.Query().Get("email"),
+		Before: before,
+		Limit:  limit,
+	}
+
+	if value := r.URL.Query().Get("user_uuid"); value != "" {
+		q.UserUUID, err = uuid.Parse(value)
+		if err != nil {
+			h.BadRequest("field-user-uuid-invalid", err, w, r)
+			return
+		}
+	}
+
+	if value := r.URL.Query().Get("type"); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			eventType, err := audit.FromString(strings.TrimSpace(item))
+			if err != nil {
+				h.BadRequest("field-type-invalid", err, w, r)
+				return
+			}
+			q.Types = append(q.Types, eventType)
+		}
+	}
+
+	events, err := h.app.GetAuthService().ListAuthEvents(r.Context(), access.UserId, q)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	render.Render(w, r, newAuthEventListResponse(events, q.Normalized().Limit))
+}
@@ -0,0 +1,136 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/audit"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_myActivity(t *testing.T) {
+	userUUID := uuid.New()
+	eventUUID := uuid.New()
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	events := []*audit.Event{{
+		UUID:      eventUUID,
+		Type:      audit.SIGN_IN,
+		UserUUID:  userUUID,
+		Email:     "email",
+		IP:        "10.0.0.1",
+		UserAgent: "agent",
+		RequestID: "host/1",
+		CreatedAt: createdAt,
+	}}
+
+	tt := []struct {
+		name       string
+		query      string
+		before     time.Time
+		limit      int
+		want       string
+		statusCode int
+	}{
+		{
+			name:       "First page",
+			want:       `{"events":[{"uuid":"` + eventUUID.String() + `","type":"sign-in","user_uuid":"` + userUUID.String() + `","email":"email","ip":"10.0.0.1","user_agent":"agent","request_id":"host/1","created_at":"2026-10-18T12:00:00Z"}]}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Full page links the next one",
+			query:      "?before=2026-10-19T00:00:00Z&limit=1",
+			before:     time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			limit:      1,
+			want:       `{"events":[{"uuid":"` + eventUUID.String() + `","type":"sign-in","user_uuid":"` + userUUID.String() + `","email":"email","ip":"10.0.0.1","user_agent":"agent","request_id":"host/1","created_at":"2026-10-18T12:00:00Z"}],"next_before":"2026-10-18T12:00:00Z"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Invalid before",
+			query:      "?before=yesterday",
+			want:       `{"slug":"field-before-invalid"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Invalid limit",
+			query:      "?limit=0",
+			want:       `{"slug":"field-limit-invalid"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/me/activity"+tc.query, nil)
+			request.Header.Set("Authorization", "Bearer access")
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: []scope.Scope{scope.PROFILE_READ}}, nil)
+			authMock.On("ListActivity", mock.Anything, userUUID, tc.before, tc.limit).Return(events, nil)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			server.myActivity(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_listAuthEvents(t *testing.T) {
+	adminUUID := uuid.New()
+	targetUUID := uuid.New()
+
+	tt := []struct {
+		name       string
+		query      string
+		want       string
+		statusCode int
+	}{
+		{name: "Filtered by user and types", query: "?user_uuid=" + targetUUID.String() + "&type=sign-in-failed,password-reset", want: `{"events":[]}`, statusCode: http.StatusOK},
+		{name: "Unknown type", query: "?type=sign-in,logout", want: `{"slug":"field-type-invalid"}`, statusCode: http.StatusBadRequest},
+		{name: "Invalid user uuid", query: "?user_uuid=nope", want: `{"slug":"field-user-uuid-invalid"}`, statusCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/admin/activity"+tc.query, nil)
+			request.Header.Set("Authorization", "Bearer access")
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: adminUUID, Scopes: scope.Full()}, nil)
+			authMock.On("ListAuthEvents", mock.Anything, adminUUID, audit.Query{
+				UserUUID: targetUUID,
+				Types:    []audit.Type{audit.SIGN_IN_FAILED, audit.PASSWORD_RESET},
+			}).Return([]*audit.Event{}, nil)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			server.listAuthEvents(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package ports
+
+import (
+	"net/http"
+
+	"github.com/go-chi/cors"
+	"github.com/ibgl/microservice-users/internal/app"
+)
+
+var (
+	DefaultCorsMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	DefaultCorsHeaders = []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"}
+	// CorsExposedHeaders are the response headers clients read, they are not configurable
+	CorsExposedHeaders = []string{"Link", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"}
+)
+
+const DefaultCorsMaxAge = 300 // Maximum value not ignored by any of major browsers
+
+// corsOptions reads the policy from the config, origins may hold one wildcard each (https://*.example.com)
+// and an empty origin list allows no cross-origin requests
+func (h *HttpServer) corsOptions() cors.Options {
+	config := h.app.GetConfig()
+
+	options := cors.Options{
+		AllowedOrigins:   app.SplitList(config.CorsAllowedOrigins),
+		AllowedMethods:   app.SplitList(config.CorsAllowedMethods),
+		AllowedHeaders:   app.SplitList(config.CorsAllowedHeaders),
+		ExposedHeaders:   CorsExposedHeaders,
+		AllowCredentials: config.CorsAllowCredentials,
+		MaxAge:           config.CorsMaxAge,
+	}
+
+	// the cors package allows any origin for an empty list without the func
+	if len(options.AllowedOrigins) == 0 {
+		options.AllowOriginFunc = func(r *http.Request, origin string) bool { return false }
+	}
+
+	if len(options.AllowedMethods) == 0 {
+		options.AllowedMethods = DefaultCorsMethods
+	}
+
+	if len(options.AllowedHeaders) == 0 {
+		options.AllowedHeaders = DefaultCorsHeaders
+	}
+
+	if options.MaxAge == 0 {
+		options.MaxAge = DefaultCorsMaxAge
+	}
+
+	return options
+}
@@ -0,0 +1,57 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/cors"
+	"github.com/ibgl/microservice-users/internal/app"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+)
+
+func Test_cors(t *testing.T) {
+	production := &app.Config{
+		CorsAllowedOrigins:   "https://app.example.com, https://*.example.org",
+		CorsAllowCredentials: true,
+	}
+
+	tt := []struct {
+		name        string
+		config      *app.Config
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{name: "No origin by default", config: &app.Config{}, origin: "https://other.com"},
+		{name: "Any origin", config: &app.Config{CorsAllowedOrigins: "*"}, origin: "https://other.com", allowOrigin: "*"},
+		{name: "Listed origin", config: production, origin: "https://app.example.com", allowOrigin: "https://app.example.com", credentials: "true"},
+		{name: "Wildcard subdomain", config: production, origin: "https://eu.example.org", allowOrigin: "https://eu.example.org", credentials: "true"},
+		{name: "Unlisted origin", config: production, origin: "https://example.com.evil.com"},
+		{name: "Other scheme", config: production, origin: "http://app.example.com"},
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server := NewHttpServer(mocks.NewAppMock(tc.config))
+
+			request := httptest.NewRequest(http.MethodOptions, "/api/v1/refresh", nil)
+			request.Header.Set("Origin", tc.origin)
+			request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			request.Header.Set("Access-Control-Request-Headers", "X-CSRF-Token")
+			responseRecorder := httptest.NewRecorder()
+
+			cors.Handler(server.corsOptions())(ok).ServeHTTP(responseRecorder, request)
+
+			if got := responseRecorder.Header().Get("Access-Control-Allow-Origin"); got != tc.allowOrigin {
+				t.Errorf("Want Access-Control-Allow-Origin '%s', got '%s'", tc.allowOrigin, got)
+			}
+
+			if got := responseRecorder.Header().Get("Access-Control-Allow-Credentials"); got != tc.credentials {
+				t.Errorf("Want Access-Control-Allow-Credentials '%s', got '%s'", tc.credentials, got)
+			}
+		})
+	}
+}
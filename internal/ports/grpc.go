@@ -0,0 +1,499 @@
+package ports
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	usersv1 "github.com/ibgl/microservice-users/api/users/v1"
+	"github.com/ibgl/microservice-users/internal/app"
+	"github.com/ibgl/microservice-users/internal/app/email"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	auth "github.com/ibgl/microservice-users/internal/app/user"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const DEFAULT_GRPC_PORT = "9088"
+
+// ErrorDomain is the domain of the google.rpc.ErrorInfo details, the reason is the slug of the HTTP API
+const ErrorDomain = "users"
+
+// GrpcServer serves the users API to internal services over gRPC next to the HttpServer
+type GrpcServer struct {
+	usersv1.UnimplementedUsersServiceServer
+	app       app.Application
+	validator *validator.Validate
+	// trustedProxies may forward the client address in the metadata, other peers are the client themselves
+	trustedProxies []*net.IPNet
+}
+
+// grpcMethod is the access policy of a method. Rate limits are shared with the HTTP routes of the same name.
+type grpcMethod struct {
+	route         string
+	authenticated bool
+	scope         scope.Scope
+}
+
+var grpcMethods = map[string]grpcMethod{
+	usersv1.UsersService_SignIn_FullMethodName:         {route: "signIn"},
+	usersv1.UsersService_SignUp_FullMethodName:         {route: "signUp"},
+	usersv1.UsersService_Refresh_FullMethodName:        {route: "refresh"},
+	usersv1.UsersService_ValidateToken_FullMethodName:  {route: "validate-token"},
+	usersv1.UsersService_GetUser_FullMethodName:        {route: "me", authenticated: true, scope: scope.PROFILE_READ},
+	usersv1.UsersService_UpdateSettings_FullMethodName: {route: "settings", authenticated: true, scope: scope.SETTINGS_WRITE},
+	usersv1.UsersService_GetUsers_FullMethodName:       {route: "users-batch", authenticated: true, scope: scope.PROFILE_READ},
+}
+
+type grpcAccessKey struct{}
+
+func NewGrpcServer(app app.Application) *GrpcServer {
+	h := &GrpcServer{
+		app:       app,
+		validator: validator.New(),
+	}
+
+	if config := app.GetConfig(); config != nil {
+		h.trustedProxies = parseTrustedProxies(config.TrustedProxies, app.GetLogger())
+	}
+
+	return h
+}
+
+// Server builds the grpc.Server with the interceptors and the users service registered
+func (h *GrpcServer) Server() *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(h.clientInfo, h.authenticate, h.rateLimit))
+	usersv1.RegisterUsersServiceServer(server, h)
+
+	return server
+}
+
+// Start serves until the context is done, the calls in flight are finished before it returns
+func (h *GrpcServer) Start(ctx context.Context) {
+	port := DEFAULT_GRPC_PORT
+	if os.Getenv("GRPC_PORT") != "" {
+		port = os.Getenv("GRPC_PORT")
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		h.app.GetLogger().Errorf("gRPC server listen error %v", err)
+		return
+	}
+
+	server := h.Server()
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	h.app.GetLogger().Printf("gRPC server started on %s", port)
+	if err := server.Serve(listener); err != nil {
+		h.app.GetLogger().Errorf("gRPC server error %v", err)
+	}
+}
+
+// clientInfo is the counterpart of the HTTP clientInfo middleware, the address is the peer unless the peer
+// is a trusted proxy forwarding the client address in the metadata
+func (h *GrpcServer) clientInfo(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := metadataValue(md, "x-request-id")
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	ctx = auth.WithClientInfo(ctx, auth.ClientInfo{
+		IP:        grpcClientIP(ctx, md, h.trustedProxies),
+		UserAgent: metadataValue(md, "user-agent"),
+		RequestID: requestID,
+		Locale:    email.ParseAcceptLanguage(metadataValue(md, "accept-language")),
+	})
+
+	return handler(ctx, req)
+}
+
+func metadataValue(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func grpcClientIP(ctx context.Context, md metadata.MD, trustedProxies []*net.IPNet) string {
+	return forwardedClientIP(peerIP(ctx), func(name string) string {
+		return metadataValue(md, name)
+	}, trustedProxies)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+// authenticate validates the bearer token of the methods which require one and checks its scope,
+// the token is then available to the method with accessFromContext
+func (h *GrpcServer) authenticate(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	method, ok := grpcMethods[info.FullMethod]
+	if !ok || !method.authenticated {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := ""
+	if header := metadataValue(md, "authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+
+	if token == "" {
+		return nil, h.respondWithError(apperrors.NewAuthorizationError("Token not presented", "invalid-token"))
+	}
+
+	access, err := h.app.GetAuthService().ValidateToken(ctx, token)
+	if err != nil {
+		if apperrors.IsApp(err) {
+			return nil, h.respondWithError(err)
+		}
+
+		return nil, h.respondWithError(apperrors.NewAuthorizationError(err.Error(), "invalid-token"))
+	}
+
+	if !access.HasScope(method.scope) {
+		return nil, h.respondWithError(apperrors.NewForbiddenError("Token lacks "+method.scope.String()+" scope", "insufficient-scope"))
+	}
+
+	return handler(context.WithValue(ctx, grpcAccessKey{}, access), req)
+}
+
+func accessFromContext(ctx context.Context) auth.Token {
+	access, _ := ctx.Value(grpcAccessKey{}).(auth.Token)
+	return access
+}
+
+// rateLimit applies the policy of the method route, keyed like the HTTP rateLimit
+func (h *GrpcServer) rateLimit(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	limiter := h.app.GetRateLimiter()
+	method, ok := grpcMethods[info.FullMethod]
+	if limiter == nil || !ok {
+		return handler(ctx, req)
+	}
+
+	policy, ok := limiter.Policy(method.route)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	subject := "ip:" + auth.ClientInfoFromContext(ctx).IP
+	if access := accessFromContext(ctx); policy.KeyBy == ratelimit.USER && access.Value != "" {
+		subject = "user:" + access.UserId.String()
+		if access.IsService() {
+			subject = "client:" + access.ClientId
+		}
+	}
+
+	result, err := limiter.Allow(ctx, method.route, subject)
+	if err != nil {
+		// an unavailable store must not take the service down
+		h.app.GetLogger().Errorf("Rate limit store error %v", err)
+		return handler(ctx, req)
+	}
+
+	if !result.Allowed {
+		return nil, h.respondWithError(apperrors.NewTooManyRequestsError("Rate limit exceeded for "+method.route, "rate-limit-exceeded", result.RetryAfter))
+	}
+
+	return handler(ctx, req)
+}
+
+// grpcCode is the status code of an application error type, the counterpart of RespondWithAppError
+func grpcCode(t apperrors.ErrorType) codes.Code {
+	switch t {
+	case apperrors.ErrorTypeAuthorization:
+		return codes.Unauthenticated
+	case apperrors.ErrorTypeIncorrectInput:
+		return codes.InvalidArgument
+	case apperrors.ErrorTypeForbidden:
+		return codes.PermissionDenied
+	case apperrors.ErrorTypeTooManyRequests:
+		return codes.ResourceExhausted
+	case apperrors.ErrorTypeUnavailable:
+		return codes.Unavailable
+	case apperrors.ErrorNotFound:
+		return codes.NotFound
+	}
+
+	return codes.Internal
+}
+
+// respondWithError converts an application error into a status with the slug in an ErrorInfo and the retry
+// delay in a RetryInfo, other errors are internal and their message is only logged
+func (h *GrpcServer) respondWithError(err error) error {
+	appError, ok := err.(apperrors.AppError)
+	if !ok {
+		appError = apperrors.NewAppError(err.Error(), "internal-server-error")
+	}
+
+	h.app.GetLogger().Debug(map[string]string{
+		"error-type": "gRPC Request Error",
+		"slug":       appError.Slug(),
+		"error":      appError.Error(),
+	})
+
+	code := grpcCode(appError.ErrorType())
+	message := appError.Error()
+	if code == codes.Internal {
+		message = "Internal server error"
+	}
+
+	st, detailsErr := status.New(code, message).WithDetails(&errdetails.ErrorInfo{Reason: appError.Slug(), Domain: ErrorDomain})
+	if detailsErr != nil {
+		return status.Error(code, message)
+	}
+
+	if appError.RetryAfter() > 0 {
+		if withRetry, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(appError.RetryAfter())}); err == nil {
+			st = withRetry
+		}
+	}
+
+	return st.Err()
+}
+
+func (h *GrpcServer) validate(request interface{}) error {
+	if err := h.validator.Struct(request); err != nil {
+		fieldErr := err.(validator.ValidationErrors)[0]
+		return apperrors.NewIncorrectInputError(fieldErr.Error(), validationSlug(fieldErr))
+	}
+
+	return nil
+}
+
+func newTokenPair(tokens *auth.LoginResponse) *usersv1.TokenPair {
+	return &usersv1.TokenPair{
+		Access:  tokens.Access.Value,
+		Refresh: tokens.Refresh.Value,
+	}
+}
+
+func newGrpcUser(u *auth.User) *usersv1.User {
+	return &usersv1.User{
+		Uuid:  u.UUID.String(),
+		Email: u.Email,
+		Name:  u.Name,
+		Settings: &usersv1.Settings{
+			Currency:          u.Settings.Currency.String(),
+			FirstDayOfWeek:    u.Settings.FirstDayOfWeek.String(),
+			ProfilePictureUrl: u.Settings.ProfilePictureUrl,
+		},
+		CreatedAt: timestamppb.New(u.CreatedAt),
+	}
+}
+
+func uuidString(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+
+	return id.String()
+}
+
+func (h *GrpcServer) SignIn(ctx context.Context, r *usersv1.SignInRequest) (*usersv1.TokenPair, error) {
+	tokens, err := h.app.GetAuthService().SignIn(ctx, &auth.SignInRequest{
+		Email:        r.Email,
+		Password:     r.Password,
+		CaptchaToken: r.CaptchaToken,
+	})
+	if err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	return newTokenPair(tokens), nil
+}
+
+func (h *GrpcServer) SignUp(ctx context.Context, r *usersv1.SignUpRequest) (*usersv1.SignUpResponse, error) {
+	request := SignUpRequest{
+		Email:        r.Email,
+		Password:     r.Password,
+		Name:         r.Name,
+		CaptchaToken: r.CaptchaToken,
+	}
+
+	if err := h.validate(request); err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	serviceRequest := &auth.SignUpRequest{
+		Email:        request.Email,
+		Password:     request.Password,
+		Name:         request.Name,
+		CaptchaToken: request.CaptchaToken,
+	}
+
+	if h.app.GetConfig().SignUpMode == app.SignUpModeConfirm {
+		if err := h.app.GetAuthService().RequestSignUp(ctx, serviceRequest); err != nil {
+			return nil, h.respondWithError(err)
+		}
+
+		return &usersv1.SignUpResponse{ConfirmationRequired: true}, nil
+	}
+
+	tokens, err := h.app.GetAuthService().SignUp(ctx, serviceRequest)
+	if err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	return &usersv1.SignUpResponse{Tokens: newTokenPair(tokens)}, nil
+}
+
+func (h *GrpcServer) Refresh(ctx context.Context, r *usersv1.RefreshRequest) (*usersv1.TokenPair, error) {
+	if r.Refresh == "" {
+		return nil, h.respondWithError(apperrors.NewIncorrectInputError("Refresh token is required", "invalid-token"))
+	}
+
+	tokens, err := h.app.GetAuthService().Refresh(ctx, r.Refresh)
+	if err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	return newTokenPair(tokens), nil
+}
+
+func (h *GrpcServer) ValidateToken(ctx context.Context, r *usersv1.ValidateTokenRequest) (*usersv1.AccessToken, error) {
+	access, err := h.app.GetAuthService().ValidateToken(ctx, r.Token)
+	if err != nil {
+		if !apperrors.IsApp(err) {
+			err = apperrors.NewAuthorizationError(err.Error(), "invalid-token")
+		}
+
+		return nil, h.respondWithError(err)
+	}
+
+	return &usersv1.AccessToken{
+		UserUuid:      uuidString(access.UserId),
+		ActorUuid:     uuidString(access.ActorId),
+		ClientId:      access.ClientId,
+		HouseholdUuid: uuidString(access.HouseholdId),
+		Scopes:        scope.ToStrings(access.Scopes),
+	}, nil
+}
+
+func (h *GrpcServer) GetUser(ctx context.Context, r *usersv1.GetUserRequest) (*usersv1.User, error) {
+	access := accessFromContext(ctx)
+
+	userUUID := access.UserId
+	if r.Uuid != "" {
+		requested, err := uuid.Parse(r.Uuid)
+		if err != nil {
+			return nil, h.respondWithError(apperrors.NewNotFoundError("User not found", "user-not-found"))
+		}
+
+		if !access.IsService() && requested != access.UserId {
+			return nil, h.respondWithError(apperrors.NewForbiddenError("Users can only read themselves", "service-token-required"))
+		}
+
+		userUUID = requested
+	} else if access.IsService() {
+		return nil, h.respondWithError(apperrors.NewIncorrectInputError("Service tokens have to name the user", "field-uuid-required"))
+	}
+
+	u, err := h.app.GetAuthService().GetUser(ctx, userUUID)
+	if err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	return newGrpcUser(u), nil
+}
+
+func (h *GrpcServer) UpdateSettings(ctx context.Context, r *usersv1.UpdateSettingsRequest) (*usersv1.User, error) {
+	access := accessFromContext(ctx)
+	if access.IsService() {
+		return nil, h.respondWithError(apperrors.NewAuthorizationError("Service tokens are not allowed for user endpoints", "user-token-required"))
+	}
+
+	payload := SettingsPayload{
+		Currency:          r.GetSettings().GetCurrency(),
+		FirstDayOfWeek:    r.GetSettings().GetFirstDayOfWeek(),
+		ProfilePictureUrl: r.GetSettings().GetProfilePictureUrl(),
+	}
+
+	if err := h.validate(payload); err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	u, err := h.app.GetAuthService().UpdateSettings(ctx, &auth.UpdateSettingsRequest{
+		UserUUID:          access.UserId,
+		Currency:          payload.Currency,
+		FirstDayOfWeek:    payload.FirstDayOfWeek,
+		ProfilePictureUrl: payload.ProfilePictureUrl,
+	})
+	if err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	return newGrpcUser(u), nil
+}
+
+// GetUsers looks the users up in one query, malformed uuids are reported missing like unknown ones
+func (h *GrpcServer) GetUsers(ctx context.Context, r *usersv1.GetUsersRequest) (*usersv1.GetUsersResponse, error) {
+	if !accessFromContext(ctx).IsService() {
+		return nil, h.respondWithError(apperrors.NewForbiddenError("Batch lookups are for services", "service-token-required"))
+	}
+
+	response := &usersv1.GetUsersResponse{Users: []*usersv1.Profile{}, MissingUuids: []string{}}
+
+	userUUIDs := make([]uuid.UUID, 0, len(r.Uuids))
+	for _, value := range r.Uuids {
+		userUUID, err := uuid.Parse(value)
+		if err != nil {
+			response.MissingUuids = append(response.MissingUuids, value)
+			continue
+		}
+
+		userUUIDs = append(userUUIDs, userUUID)
+	}
+
+	users, err := h.app.GetAuthService().GetUsers(ctx, userUUIDs)
+	if err != nil {
+		return nil, h.respondWithError(err)
+	}
+
+	found := make(map[uuid.UUID]bool, len(users))
+	for _, u := range users {
+		found[u.UUID] = true
+		response.Users = append(response.Users, &usersv1.Profile{
+			Uuid:              u.UUID.String(),
+			Name:              u.Name,
+			ProfilePictureUrl: u.Settings.ProfilePictureUrl,
+		})
+	}
+
+	for _, userUUID := range userUUIDs {
+		if !found[userUUID] {
+			response.MissingUuids = append(response.MissingUuids, userUUID.String())
+		}
+	}
+
+	return response, nil
+}
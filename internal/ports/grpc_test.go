@@ -0,0 +1,227 @@
+package ports
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	usersv1 "github.com/ibgl/microservice-users/api/users/v1"
+	"github.com/ibgl/microservice-users/internal/app"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/role"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newGrpcClient(t *testing.T, application app.Application) usersv1.UsersServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGrpcServer(application).Server()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return usersv1.NewUsersServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// assertStatus checks the code and the slug in the ErrorInfo of a failed call
+func assertStatus(t *testing.T, err error, code codes.Code, slug string) {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != code {
+		t.Fatalf("Want status '%s', got '%v'", code, err)
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if info.Reason != slug || info.Domain != ErrorDomain {
+				t.Errorf("Want reason '%s', got '%s'", slug, info.Reason)
+			}
+			return
+		}
+	}
+
+	t.Errorf("Want an ErrorInfo with reason '%s', got %v", slug, st.Details())
+}
+
+func Test_grpcClientIP(t *testing.T) {
+	proxies := parseTrustedProxies("10.0.0.0/8, 192.168.1.5, not-an-address", mocks.NewAppMock(nil).GetLogger())
+
+	tt := []struct {
+		name string
+		peer string
+		md   metadata.MD
+		want string
+	}{
+		{name: "Peer without metadata", peer: "203.0.113.7:5000", md: metadata.MD{}, want: "203.0.113.7"},
+		{name: "Untrusted peer can not forward", peer: "203.0.113.7:5000", md: metadata.Pairs("x-real-ip", "198.51.100.1", "x-forwarded-for", "198.51.100.2"), want: "203.0.113.7"},
+		{name: "Trusted proxy forwards x-real-ip", peer: "10.1.2.3:5000", md: metadata.Pairs("x-real-ip", "198.51.100.1"), want: "198.51.100.1"},
+		{name: "Trusted proxy forwards true-client-ip", peer: "192.168.1.5:5000", md: metadata.Pairs("true-client-ip", "198.51.100.1", "x-real-ip", "198.51.100.2"), want: "198.51.100.1"},
+		{name: "Spoofed hops left of the client are skipped", peer: "10.1.2.3:5000", md: metadata.Pairs("x-forwarded-for", "1.2.3.4, 198.51.100.1, 10.4.4.4"), want: "198.51.100.1"},
+		{name: "Trusted proxy without metadata", peer: "10.1.2.3:5000", md: metadata.MD{}, want: "10.1.2.3"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tc.peer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+			if got := grpcClientIP(ctx, tc.md, proxies); got != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_grpcCode(t *testing.T) {
+	tt := []struct {
+		err  error
+		code codes.Code
+	}{
+		{err: apperrors.NewAuthorizationError("", "invalid-token"), code: codes.Unauthenticated},
+		{err: apperrors.NewIncorrectInputError("", "field-email-invalid"), code: codes.InvalidArgument},
+		{err: apperrors.NewForbiddenError("", "admin-required"), code: codes.PermissionDenied},
+		{err: apperrors.NewTooManyRequestsError("", "rate-limit-exceeded", time.Second), code: codes.ResourceExhausted},
+		{err: apperrors.NewUnavailableError("", "hashing-overloaded", time.Second), code: codes.Unavailable},
+		{err: apperrors.NewNotFoundError("", "user-not-found"), code: codes.NotFound},
+		{err: apperrors.NewAppError("", "user-saving-error"), code: codes.Internal},
+	}
+
+	for _, tc := range tt {
+		appError := tc.err.(apperrors.AppError)
+		if got := grpcCode(appError.ErrorType()); got != tc.code {
+			t.Errorf("%s: want '%s', got '%s'", appError.Slug(), tc.code, got)
+		}
+	}
+}
+
+func TestGrpcServer_SignIn(t *testing.T) {
+	authMock := new(mocks.AuthServiceMock)
+	authMock.On("SignIn", mock.Anything, &user.SignInRequest{Email: "email@email.ru", Password: "password"}).
+		Return(&user.LoginResponse{Access: user.Token{Value: "access"}, Refresh: user.Token{Value: "refresh"}}, nil)
+	authMock.On("SignIn", mock.Anything, &user.SignInRequest{Email: "email@email.ru", Password: "locked"}).
+		Return(&user.LoginResponse{}, apperrors.NewTooManyRequestsError("Locked", "sign-in-locked", 30*time.Second))
+
+	client := newGrpcClient(t, mocks.NewAppMock(nil).SetAuthService(authMock))
+
+	tokens, err := client.SignIn(context.Background(), &usersv1.SignInRequest{Email: "email@email.ru", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tokens.Access != "access" || tokens.Refresh != "refresh" {
+		t.Errorf("Want the token pair, got %v", tokens)
+	}
+
+	_, err = client.SignIn(context.Background(), &usersv1.SignInRequest{Email: "email@email.ru", Password: "locked"})
+	assertStatus(t, err, codes.ResourceExhausted, "sign-in-locked")
+
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.RetryDelay.AsDuration() != 30*time.Second {
+			t.Errorf("Want a retry after 30s, got %s", info.RetryDelay.AsDuration())
+		}
+	}
+}
+
+func TestGrpcServer_SignUp(t *testing.T) {
+	authMock := new(mocks.AuthServiceMock)
+	client := newGrpcClient(t, mocks.NewAppMock(nil).SetAuthService(authMock))
+
+	_, err := client.SignUp(context.Background(), &usersv1.SignUpRequest{Email: "not an email", Password: "password", Name: "Name"})
+	assertStatus(t, err, codes.InvalidArgument, "field-email-invalid")
+
+	authMock.AssertNotCalled(t, "SignUp", mock.Anything, mock.Anything)
+}
+
+func TestGrpcServer_GetUser(t *testing.T) {
+	userUUID := uuid.New()
+	u := user.NewUser(userUUID, "email@email.ru", "Name", "hash", role.USER, user.DefaultUserSettings(), time.Now(), time.Now())
+
+	authMock := new(mocks.AuthServiceMock)
+	authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: []scope.Scope{scope.PROFILE_READ}}, nil)
+	authMock.On("ValidateToken", mock.Anything, "settings").Return(user.Token{Value: "settings", UserId: userUUID, Scopes: []scope.Scope{scope.SETTINGS_WRITE}}, nil)
+	authMock.On("ValidateToken", mock.Anything, "expired").Return(user.Token{}, apperrors.NewAuthorizationError("Token is expired", "invalid-token"))
+	authMock.On("GetUser", mock.Anything, userUUID).Return(u, nil)
+
+	client := newGrpcClient(t, mocks.NewAppMock(nil).SetAuthService(authMock))
+
+	got, err := client.GetUser(withToken("access"), &usersv1.GetUserRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Uuid != userUUID.String() || got.Email != "email@email.ru" || got.Settings.Currency != "RUB" {
+		t.Errorf("Want the user of the token, got %v", got)
+	}
+
+	_, err = client.GetUser(context.Background(), &usersv1.GetUserRequest{})
+	assertStatus(t, err, codes.Unauthenticated, "invalid-token")
+
+	_, err = client.GetUser(withToken("expired"), &usersv1.GetUserRequest{})
+	assertStatus(t, err, codes.Unauthenticated, "invalid-token")
+
+	_, err = client.GetUser(withToken("settings"), &usersv1.GetUserRequest{})
+	assertStatus(t, err, codes.PermissionDenied, "insufficient-scope")
+
+	_, err = client.GetUser(withToken("access"), &usersv1.GetUserRequest{Uuid: uuid.NewString()})
+	assertStatus(t, err, codes.PermissionDenied, "service-token-required")
+}
+
+func TestGrpcServer_GetUsers(t *testing.T) {
+	alice, bob, unknown := uuid.New(), uuid.New(), uuid.New()
+	users := []*user.User{
+		user.NewUser(alice, "alice@email.ru", "Alice", "hash", role.USER, user.DefaultUserSettings(), time.Now(), time.Now()),
+		user.NewUser(bob, "bob@email.ru", "Bob", "hash", role.USER, user.DefaultUserSettings(), time.Now(), time.Now()),
+	}
+
+	authMock := new(mocks.AuthServiceMock)
+	authMock.On("ValidateToken", mock.Anything, "service").Return(user.Token{Value: "service", ClientId: "svc_transactions", Scopes: []scope.Scope{scope.PROFILE_READ}}, nil)
+	authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: alice, Scopes: scope.Full()}, nil)
+	authMock.On("GetUsers", mock.Anything, []uuid.UUID{alice, unknown, bob}).Return(users, nil)
+
+	client := newGrpcClient(t, mocks.NewAppMock(nil).SetAuthService(authMock))
+
+	got, err := client.GetUsers(withToken("service"), &usersv1.GetUsersRequest{Uuids: []string{alice.String(), "nope", unknown.String(), bob.String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Users) != 2 || got.Users[0].Name != "Alice" || got.Users[1].Name != "Bob" {
+		t.Errorf("Want the profiles of the found users, got %v", got.Users)
+	}
+
+	if len(got.MissingUuids) != 2 || got.MissingUuids[0] != "nope" || got.MissingUuids[1] != unknown.String() {
+		t.Errorf("Want the malformed and unknown uuids missing, got %v", got.MissingUuids)
+	}
+
+	_, err = client.GetUsers(withToken("access"), &usersv1.GetUsersRequest{Uuids: []string{bob.String()}})
+	assertStatus(t, err, codes.PermissionDenied, "service-token-required")
+}
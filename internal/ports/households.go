@@ -0,0 +1,384 @@
+package ports
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app/household"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+)
+
+type CreateHouseholdRequest struct {
+	Name string `json:"name" validate:"required,gte=1,lte=200"`
+}
+
+type InviteRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ActiveHouseholdRequest struct {
+	HouseholdUUID *uuid.UUID `json:"household_uuid"`
+}
+
+type HouseholdResponse struct {
+	UUID      uuid.UUID `json:"uuid"`
+	Name      string    `json:"name"`
+	OwnerUUID uuid.UUID `json:"owner_uuid"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type HouseholdMemberResponse struct {
+	UserUUID uuid.UUID `json:"user_uuid"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type HouseholdDetailsResponse struct {
+	HouseholdResponse
+	Members    []HouseholdMemberResponse `json:"members"`
+	httpStatus int
+}
+
+type HouseholdListResponse struct {
+	Households []HouseholdResponse `json:"households"`
+}
+
+type InvitationResponse struct {
+	UUID          uuid.UUID `json:"uuid"`
+	HouseholdUUID uuid.UUID `json:"household_uuid"`
+	HouseholdName string    `json:"household_name"`
+	Email         string    `json:"email"`
+	Status        string    `json:"status"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	httpStatus    int
+}
+
+type InvitationListResponse struct {
+	Invitations []InvitationResponse `json:"invitations"`
+}
+
+func (e *HouseholdDetailsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, e.httpStatus)
+	return nil
+}
+
+func (e *HouseholdListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+func (e *InvitationResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, e.httpStatus)
+	return nil
+}
+
+func (e *InvitationListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+func newHouseholdResponse(h *household.Household) HouseholdResponse {
+	return HouseholdResponse{
+		UUID:      h.UUID,
+		Name:      h.Name,
+		OwnerUUID: h.OwnerUUID,
+		CreatedAt: h.CreatedAt,
+	}
+}
+
+func newHouseholdDetailsResponse(d *household.Details, status int) *HouseholdDetailsResponse {
+	response := &HouseholdDetailsResponse{
+		HouseholdResponse: newHouseholdResponse(d.Household),
+		Members:           make([]HouseholdMemberResponse, 0, len(d.Members)),
+		httpStatus:        status,
+	}
+
+	for _, m := range d.Members {
+		response.Members = append(response.Members, HouseholdMemberResponse{
+			UserUUID: m.UserUUID,
+			Name:     m.Name,
+			Email:    m.Email,
+			Role:     m.Role.String(),
+			JoinedAt: m.CreatedAt,
+		})
+	}
+
+	return response
+}
+
+func newInvitationResponse(i *household.Invitation, status int) InvitationResponse {
+	return InvitationResponse{
+		UUID:          i.UUID,
+		HouseholdUUID: i.HouseholdUUID,
+		HouseholdName: i.HouseholdName,
+		Email:         i.Email,
+		Status:        i.Status.String(),
+		ExpiresAt:     i.ExpiresAt,
+		CreatedAt:     i.CreatedAt,
+		httpStatus:    status,
+	}
+}
+
+func (h *HttpServer) registerHouseholdRoutes(r chi.Router) {
+	r.Route("/households", func(r chi.Router) {
+		r.Use(h.rateLimit("households"))
+		r.Get("/", h.listHouseholds)
+		r.Post("/", h.createHousehold)
+		r.Put("/active", h.setActiveHousehold)
+		r.Get("/{uuid}", h.getHousehold)
+		r.Post("/{uuid}/invitations", h.inviteToHousehold)
+		r.Post("/{uuid}/leave", h.leaveHousehold)
+	})
+
+	r.Route("/invitations", func(r chi.Router) {
+		r.Use(h.rateLimit("invitations"))
+		r.Get("/", h.listInvitations)
+		r.Post("/{uuid}/accept", h.acceptInvitation)
+		r.Post("/{uuid}/decline", h.declineInvitation)
+	})
+}
+
+func (h *HttpServer) listHouseholds(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.PROFILE_READ, w, r)
+	if !ok {
+		return
+	}
+
+	households, err := h.app.GetHouseholdService().List(r.Context(), access.UserId)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := &HouseholdListResponse{Households: make([]HouseholdResponse, 0, len(households))}
+	for _, household := range households {
+		response.Households = append(response.Households, newHouseholdResponse(household))
+	}
+
+	render.Render(w, r, response)
+}
+
+func (h *HttpServer) createHousehold(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.HOUSEHOLDS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request CreateHouseholdRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	details, err := h.app.GetHouseholdService().Create(r.Context(), access.UserId, request.Name)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	render.Render(w, r, newHouseholdDetailsResponse(details, http.StatusCreated))
+}
+
+func (h *HttpServer) getHousehold(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.PROFILE_READ, w, r)
+	if !ok {
+		return
+	}
+
+	householdUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("household-not-found", err, w, r)
+		return
+	}
+
+	details, err := h.app.GetHouseholdService().Get(r.Context(), access.UserId, householdUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	render.Render(w, r, newHouseholdDetailsResponse(details, http.StatusOK))
+}
+
+func (h *HttpServer) inviteToHousehold(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.HOUSEHOLDS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	householdUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("household-not-found", err, w, r)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request InviteRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	invitation, err := h.app.GetHouseholdService().Invite(r.Context(), &household.InviteRequest{
+		InviterUUID:   access.UserId,
+		HouseholdUUID: householdUUID,
+		Email:         request.Email,
+	})
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := newInvitationResponse(invitation, http.StatusCreated)
+	render.Render(w, r, &response)
+}
+
+func (h *HttpServer) leaveHousehold(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.HOUSEHOLDS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	householdUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("household-not-found", err, w, r)
+		return
+	}
+
+	err = h.app.GetHouseholdService().Leave(r.Context(), access.UserId, householdUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HttpServer) setActiveHousehold(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.HOUSEHOLDS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request ActiveHouseholdRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	householdUUID := uuid.Nil
+	if request.HouseholdUUID != nil {
+		householdUUID = *request.HouseholdUUID
+	}
+
+	err = h.app.GetHouseholdService().SetActive(r.Context(), access.UserId, householdUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HttpServer) listInvitations(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.PROFILE_READ, w, r)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.app.GetHouseholdService().ListInvitations(r.Context(), access.UserId)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := &InvitationListResponse{Invitations: make([]InvitationResponse, 0, len(invitations))}
+	for _, invitation := range invitations {
+		response.Invitations = append(response.Invitations, newInvitationResponse(invitation, http.StatusOK))
+	}
+
+	render.Render(w, r, response)
+}
+
+func (h *HttpServer) acceptInvitation(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.HOUSEHOLDS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	invitationUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("household-invitation-not-found", err, w, r)
+		return
+	}
+
+	details, err := h.app.GetHouseholdService().AcceptInvitation(r.Context(), access.UserId, invitationUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	render.Render(w, r, newHouseholdDetailsResponse(details, http.StatusOK))
+}
+
+func (h *HttpServer) declineInvitation(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.HOUSEHOLDS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	invitationUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("household-invitation-not-found", err, w, r)
+		return
+	}
+
+	err = h.app.GetHouseholdService().DeclineInvitation(r.Context(), access.UserId, invitationUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,333 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/household"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_createHousehold(t *testing.T) {
+	userUUID := uuid.New()
+	householdUUID := uuid.New()
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	details := &household.Details{
+		Household: &household.Household{UUID: householdUUID, Name: "Home", OwnerUUID: userUUID, CreatedAt: createdAt, UpdatedAt: createdAt},
+		Members: []*household.Member{
+			{HouseholdUUID: householdUUID, UserUUID: userUUID, Name: "Owner", Email: "owner@example.com", Role: household.OWNER, CreatedAt: createdAt},
+		},
+	}
+
+	tt := []struct {
+		name       string
+		body       string
+		scopes     []scope.Scope
+		create     bool
+		want       string
+		statusCode int
+	}{
+		{
+			name:       "Household is created",
+			body:       `{"name":"Home"}`,
+			scopes:     scope.Full(),
+			create:     true,
+			want:       `{"uuid":"` + householdUUID.String() + `","name":"Home","owner_uuid":"` + userUUID.String() + `","created_at":"2026-10-18T12:00:00Z","members":[{"user_uuid":"` + userUUID.String() + `","name":"Owner","email":"owner@example.com","role":"owner","joined_at":"2026-10-18T12:00:00Z"}]}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "Name is required",
+			body:       `{"name":""}`,
+			scopes:     scope.Full(),
+			want:       `{"slug":"field-name-required"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Read only token",
+			body:       `{"name":"Home"}`,
+			scopes:     []scope.Scope{scope.PROFILE_READ},
+			want:       `{"slug":"insufficient-scope"}`,
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/households", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer access")
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: tc.scopes}, nil)
+
+			householdMock := new(mocks.HouseholdServiceMock)
+			householdMock.On("Create", mock.Anything, userUUID, "Home").Return(details, nil)
+
+			server := NewHttpServer(mocks.NewAppMock(nil).SetAuthService(authMock).SetHouseholdService(householdMock))
+
+			server.createHousehold(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+
+			if !tc.create {
+				householdMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func Test_inviteToHousehold(t *testing.T) {
+	userUUID := uuid.New()
+	householdUUID := uuid.New()
+	invitationUUID := uuid.New()
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name            string
+		household       string
+		body            string
+		serviceRequest  *household.InviteRequest
+		serviceResponse *household.Invitation
+		serviceError    error
+		want            string
+		statusCode      int
+	}{
+		{
+			name:           "Invitation is created",
+			household:      householdUUID.String(),
+			body:           `{"email":"guest@example.com"}`,
+			serviceRequest: &household.InviteRequest{InviterUUID: userUUID, HouseholdUUID: householdUUID, Email: "guest@example.com"},
+			serviceResponse: &household.Invitation{
+				UUID:          invitationUUID,
+				HouseholdUUID: householdUUID,
+				HouseholdName: "Home",
+				Email:         "guest@example.com",
+				Status:        household.PENDING,
+				ExpiresAt:     createdAt.Add(7 * 24 * time.Hour),
+				CreatedAt:     createdAt,
+			},
+			want:       `{"uuid":"` + invitationUUID.String() + `","household_uuid":"` + householdUUID.String() + `","household_name":"Home","email":"guest@example.com","status":"pending","expires_at":"2026-10-25T12:00:00Z","created_at":"2026-10-18T12:00:00Z"}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:            "Only the owner invites",
+			household:       householdUUID.String(),
+			body:            `{"email":"guest@example.com"}`,
+			serviceRequest:  &household.InviteRequest{InviterUUID: userUUID, HouseholdUUID: householdUUID, Email: "guest@example.com"},
+			serviceResponse: &household.Invitation{},
+			serviceError:    apperrors.NewForbiddenError("Only the owner can invite members", "household-owner-required"),
+			want:            `{"slug":"household-owner-required"}`,
+			statusCode:      http.StatusForbidden,
+		},
+		{
+			name:       "Invalid email",
+			household:  householdUUID.String(),
+			body:       `{"email":"guest"}`,
+			want:       `{"slug":"field-email-invalid"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Malformed household",
+			household:  "home",
+			body:       `{"email":"guest@example.com"}`,
+			want:       `{"slug":"household-not-found"}`,
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/households/"+tc.household+"/invitations", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer access")
+			request = withURLParams(request, map[string]string{"uuid": tc.household})
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()}, nil)
+
+			householdMock := new(mocks.HouseholdServiceMock)
+			householdMock.On("Invite", mock.Anything, tc.serviceRequest).Return(tc.serviceResponse, tc.serviceError)
+
+			server := NewHttpServer(mocks.NewAppMock(nil).SetAuthService(authMock).SetHouseholdService(householdMock))
+
+			server.inviteToHousehold(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_leaveHousehold(t *testing.T) {
+	userUUID := uuid.New()
+	householdUUID := uuid.New()
+
+	tt := []struct {
+		name         string
+		serviceError error
+		want         string
+		statusCode   int
+	}{
+		{name: "Member leaves", statusCode: http.StatusNoContent},
+		{
+			name:         "Owner of members",
+			serviceError: apperrors.NewIncorrectInputError("Owner can not leave a household with members", "household-owner-cannot-leave"),
+			want:         `{"slug":"household-owner-cannot-leave"}`,
+			statusCode:   http.StatusBadRequest,
+		},
+		{
+			name:         "Not a member",
+			serviceError: apperrors.NewNotFoundError("Not found", "household-not-found"),
+			want:         `{"slug":"household-not-found"}`,
+			statusCode:   http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/households/"+householdUUID.String()+"/leave", nil)
+			request.Header.Set("Authorization", "Bearer access")
+			request = withURLParams(request, map[string]string{"uuid": householdUUID.String()})
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()}, nil)
+
+			householdMock := new(mocks.HouseholdServiceMock)
+			householdMock.On("Leave", mock.Anything, userUUID, householdUUID).Return(tc.serviceError)
+
+			server := NewHttpServer(mocks.NewAppMock(nil).SetAuthService(authMock).SetHouseholdService(householdMock))
+
+			server.leaveHousehold(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_acceptInvitation(t *testing.T) {
+	userUUID := uuid.New()
+	householdUUID := uuid.New()
+	invitationUUID := uuid.New()
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	details := &household.Details{
+		Household: &household.Household{UUID: householdUUID, Name: "Home", OwnerUUID: uuid.Nil, CreatedAt: createdAt},
+		Members:   []*household.Member{},
+	}
+
+	tt := []struct {
+		name         string
+		serviceError error
+		want         string
+		statusCode   int
+	}{
+		{
+			name:       "Invitation is accepted",
+			want:       `{"uuid":"` + householdUUID.String() + `","name":"Home","owner_uuid":"00000000-0000-0000-0000-000000000000","created_at":"2026-10-18T12:00:00Z","members":[]}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:         "Expired invitation",
+			serviceError: apperrors.NewIncorrectInputError("Invitation expired", "household-invitation-expired"),
+			want:         `{"slug":"household-invitation-expired"}`,
+			statusCode:   http.StatusBadRequest,
+		},
+		{
+			name:         "Invitation of someone else",
+			serviceError: apperrors.NewNotFoundError("Invitation not found", "household-invitation-not-found"),
+			want:         `{"slug":"household-invitation-not-found"}`,
+			statusCode:   http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/invitations/"+invitationUUID.String()+"/accept", nil)
+			request.Header.Set("Authorization", "Bearer access")
+			request = withURLParams(request, map[string]string{"uuid": invitationUUID.String()})
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()}, nil)
+
+			householdMock := new(mocks.HouseholdServiceMock)
+			householdMock.On("AcceptInvitation", mock.Anything, userUUID, invitationUUID).Return(details, tc.serviceError)
+
+			server := NewHttpServer(mocks.NewAppMock(nil).SetAuthService(authMock).SetHouseholdService(householdMock))
+
+			server.acceptInvitation(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_setActiveHousehold(t *testing.T) {
+	userUUID := uuid.New()
+	householdUUID := uuid.New()
+
+	tt := []struct {
+		name       string
+		body       string
+		household  uuid.UUID
+		statusCode int
+	}{
+		{name: "Household is selected", body: `{"household_uuid":"` + householdUUID.String() + `"}`, household: householdUUID, statusCode: http.StatusNoContent},
+		{name: "Selection is cleared", body: `{"household_uuid":null}`, household: uuid.Nil, statusCode: http.StatusNoContent},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/api/v1/households/active", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer access")
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()}, nil)
+
+			householdMock := new(mocks.HouseholdServiceMock)
+			householdMock.On("SetActive", mock.Anything, userUUID, tc.household).Return(nil)
+
+			server := NewHttpServer(mocks.NewAppMock(nil).SetAuthService(authMock).SetHouseholdService(householdMock))
+
+			server.setActiveHousehold(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			householdMock.AssertCalled(t, "SetActive", mock.Anything, userUUID, tc.household)
+		})
+	}
+}
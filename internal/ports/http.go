@@ -1,11 +1,15 @@
 package ports
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,27 +21,41 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/ibgl/microservice-users/internal/app"
+	"github.com/ibgl/microservice-users/internal/app/email"
 	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
 	auth "github.com/ibgl/microservice-users/internal/app/user"
 )
 
 type HttpServer struct {
 	app       app.Application
 	validator *validator.Validate
+	// trustedProxies may forward the client address in headers, other peers are the client themselves
+	trustedProxies []*net.IPNet
 }
 
 const DEFAULT_PORT = "8088"
 
+// shutdownTimeout is how long the requests in flight may take to finish once the server stops
+const shutdownTimeout = 10 * time.Second
+
 func NewHttpServer(app app.Application) *HttpServer {
 	validate := validator.New()
 
-	return &HttpServer{
+	h := &HttpServer{
 		app:       app,
 		validator: validate,
 	}
+
+	if config := app.GetConfig(); config != nil {
+		h.trustedProxies = parseTrustedProxies(config.TrustedProxies, app.GetLogger())
+	}
+
+	return h
 }
 
-func (h *HttpServer) Start() {
+// Start serves until the context is done, the requests in flight are then given shutdownTimeout to finish
+func (h *HttpServer) Start(ctx context.Context) {
 	port := DEFAULT_PORT
 	if os.Getenv("APP_PORT") != "" {
 		port = os.Getenv("APP_PORT")
@@ -48,28 +66,71 @@ func (h *HttpServer) Start() {
 	h.registerMiddlewares(r)
 	h.registerRoutes(r)
 
+	server := &http.Server{Addr: fmt.Sprintf(":%s", port), Handler: r}
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			h.app.GetLogger().Errorf("Server shutdown error %v", err)
+		}
+	}()
+
 	h.app.GetLogger().Printf("Server started on %s", port)
-	http.ListenAndServe(fmt.Sprintf(":%s", port), r)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		h.app.GetLogger().Errorf("Server error %v", err)
+	}
 }
 
 func (h *HttpServer) registerMiddlewares(r *chi.Mux) {
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(h.realIP)
+	r.Use(clientInfo)
 	r.Use(middleware.Logger)
 	r.Use(chilogger.Logger("router", h.app.GetLogger()))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
 	r.Use(render.SetContentType(render.ContentTypeJSON))
-	r.Use(cors.Handler(cors.Options{
-		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
-		AllowedOrigins: []string{"*"},
-		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}))
+	r.Use(cors.Handler(h.corsOptions()))
+}
+
+// realIP replaces RemoteAddr with the client address forwarded by a trusted proxy, like middleware.RealIP
+// for the configured proxies only
+func (h *HttpServer) realIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer := clientIP(r)
+		if ip := forwardedClientIP(peer, r.Header.Get, h.trustedProxies); ip != peer {
+			r.RemoteAddr = ip
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientInfo passes the client address, user agent, request id and language to the application layer,
+// RequestID and realIP must run before it
+func clientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := auth.WithClientInfo(r.Context(), auth.ClientInfo{
+			IP:        clientIP(r),
+			UserAgent: r.UserAgent(),
+			RequestID: middleware.GetReqID(r.Context()),
+			Locale:    email.ParseAcceptLanguage(r.Header.Get("Accept-Language")),
+		})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
 }
 
 func (h *HttpServer) registerRoutes(r *chi.Mux) {
@@ -77,36 +138,82 @@ func (h *HttpServer) registerRoutes(r *chi.Mux) {
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 			render.JSON(w, r, map[string]string{"status": "ok"})
 		})
+		r.Get("/metrics", h.metrics)
+
+		r.With(h.rateLimit("signIn")).Post("/signIn", h.signIn)
+		r.With(h.rateLimit("signIn-report")).Post("/signIn/report", h.reportSignIn)
+		r.With(h.rateLimit("signUp")).Post("/signUp", h.signUp)
+		r.With(h.rateLimit("signUp-confirm")).Post("/signUp/confirm", h.confirmSignUp)
+		r.Route("/refresh", func(r chi.Router) {
+			r.Use(h.rateLimit("refresh"), h.csrf)
+			r.Post("/", h.refresh)
+			r.Delete("/", h.signOut)
+		})
 
-		r.Post("/signIn", h.signIn)
-		r.Post("/signUp", h.signUp)
-		r.Post("/refresh", h.refresh)
+		r.With(h.rateLimit("google-signIn")).Post("/google-signIn", h.googleSignIn)
 
-		r.Post("/google-signIn", h.googleSignIn)
+		r.With(h.rateLimit("me")).Get("/me", h.me)
+		r.With(h.rateLimit("me")).Get("/me/activity", h.myActivity)
+		r.With(h.rateLimit("settings")).Put("/settings", h.updateSettings)
+
+		h.registerPasswordRoutes(r)
+
+		h.registerHouseholdRoutes(r)
+
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(h.rateLimit("tokens"))
+			r.Get("/", h.listTokens)
+			r.Post("/", h.createToken)
+			r.Delete("/{uuid}", h.revokeToken)
+		})
 
-		r.Get("/me", h.me)
-		r.Put("/settings", h.updateSettings)
+		r.With(h.rateLimit("oauth-token")).Post("/oauth/token", h.oauthToken)
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(h.rateLimit("admin"))
+			r.Post("/impersonate", h.impersonate)
+			r.Get("/activity", h.listAuthEvents)
+
+			r.Get("/clients", h.listClients)
+			r.Post("/clients", h.createClient)
+			r.Delete("/clients/{clientId}", h.deleteClient)
+
+			h.registerWebhookRoutes(r)
+		})
+
+		r.With(h.rateLimit("users-batch")).Post("/internal/users:batchGet", h.batchGetUsers)
 	})
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,gte=5"`
+	Email        string `json:"email" validate:"required,email"`
+	Password     string `json:"password" validate:"required,gte=5"`
+	CaptchaToken string `json:"captcha_token"`
 }
 
+// RefreshRequest carries the refresh token in the body, in cookie mode it comes from the cookie instead
 type RefreshRequest struct {
-	Refresh string `json:"refresh" validate:"required"`
+	Refresh string `json:"refresh"`
 }
 
 type SignUpRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,gte=5"`
-	Name     string `json:"name" validate:"required,gte=1"`
+	Email        string `json:"email" validate:"required,email"`
+	Password     string `json:"password" validate:"required"`
+	Name         string `json:"name" validate:"required,gte=1"`
+	CaptchaToken string `json:"captcha_token"`
+}
+
+type ConfirmSignUpRequest struct {
+	SignUpToken string `json:"token" validate:"required"`
+}
+
+type ReportSignInRequest struct {
+	ReportToken string `json:"token" validate:"required"`
 }
 
 type TokenPairResponse struct {
 	Access  string `json:"access"`
-	Refresh string `json:"refresh"`
+	Refresh string `json:"refresh,omitempty"`
 }
 
 type UserResponse struct {
@@ -126,6 +233,62 @@ type GoogleSignInRequest struct {
 	Credential string `json:"credential"`
 }
 
+type ImpersonateRequest struct {
+	UserUUID uuid.UUID `json:"user_uuid" validate:"required"`
+	Reason   string    `json:"reason" validate:"required,gte=1,lte=500"`
+}
+
+type ImpersonationResponse struct {
+	Access    string    `json:"access"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (e *ImpersonationResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+type CreateTokenRequest struct {
+	Name          string   `json:"name" validate:"required,gte=1,lte=100"`
+	Scopes        []string `json:"scopes" validate:"required,gte=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"gte=0,lte=365"`
+}
+
+type PersonalTokenResponse struct {
+	UUID       uuid.UUID  `json:"uuid"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+type PersonalTokenListResponse struct {
+	Tokens []PersonalTokenResponse `json:"tokens"`
+}
+
+func (e *PersonalTokenResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 201)
+	return nil
+}
+
+func (e *PersonalTokenListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+func newPersonalTokenResponse(t *auth.PersonalAccessToken) PersonalTokenResponse {
+	return PersonalTokenResponse{
+		UUID:       t.UUID,
+		Name:       t.Name,
+		Scopes:     scope.ToStrings(t.Scopes),
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
 func (e *TokenPairResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	render.Status(r, 200)
 	return nil
@@ -151,8 +314,9 @@ func (h *HttpServer) signIn(w http.ResponseWriter, r *http.Request) {
 	}
 
 	serviceRequest := &auth.SignInRequest{
-		Email:    request.Email,
-		Password: request.Password,
+		Email:        request.Email,
+		Password:     request.Password,
+		CaptchaToken: request.CaptchaToken,
 	}
 
 	tokens, err := h.app.GetAuthService().SignIn(r.Context(), serviceRequest)
@@ -161,28 +325,15 @@ func (h *HttpServer) signIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	render.Render(w, r, &TokenPairResponse{
-		Access:  tokens.Access.Value,
-		Refresh: tokens.Refresh.Value,
-	})
+	h.respondTokens(tokens, w, r)
 }
 
 func (h *HttpServer) RespondValidationError(errs []validator.FieldError, w http.ResponseWriter, r *http.Request) {
-	for _, err := range errs {
-		fmt.Println(err.Namespace())
-		fmt.Println(err.Field())
-		fmt.Println(err.StructNamespace())
-		fmt.Println(err.StructField())
-		fmt.Println(err.Tag())
-		fmt.Println(err.ActualTag())
-		fmt.Println(err.Kind())
-		fmt.Println(err.Type())
-		fmt.Println(err.Value())
-		fmt.Println(err.Param())
-	}
-
-	err := errs[0]
+	h.BadRequest(validationSlug(errs[0]), errs[0], w, r)
+}
 
+// validationSlug names the first failed rule of a request for the client
+func validationSlug(err validator.FieldError) string {
 	slug := "invalid-input"
 	if err.Field() == "Password" && err.Tag() == "gte" {
 		slug = "field-password-invalid-length"
@@ -200,9 +351,31 @@ func (h *HttpServer) RespondValidationError(errs []validator.FieldError, w http.
 		slug = "invalid-token"
 	} else if err.Field() == "Currency" && err.Tag() == "required" {
 		slug = "field-currency-required"
+	} else if err.Field() == "UserUUID" && err.Tag() == "required" {
+		slug = "field-user-uuid-required"
+	} else if err.Field() == "Reason" {
+		slug = "field-reason-invalid"
+	} else if err.Field() == "Name" && err.Tag() == "lte" {
+		slug = "field-name-invalid-length"
+	} else if err.Field() == "Scopes" {
+		slug = "field-scopes-invalid"
+	} else if err.Field() == "UUIDs" && err.Tag() == "required" {
+		slug = "field-uuids-required"
+	} else if err.Field() == "ExpiresInDays" {
+		slug = "field-expires-in-invalid"
+	} else if err.Field() == "CurrentPassword" && err.Tag() == "required" {
+		slug = "field-current-password-required"
+	} else if err.Field() == "NewPassword" && err.Tag() == "required" {
+		slug = "field-password-required"
+	} else if err.Field() == "Token" && err.Tag() == "required" {
+		slug = "password-reset-token-invalid"
+	} else if err.Field() == "SignUpToken" && err.Tag() == "required" {
+		slug = "sign-up-token-invalid"
+	} else if err.Field() == "ReportToken" && err.Tag() == "required" {
+		slug = "sign-in-report-token-invalid"
 	}
 
-	h.BadRequest(slug, err, w, r)
+	return slug
 }
 
 func (h *HttpServer) signUp(w http.ResponseWriter, r *http.Request) {
@@ -226,9 +399,21 @@ func (h *HttpServer) signUp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	serviceRequest := &auth.SignUpRequest{
-		Email:    request.Email,
-		Password: request.Password,
-		Name:     request.Name,
+		Email:        request.Email,
+		Password:     request.Password,
+		Name:         request.Name,
+		CaptchaToken: request.CaptchaToken,
+	}
+
+	if h.app.GetConfig().SignUpMode == app.SignUpModeConfirm {
+		if err := h.app.GetAuthService().RequestSignUp(r.Context(), serviceRequest); err != nil {
+			h.RespondWithAppError(err, w, r)
+			return
+		}
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, map[string]string{"status": "check-email"})
+		return
 	}
 
 	tokens, err := h.app.GetAuthService().SignUp(r.Context(), serviceRequest)
@@ -237,16 +422,71 @@ func (h *HttpServer) signUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	render.Render(w, r, &TokenPairResponse{
-		Access:  tokens.Access.Value,
-		Refresh: tokens.Refresh.Value,
-	})
+	h.respondTokens(tokens, w, r)
 }
 
-func (h *HttpServer) updateSettings(w http.ResponseWriter, r *http.Request) {
-	access, err := h.getAccessFromHeader(w, r)
+func (h *HttpServer) confirmSignUp(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		h.Unauthorised("invalid-token", err, w, r)
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request ConfirmSignUpRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	tokens, err := h.app.GetAuthService().ConfirmSignUp(r.Context(), request.SignUpToken)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	h.respondTokens(tokens, w, r)
+}
+
+// reportSignIn is the "this wasn't me" link of new sign in emails
+func (h *HttpServer) reportSignIn(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request ReportSignInRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	err = h.app.GetAuthService().ReportSignIn(r.Context(), request.ReportToken)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HttpServer) updateSettings(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.SETTINGS_WRITE, w, r)
+	if !ok {
 		return
 	}
 
@@ -295,9 +535,8 @@ func (h *HttpServer) updateSettings(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *HttpServer) me(w http.ResponseWriter, r *http.Request) {
-	access, err := h.getAccessFromHeader(w, r)
-	if err != nil {
-		h.Unauthorised("invalid-token", err, w, r)
+	access, ok := h.authorize(scope.PROFILE_READ, w, r)
+	if !ok {
 		return
 	}
 
@@ -328,6 +567,110 @@ func (h *HttpServer) refresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request RefreshRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			h.BadRequest("invalid-input", err, w, r)
+			return
+		}
+	}
+
+	token := h.refreshToken(request.Refresh, r)
+	if token == "" {
+		h.BadRequest("invalid-token", apperrors.NewIncorrectInputError("Refresh token is required", "invalid-token"), w, r)
+		return
+	}
+
+	tokens, err := h.app.GetAuthService().Refresh(r.Context(), token)
+	if err != nil {
+		if h.cookieMode() {
+			h.clearSessionCookies(w)
+		}
+
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	h.respondTokens(tokens, w, r)
+}
+
+// signOut revokes the refresh token and, in cookie mode, clears the session cookies
+func (h *HttpServer) signOut(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request RefreshRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			h.BadRequest("invalid-input", err, w, r)
+			return
+		}
+	}
+
+	if token := h.refreshToken(request.Refresh, r); token != "" {
+		if err := h.app.GetAuthService().SignOut(r.Context(), token); err != nil {
+			h.RespondWithAppError(err, w, r)
+			return
+		}
+	}
+
+	if h.cookieMode() {
+		h.clearSessionCookies(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HttpServer) googleSignIn(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request GoogleSignInRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	tokens, err := h.app.GetAuthService().GoogleSignIn(r.Context(), request.Credential)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	h.respondTokens(tokens, w, r)
+}
+
+func (h *HttpServer) impersonate(w http.ResponseWriter, r *http.Request) {
+	access, err := h.getAccessFromHeader(w, r)
+	if err != nil {
+		h.Unauthorised("invalid-token", err, w, r)
+		return
+	}
+
+	if access.IsImpersonated() {
+		h.Forbidden("impersonation-nested", apperrors.NewForbiddenError("Impersonated sessions can not impersonate", "impersonation-nested"), w, r)
+		return
+	}
+
+	if !h.requireScope(access, scope.ADMIN, w, r) {
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request ImpersonateRequest
 	if err := json.Unmarshal(body, &request); err != nil {
 		h.BadRequest("invalid-input", err, w, r)
 		return
@@ -339,19 +682,48 @@ func (h *HttpServer) refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.app.GetAuthService().Refresh(r.Context(), request.Refresh)
+	impersonation, err := h.app.GetAuthService().Impersonate(r.Context(), &auth.ImpersonateRequest{
+		ActorUUID:  access.UserId,
+		TargetUUID: request.UserUUID,
+		Reason:     request.Reason,
+	})
 	if err != nil {
 		h.RespondWithAppError(err, w, r)
 		return
 	}
 
-	render.Render(w, r, &TokenPairResponse{
-		Access:  tokens.Access.Value,
-		Refresh: tokens.Refresh.Value,
+	render.Render(w, r, &ImpersonationResponse{
+		Access:    impersonation.Access.Value,
+		ExpiresAt: impersonation.ExpiresAt,
 	})
 }
 
-func (h *HttpServer) googleSignIn(w http.ResponseWriter, r *http.Request) {
+func (h *HttpServer) listTokens(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.TOKENS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.app.GetAuthService().ListPersonalAccessTokens(r.Context(), access.UserId)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := &PersonalTokenListResponse{Tokens: make([]PersonalTokenResponse, 0, len(tokens))}
+	for _, t := range tokens {
+		response.Tokens = append(response.Tokens, newPersonalTokenResponse(t))
+	}
+
+	render.Render(w, r, response)
+}
+
+func (h *HttpServer) createToken(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.TOKENS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
 	defer r.Body.Close()
 	body, err := ioutil.ReadAll(r.Body) // response body is []byte
 	if err != nil {
@@ -359,25 +731,96 @@ func (h *HttpServer) googleSignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var request GoogleSignInRequest
+	var request CreateTokenRequest
 	if err := json.Unmarshal(body, &request); err != nil {
 		h.BadRequest("invalid-input", err, w, r)
 		return
 	}
 
-	tokens, err := h.app.GetAuthService().GoogleSignIn(r.Context(), request.Credential)
+	err = h.validator.Struct(request)
 	if err != nil {
-		h.RespondWithAppError(err, w, r)
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
 		return
 	}
 
-	render.Render(w, r, &TokenPairResponse{
-		Access:  tokens.Access.Value,
-		Refresh: tokens.Refresh.Value,
+	created, err := h.app.GetAuthService().CreatePersonalAccessToken(r.Context(), &auth.CreatePersonalAccessTokenRequest{
+		UserUUID:  access.UserId,
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+		ExpiresIn: time.Duration(request.ExpiresInDays) * 24 * time.Hour,
 	})
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := newPersonalTokenResponse(created.Token)
+	response.Token = created.Value
+
+	render.Render(w, r, &response)
+}
+
+func (h *HttpServer) revokeToken(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.TOKENS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	tokenUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("token-not-found", err, w, r)
+		return
+	}
+
+	err = h.app.GetAuthService().RevokePersonalAccessToken(r.Context(), access.UserId, tokenUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorize reads the bearer token and checks that it was granted the scope required by the handler
+func (h *HttpServer) authorize(required scope.Scope, w http.ResponseWriter, r *http.Request) (auth.Token, bool) {
+	access, err := h.getAccessFromHeader(w, r)
+	if err != nil {
+		h.Unauthorised("invalid-token", err, w, r)
+		return auth.Token{}, false
+	}
+
+	if !h.requireScope(access, required, w, r) {
+		return auth.Token{}, false
+	}
+
+	return access, true
+}
+
+func (h *HttpServer) requireScope(access auth.Token, required scope.Scope, w http.ResponseWriter, r *http.Request) bool {
+	if access.HasScope(required) {
+		return true
+	}
+
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, required.String()))
+	h.Forbidden("insufficient-scope", apperrors.NewForbiddenError("Token lacks "+required.String()+" scope", "insufficient-scope"), w, r)
+	return false
 }
 
 func (h *HttpServer) getAccessFromHeader(w http.ResponseWriter, r *http.Request) (auth.Token, error) {
+	access, err := h.getTokenFromHeader(r)
+	if err != nil {
+		return auth.Token{}, err
+	}
+
+	if access.IsService() {
+		return auth.Token{}, apperrors.NewAuthorizationError("Service tokens are not allowed for user endpoints", "user-token-required")
+	}
+
+	return access, nil
+}
+
+// getTokenFromHeader validates the bearer token of any kind, user or service
+func (h *HttpServer) getTokenFromHeader(r *http.Request) (auth.Token, error) {
 	reqToken := r.Header.Get("Authorization")
 	splitToken := strings.Split(reqToken, "Bearer ")
 	if len(splitToken) < 2 {
@@ -386,12 +829,7 @@ func (h *HttpServer) getAccessFromHeader(w http.ResponseWriter, r *http.Request)
 
 	tokenHeader := splitToken[1]
 
-	access, err := h.app.GetAuthService().ValidateToken(r.Context(), tokenHeader)
-	if err != nil {
-		return auth.Token{}, err
-	}
-
-	return access, nil
+	return h.app.GetAuthService().ValidateToken(r.Context(), tokenHeader)
 }
 
 func (h *HttpServer) InternalError(slug string, err error, w http.ResponseWriter, r *http.Request) {
@@ -403,6 +841,20 @@ func (h *HttpServer) Unauthorised(slug string, err error, w http.ResponseWriter,
 	h.httpRespondWithError(err, slug, w, r, "Unauthorised", http.StatusUnauthorized)
 }
 
+func (h *HttpServer) Forbidden(slug string, err error, w http.ResponseWriter, r *http.Request) {
+	h.httpRespondWithError(err, slug, w, r, "Forbidden", http.StatusForbidden)
+}
+
+func (h *HttpServer) TooManyRequests(slug string, retryAfter time.Duration, err error, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	h.httpRespondWithError(err, slug, w, r, "Too many requests", http.StatusTooManyRequests)
+}
+
+func (h *HttpServer) ServiceUnavailable(slug string, retryAfter time.Duration, err error, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	h.httpRespondWithError(err, slug, w, r, "Service unavailable", http.StatusServiceUnavailable)
+}
+
 func (h *HttpServer) BadRequest(slug string, err error, w http.ResponseWriter, r *http.Request) {
 	h.httpRespondWithError(err, slug, w, r, "Bad request", http.StatusBadRequest)
 }
@@ -423,6 +875,12 @@ func (h *HttpServer) RespondWithAppError(err error, w http.ResponseWriter, r *ht
 		h.Unauthorised(appError.Slug(), appError, w, r)
 	case apperrors.ErrorTypeIncorrectInput:
 		h.BadRequest(appError.Slug(), appError, w, r)
+	case apperrors.ErrorTypeForbidden:
+		h.Forbidden(appError.Slug(), appError, w, r)
+	case apperrors.ErrorTypeTooManyRequests:
+		h.TooManyRequests(appError.Slug(), appError.RetryAfter(), appError, w, r)
+	case apperrors.ErrorTypeUnavailable:
+		h.ServiceUnavailable(appError.Slug(), appError.RetryAfter(), appError, w, r)
 	case apperrors.ErrorNotFound:
 		h.NotFound(appError.Slug(), appError, w, r)
 	default:
@@ -5,9 +5,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/app"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
 	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/scope"
 	"github.com/ibgl/microservice-users/internal/app/user"
 	"github.com/stretchr/testify/mock"
 )
@@ -23,6 +27,7 @@ func Test_signIn(t *testing.T) {
 		body            string
 		want            string
 		statusCode      int
+		retryAfter      string
 	}{
 		{
 			name:   "With a valid username and password",
@@ -46,6 +51,49 @@ func Test_signIn(t *testing.T) {
 			want:         `{"access":"access","refresh":"refresh"}`,
 			statusCode:   http.StatusOK,
 		},
+		{
+			name:   "With a locked account",
+			method: http.MethodPost,
+			serviceRequest: &user.SignInRequest{
+				Email:    "email",
+				Password: "password",
+			},
+			serviceResponse: &user.LoginResponse{},
+			serviceError:    apperrors.NewTooManyRequestsError("Too many failed sign in attempts", "too-many-attempts", 1500*time.Millisecond),
+			body:            `{"email":"email","password":"password"}`,
+			want:            `{"slug":"too-many-attempts"}`,
+			statusCode:      http.StatusTooManyRequests,
+			retryAfter:      "2",
+		},
+		{
+			name:   "Without a captcha after failed attempts",
+			method: http.MethodPost,
+			serviceRequest: &user.SignInRequest{
+				Email:    "email",
+				Password: "password",
+			},
+			serviceResponse: &user.LoginResponse{},
+			serviceError:    apperrors.NewIncorrectInputError("Captcha is required", "captcha-required"),
+			body:            `{"email":"email","password":"password"}`,
+			want:            `{"slug":"captcha-required"}`,
+			statusCode:      http.StatusBadRequest,
+		},
+		{
+			name:   "With a captcha token",
+			method: http.MethodPost,
+			serviceRequest: &user.SignInRequest{
+				Email:        "email",
+				Password:     "password",
+				CaptchaToken: "solved",
+			},
+			serviceResponse: &user.LoginResponse{
+				Access:  user.Token{Value: "access"},
+				Refresh: user.Token{Value: "refresh"},
+			},
+			body:       `{"email":"email","password":"password","captcha_token":"solved"}`,
+			want:       `{"access":"access","refresh":"refresh"}`,
+			statusCode: http.StatusOK,
+		},
 	}
 
 	for _, tc := range tt {
@@ -66,9 +114,431 @@ func Test_signIn(t *testing.T) {
 				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
 			}
 
+			if responseRecorder.Header().Get("Retry-After") != tc.retryAfter {
+				t.Errorf("Want Retry-After '%s', got '%s'", tc.retryAfter, responseRecorder.Header().Get("Retry-After"))
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_impersonate(t *testing.T) {
+	adminUUID := uuid.New()
+	targetUUID := uuid.New()
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name            string
+		access          user.Token
+		serviceResponse *user.ImpersonationResponse
+		serviceError    error
+		body            string
+		want            string
+		statusCode      int
+	}{
+		{
+			name:   "Admin receives access token for target user",
+			access: user.Token{Value: "admin", UserId: adminUUID, Scopes: scope.Full()},
+			serviceResponse: &user.ImpersonationResponse{
+				Access:    user.Token{Value: "impersonated", UserId: targetUUID, ActorId: adminUUID},
+				ExpiresAt: expiresAt,
+			},
+			body:       `{"user_uuid":"` + targetUUID.String() + `","reason":"ticket 42"}`,
+			want:       `{"access":"impersonated","expires_at":"2030-01-01T00:00:00Z"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Impersonated session can not impersonate again",
+			access:     user.Token{Value: "impersonated", UserId: targetUUID, ActorId: adminUUID, Scopes: scope.Impersonation()},
+			body:       `{"user_uuid":"` + uuid.NewString() + `","reason":"ticket 42"}`,
+			want:       `{"slug":"impersonation-nested"}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:         "Non admin is refused",
+			access:       user.Token{Value: "user", UserId: targetUUID, Scopes: scope.Full()},
+			serviceError: apperrors.NewForbiddenError("Impersonation requires admin role", "admin-required"),
+			body:         `{"user_uuid":"` + adminUUID.String() + `","reason":"ticket 42"}`,
+			want:         `{"slug":"admin-required"}`,
+			statusCode:   http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/admin/impersonate", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer "+tc.access.Value)
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, tc.access.Value).Return(tc.access, nil)
+			if tc.serviceResponse == nil {
+				tc.serviceResponse = &user.ImpersonationResponse{}
+			}
+			authMock.On("Impersonate", mock.Anything, mock.Anything).Return(tc.serviceResponse, tc.serviceError)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			handler := server.impersonate
+			handler(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_createToken(t *testing.T) {
+	userUUID := uuid.New()
+	tokenUUID := uuid.New()
+	createdAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tt := []struct {
+		name       string
+		access     user.Token
+		body       string
+		want       string
+		statusCode int
+	}{
+		{
+			name:       "Token value is returned on creation",
+			access:     user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()},
+			body:       `{"name":"backup script","scopes":["profile:read"],"expires_in_days":30}`,
+			want:       `{"uuid":"` + tokenUUID.String() + `","name":"backup script","scopes":["profile:read"],"expires_at":"2030-01-31T00:00:00Z","last_used_at":null,"created_at":"2030-01-01T00:00:00Z","token":"pat_secret"}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "Personal access token can not create tokens",
+			access:     user.Token{Value: "pat_secret", UserId: userUUID, PersonalTokenId: tokenUUID, Scopes: scope.Delegable()},
+			body:       `{"name":"backup script","scopes":["profile:read"]}`,
+			want:       `{"slug":"insufficient-scope"}`,
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/tokens", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer "+tc.access.Value)
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, tc.access.Value).Return(tc.access, nil)
+			authMock.On("CreatePersonalAccessToken", mock.Anything, &user.CreatePersonalAccessTokenRequest{
+				UserUUID:  userUUID,
+				Name:      "backup script",
+				Scopes:    []string{"profile:read"},
+				ExpiresIn: 30 * 24 * time.Hour,
+			}).Return(&user.CreatedPersonalAccessToken{
+				Token: &user.PersonalAccessToken{
+					UUID:      tokenUUID,
+					UserUUID:  userUUID,
+					Name:      "backup script",
+					Scopes:    []scope.Scope{scope.PROFILE_READ},
+					ExpiresAt: createdAt.Add(30 * 24 * time.Hour),
+					CreatedAt: createdAt,
+				},
+				Value: "pat_secret",
+			}, nil)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			handler := server.createToken
+			handler(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_me(t *testing.T) {
+	userUUID := uuid.New()
+	tt := []struct {
+		name       string
+		access     user.Token
+		want       string
+		statusCode int
+	}{
+		{
+			name:       "Token with profile:read scope",
+			access:     user.Token{Value: "access", UserId: userUUID, Scopes: []scope.Scope{scope.PROFILE_READ}},
+			want:       `{"uuid":"` + userUUID.String() + `","email":"email","name":"name","settings":{"currency":"RUB","first_day_of_week":"MON","profile_picture_url":""}}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Token without profile:read scope",
+			access:     user.Token{Value: "pat_secret", UserId: userUUID, PersonalTokenId: uuid.New(), Scopes: []scope.Scope{scope.SETTINGS_WRITE}},
+			want:       `{"slug":"insufficient-scope"}`,
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+			request.Header.Set("Authorization", "Bearer "+tc.access.Value)
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, tc.access.Value).Return(tc.access, nil)
+			authMock.On("GetUser", mock.Anything, userUUID).Return(&user.User{
+				UUID:     userUUID,
+				Email:    "email",
+				Name:     "name",
+				Settings: user.DefaultUserSettings(),
+			}, nil)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			handler := server.me
+			handler(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_signUp(t *testing.T) {
+	serviceRequest := &user.SignUpRequest{
+		Email:    "new@example.com",
+		Password: "correct horse battery",
+		Name:     "Name",
+	}
+	tokens := &user.LoginResponse{
+		Access:  user.Token{Value: "access"},
+		Refresh: user.Token{Value: "refresh"},
+	}
+
+	tt := []struct {
+		name         string
+		signUpMode   string
+		body         string
+		serviceError error
+		want         string
+		statusCode   int
+	}{
+		{
+			name:       "Direct sign up signs in",
+			signUpMode: app.SignUpModeDirect,
+			body:       `{"email":"new@example.com","password":"correct horse battery","name":"Name"}`,
+			want:       `{"access":"access","refresh":"refresh"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:         "Direct sign up reports a registered email",
+			signUpMode:   app.SignUpModeDirect,
+			body:         `{"email":"new@example.com","password":"correct horse battery","name":"Name"}`,
+			serviceError: apperrors.NewIncorrectInputError("Email already in use", "field-email-invalid"),
+			want:         `{"slug":"field-email-invalid"}`,
+			statusCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "Confirmed sign up always asks to check the email",
+			signUpMode: app.SignUpModeConfirm,
+			body:       `{"email":"new@example.com","password":"correct horse battery","name":"Name"}`,
+			want:       `{"status":"check-email"}`,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:         "Confirmed sign up reports a weak password",
+			signUpMode:   app.SignUpModeConfirm,
+			body:         `{"email":"new@example.com","password":"correct horse battery","name":"Name"}`,
+			serviceError: apperrors.NewIncorrectInputError("Password is too weak", "field-password-too-weak"),
+			want:         `{"slug":"field-password-too-weak"}`,
+			statusCode:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/signUp", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("SignUp", mock.Anything, serviceRequest).Return(tokens, tc.serviceError)
+			authMock.On("RequestSignUp", mock.Anything, serviceRequest).Return(tc.serviceError)
+
+			app := mocks.NewAppMock(&app.Config{SignUpMode: tc.signUpMode}).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			server.signUp(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_confirmSignUp(t *testing.T) {
+	tt := []struct {
+		name            string
+		body            string
+		serviceResponse *user.LoginResponse
+		serviceError    error
+		want            string
+		statusCode      int
+	}{
+		{
+			name: "With a valid token",
+			body: `{"token":"valid"}`,
+			serviceResponse: &user.LoginResponse{
+				Access:  user.Token{Value: "access"},
+				Refresh: user.Token{Value: "refresh"},
+			},
+			want:       `{"access":"access","refresh":"refresh"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:            "With an expired token",
+			body:            `{"token":"valid"}`,
+			serviceResponse: &user.LoginResponse{},
+			serviceError:    apperrors.NewIncorrectInputError("Sign up token is invalid", "sign-up-token-invalid"),
+			want:            `{"slug":"sign-up-token-invalid"}`,
+			statusCode:      http.StatusBadRequest,
+		},
+		{
+			name:       "Without a token",
+			body:       `{}`,
+			want:       `{"slug":"sign-up-token-invalid"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/signUp/confirm", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ConfirmSignUp", mock.Anything, "valid").Return(tc.serviceResponse, tc.serviceError)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			server.confirmSignUp(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
 			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
 				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
 			}
 		})
 	}
 }
+
+func Test_reportSignIn(t *testing.T) {
+	tt := []struct {
+		name         string
+		body         string
+		serviceError error
+		want         string
+		statusCode   int
+	}{
+		{
+			name:       "With a valid token",
+			body:       `{"token":"valid"}`,
+			want:       ``,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:         "With a used token",
+			body:         `{"token":"valid"}`,
+			serviceError: apperrors.NewIncorrectInputError("Sign in report token is invalid", "sign-in-report-token-invalid"),
+			want:         `{"slug":"sign-in-report-token-invalid"}`,
+			statusCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "Without a token",
+			body:       `{}`,
+			want:       `{"slug":"sign-in-report-token-invalid"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/signIn/report", strings.NewReader(tc.body))
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ReportSignIn", mock.Anything, "valid").Return(tc.serviceError)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			server.reportSignIn(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_realIP(t *testing.T) {
+	server := NewHttpServer(mocks.NewAppMock(&app.Config{TrustedProxies: "10.0.0.0/8"}))
+
+	tt := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{name: "Client without headers", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "Untrusted peer can not forward", remoteAddr: "203.0.113.7:5000", headers: map[string]string{"X-Real-IP": "198.51.100.1", "X-Forwarded-For": "198.51.100.2"}, want: "203.0.113.7"},
+		{name: "Trusted proxy forwards X-Real-IP", remoteAddr: "10.1.2.3:5000", headers: map[string]string{"X-Real-IP": "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "Rotated hops left of the client are skipped", remoteAddr: "10.1.2.3:5000", headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.4.4.4"}, want: "198.51.100.1"},
+		{name: "Trusted proxy without headers", remoteAddr: "10.1.2.3:5000", want: "10.1.2.3"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+			request.RemoteAddr = tc.remoteAddr
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+
+			var got string
+			server.realIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), request)
+
+			if got != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
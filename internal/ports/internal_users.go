@@ -0,0 +1,106 @@
+package ports
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	auth "github.com/ibgl/microservice-users/internal/app/user"
+)
+
+type BatchGetUsersRequest struct {
+	UUIDs []uuid.UUID `json:"uuids" validate:"required"`
+}
+
+type ProfileResponse struct {
+	UUID              uuid.UUID `json:"uuid"`
+	Name              string    `json:"name"`
+	ProfilePictureUrl string    `json:"profile_picture_url"`
+}
+
+type BatchGetUsersResponse struct {
+	Users        []ProfileResponse `json:"users"`
+	MissingUUIDs []uuid.UUID       `json:"missing_uuids"`
+}
+
+func (e *BatchGetUsersResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+// authorizeService is authorize for the internal endpoints, only service account tokens are accepted there
+func (h *HttpServer) authorizeService(required scope.Scope, w http.ResponseWriter, r *http.Request) (auth.Token, bool) {
+	access, err := h.getTokenFromHeader(r)
+	if err != nil {
+		h.Unauthorised("invalid-token", err, w, r)
+		return auth.Token{}, false
+	}
+
+	if !access.IsService() {
+		h.Forbidden("service-token-required", apperrors.NewForbiddenError("Internal endpoints are for services", "service-token-required"), w, r)
+		return auth.Token{}, false
+	}
+
+	if !h.requireScope(access, required, w, r) {
+		return auth.Token{}, false
+	}
+
+	return access, true
+}
+
+// batchGetUsers looks up to auth.MaxBatchUsers users up in one query, the uuids not found are listed separately
+func (h *HttpServer) batchGetUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.authorizeService(scope.PROFILE_READ, w, r); !ok {
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request BatchGetUsersRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	users, err := h.app.GetAuthService().GetUsers(r.Context(), request.UUIDs)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := &BatchGetUsersResponse{Users: make([]ProfileResponse, 0, len(users)), MissingUUIDs: []uuid.UUID{}}
+
+	found := make(map[uuid.UUID]bool, len(users))
+	for _, u := range users {
+		found[u.UUID] = true
+		response.Users = append(response.Users, ProfileResponse{
+			UUID:              u.UUID,
+			Name:              u.Name,
+			ProfilePictureUrl: u.Settings.ProfilePictureUrl,
+		})
+	}
+
+	for _, userUUID := range request.UUIDs {
+		if !found[userUUID] {
+			response.MissingUUIDs = append(response.MissingUUIDs, userUUID)
+		}
+	}
+
+	render.Render(w, r, response)
+}
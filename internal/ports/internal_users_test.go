@@ -0,0 +1,117 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/role"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_batchGetUsers(t *testing.T) {
+	alice, bob, unknown := uuid.New(), uuid.New(), uuid.New()
+	users := []*user.User{
+		user.NewUser(alice, "alice@email.ru", "Alice", "hash", role.USER, user.DefaultUserSettings(), time.Now(), time.Now()),
+		user.NewUser(bob, "bob@email.ru", "Bob", "hash", role.USER, user.DefaultUserSettings(), time.Now(), time.Now()),
+	}
+
+	tt := []struct {
+		name           string
+		token          string
+		body           string
+		serviceRequest []uuid.UUID
+		serviceError   error
+		want           string
+		statusCode     int
+	}{
+		{
+			name:           "Found and missing users",
+			token:          "service",
+			body:           `{"uuids":["` + alice.String() + `","` + unknown.String() + `","` + bob.String() + `"]}`,
+			serviceRequest: []uuid.UUID{alice, unknown, bob},
+			want:           `{"users":[{"uuid":"` + alice.String() + `","name":"Alice","profile_picture_url":""},{"uuid":"` + bob.String() + `","name":"Bob","profile_picture_url":""}],"missing_uuids":["` + unknown.String() + `"]}`,
+			statusCode:     http.StatusOK,
+		},
+		{
+			name:           "Too many uuids",
+			token:          "service",
+			body:           `{"uuids":["` + alice.String() + `"]}`,
+			serviceRequest: []uuid.UUID{alice},
+			serviceError:   apperrors.NewIncorrectInputError("Too many uuids", "field-uuids-too-many"),
+			want:           `{"slug":"field-uuids-too-many"}`,
+			statusCode:     http.StatusBadRequest,
+		},
+		{
+			name:       "Uuids are required",
+			token:      "service",
+			body:       `{}`,
+			want:       `{"slug":"field-uuids-required"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "Malformed uuid",
+			token:      "service",
+			body:       `{"uuids":["nope"]}`,
+			want:       `{"slug":"invalid-input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "User tokens are rejected",
+			token:      "access",
+			body:       `{"uuids":["` + alice.String() + `"]}`,
+			want:       `{"slug":"service-token-required"}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "Service token without the scope",
+			token:      "settings",
+			body:       `{"uuids":["` + alice.String() + `"]}`,
+			want:       `{"slug":"insufficient-scope"}`,
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/internal/users:batchGet", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer "+tc.token)
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "service").Return(user.Token{Value: "service", ClientId: "svc_transactions", Scopes: []scope.Scope{scope.PROFILE_READ}}, nil)
+			authMock.On("ValidateToken", mock.Anything, "settings").Return(user.Token{Value: "settings", ClientId: "svc_transactions", Scopes: []scope.Scope{scope.SETTINGS_WRITE}}, nil)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: alice, Scopes: scope.Full()}, nil)
+			authMock.On("GetUsers", mock.Anything, tc.serviceRequest).Return(users, tc.serviceError)
+
+			server := NewHttpServer(mocks.NewAppMock(nil).SetAuthService(authMock))
+
+			server.batchGetUsers(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_batchGetUsersRoute(t *testing.T) {
+	router := chi.NewRouter()
+	NewHttpServer(mocks.NewAppMock(nil)).registerRoutes(router)
+
+	if !router.Match(chi.NewRouteContext(), http.MethodPost, "/api/v1/internal/users:batchGet") {
+		t.Error("Want the batch lookup routed")
+	}
+}
@@ -0,0 +1,54 @@
+package ports
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+type HashingMetricsResponse struct {
+	Workers       int     `json:"workers"`
+	QueueSize     int     `json:"queue_size"`
+	Running       int     `json:"running"`
+	Queued        int     `json:"queued"`
+	Started       uint64  `json:"started"`
+	Rejected      uint64  `json:"rejected"`
+	Canceled      uint64  `json:"canceled"`
+	WaitAverageMs float64 `json:"wait_average_ms"`
+	WaitMaxMs     float64 `json:"wait_max_ms"`
+}
+
+type MetricsResponse struct {
+	Hashing *HashingMetricsResponse `json:"hashing,omitempty"`
+}
+
+func (e *MetricsResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, http.StatusOK)
+	return nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (h *HttpServer) metrics(w http.ResponseWriter, r *http.Request) {
+	response := &MetricsResponse{}
+
+	if pool := h.app.GetPasswordPool(); pool != nil {
+		stats := pool.Stats()
+		response.Hashing = &HashingMetricsResponse{
+			Workers:       stats.Workers,
+			QueueSize:     stats.QueueSize,
+			Running:       stats.Running,
+			Queued:        stats.Queued,
+			Started:       stats.Started,
+			Rejected:      stats.Rejected,
+			Canceled:      stats.Canceled,
+			WaitAverageMs: milliseconds(stats.WaitAverage()),
+			WaitMaxMs:     milliseconds(stats.WaitMax),
+		}
+	}
+
+	render.Render(w, r, response)
+}
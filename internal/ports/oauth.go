@@ -0,0 +1,224 @@
+package ports
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	auth "github.com/ibgl/microservice-users/internal/app/user"
+)
+
+const grantTypeClientCredentials = "client_credentials"
+
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+func (e *OAuthTokenResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+// OAuthErrorResponse follows RFC 6749 section 5.2 so that stock OAuth client libraries understand it
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	httpStatus       int
+}
+
+func (e OAuthErrorResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, e.httpStatus)
+	return nil
+}
+
+type CreateClientRequest struct {
+	Name   string   `json:"name" validate:"required,gte=1,lte=200"`
+	Scopes []string `json:"scopes" validate:"required,gte=1"`
+}
+
+type ClientResponse struct {
+	ClientId     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+type ClientListResponse struct {
+	Clients []ClientResponse `json:"clients"`
+}
+
+func (e *ClientResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 201)
+	return nil
+}
+
+func (e *ClientListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+func newClientResponse(c *auth.Client) ClientResponse {
+	return ClientResponse{
+		ClientId:  c.ClientId,
+		Name:      c.Name,
+		Scopes:    scope.ToStrings(c.Scopes),
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+func (h *HttpServer) oauthToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		h.oauthError("invalid_request", err.Error(), http.StatusBadRequest, w, r)
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != grantTypeClientCredentials {
+		h.oauthError("unsupported_grant_type", "Only client_credentials grant is supported", http.StatusBadRequest, w, r)
+		return
+	}
+
+	clientId, clientSecret, basic := r.BasicAuth()
+	if !basic {
+		clientId = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+
+	if clientId == "" || clientSecret == "" {
+		h.oauthError("invalid_client", "Client credentials are required", http.StatusUnauthorized, w, r)
+		return
+	}
+
+	tokens, err := h.app.GetAuthService().ClientCredentials(r.Context(), &auth.ClientCredentialsRequest{
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Scopes:       strings.Fields(r.PostForm.Get("scope")),
+	})
+	if err != nil {
+		appError, ok := err.(apperrors.AppError)
+		if !ok {
+			h.oauthError("server_error", "", http.StatusInternalServerError, w, r)
+			return
+		}
+
+		switch appError.Slug() {
+		case "invalid-client":
+			if basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			h.oauthError("invalid_client", appError.Error(), http.StatusUnauthorized, w, r)
+		case "invalid-scope":
+			h.oauthError("invalid_scope", appError.Error(), http.StatusBadRequest, w, r)
+		default:
+			h.oauthError("server_error", "", http.StatusInternalServerError, w, r)
+		}
+		return
+	}
+
+	render.Render(w, r, &OAuthTokenResponse{
+		AccessToken: tokens.Access.Value,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(tokens.ExpiresIn.Seconds()),
+		Scope:       scope.Join(tokens.Scopes),
+	})
+}
+
+func (h *HttpServer) oauthError(code, description string, status int, w http.ResponseWriter, r *http.Request) {
+	h.app.GetLogger().Debug(map[string]string{
+		"error-type": "OAuth Request Error",
+		"error":      code,
+	})
+
+	if err := render.Render(w, r, OAuthErrorResponse{code, description, status}); err != nil {
+		panic(err)
+	}
+}
+
+func (h *HttpServer) listClients(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	clients, err := h.app.GetAuthService().ListClients(r.Context(), access.UserId)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := &ClientListResponse{Clients: make([]ClientResponse, 0, len(clients))}
+	for _, c := range clients {
+		response.Clients = append(response.Clients, newClientResponse(c))
+	}
+
+	render.Render(w, r, response)
+}
+
+func (h *HttpServer) createClient(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request CreateClientRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	created, err := h.app.GetAuthService().CreateClient(r.Context(), &auth.CreateClientRequest{
+		ActorUUID: access.UserId,
+		Name:      request.Name,
+		Scopes:    request.Scopes,
+	})
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := newClientResponse(created.Client)
+	response.ClientSecret = created.Secret
+
+	render.Render(w, r, &response)
+}
+
+func (h *HttpServer) deleteClient(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	err := h.app.GetAuthService().DeleteClient(r.Context(), access.UserId, chi.URLParam(r, "clientId"))
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
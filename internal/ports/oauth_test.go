@@ -0,0 +1,95 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_oauthToken(t *testing.T) {
+	tt := []struct {
+		name            string
+		body            string
+		basicAuth       bool
+		serviceRequest  *user.ClientCredentialsRequest
+		serviceResponse *user.ClientTokenResponse
+		serviceError    error
+		want            string
+		statusCode      int
+	}{
+		{
+			name:      "Client authenticates with basic auth",
+			body:      "grant_type=client_credentials&scope=profile:read",
+			basicAuth: true,
+			serviceRequest: &user.ClientCredentialsRequest{
+				ClientId:     "svc_worker",
+				ClientSecret: "secret",
+				Scopes:       []string{"profile:read"},
+			},
+			serviceResponse: &user.ClientTokenResponse{
+				Access:    user.Token{Value: "access", ClientId: "svc_worker"},
+				ExpiresIn: 10 * time.Minute,
+				Scopes:    []scope.Scope{scope.PROFILE_READ},
+			},
+			want:       `{"access_token":"access","token_type":"Bearer","expires_in":600,"scope":"profile:read"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name: "Client authenticates with form parameters",
+			body: "grant_type=client_credentials&client_id=svc_worker&client_secret=wrong",
+			serviceRequest: &user.ClientCredentialsRequest{
+				ClientId:     "svc_worker",
+				ClientSecret: "wrong",
+				Scopes:       []string{},
+			},
+			serviceResponse: &user.ClientTokenResponse{},
+			serviceError:    apperrors.NewAuthorizationError("Invalid client secret", "invalid-client"),
+			want:            `{"error":"invalid_client","error_description":"Invalid client secret"}`,
+			statusCode:      http.StatusUnauthorized,
+		},
+		{
+			name:       "Other grants are not supported",
+			body:       "grant_type=password&username=a&password=b",
+			want:       `{"error":"unsupported_grant_type","error_description":"Only client_credentials grant is supported"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/oauth/token", strings.NewReader(tc.body))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.basicAuth {
+				request.SetBasicAuth("svc_worker", "secret")
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			if tc.serviceRequest != nil {
+				authMock.On("ClientCredentials", mock.Anything, tc.serviceRequest).Return(tc.serviceResponse, tc.serviceError)
+			}
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			handler := server.oauthToken
+			handler(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package ports
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	auth "github.com/ibgl/microservice-users/internal/app/user"
+)
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+	// Refresh is the refresh token of the session kept signed in, read from the cookie in cookie mode
+	Refresh string `json:"refresh"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+func (h *HttpServer) registerPasswordRoutes(r chi.Router) {
+	r.Route("/password", func(r chi.Router) {
+		r.With(h.rateLimit("password")).Put("/", h.changePassword)
+		r.With(h.rateLimit("password-forgot")).Post("/forgot", h.forgotPassword)
+		r.With(h.rateLimit("password-reset")).Post("/reset", h.resetPassword)
+	})
+}
+
+func (h *HttpServer) changePassword(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.SETTINGS_WRITE, w, r)
+	if !ok {
+		return
+	}
+
+	// the password is changed by its owner only, not by an admin acting as the user or a script
+	if access.IsImpersonated() || access.IsPersonal() {
+		h.Forbidden("password-change-forbidden", apperrors.NewForbiddenError("Password change requires a sign in session", "password-change-forbidden"), w, r)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request ChangePasswordRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	err = h.app.GetAuthService().ChangePassword(r.Context(), &auth.ChangePasswordRequest{
+		UserUUID:        access.UserId,
+		CurrentPassword: request.CurrentPassword,
+		NewPassword:     request.NewPassword,
+		RefreshToken:    h.refreshToken(request.Refresh, r),
+	})
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HttpServer) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request ForgotPasswordRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	err = h.app.GetAuthService().RequestPasswordReset(r.Context(), request.Email)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *HttpServer) resetPassword(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request ResetPasswordRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	err = h.app.GetAuthService().ResetPassword(r.Context(), &auth.ResetPasswordRequest{
+		Token:    request.Token,
+		Password: request.Password,
+	})
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_changePassword(t *testing.T) {
+	userUUID := uuid.New()
+	tt := []struct {
+		name         string
+		access       user.Token
+		serviceError error
+		body         string
+		refresh      string
+		want         string
+		statusCode   int
+	}{
+		{
+			name:       "Password is changed",
+			access:     user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()},
+			body:       `{"current_password":"old","new_password":"Xk9#mQ2$vL"}`,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "Session of the refresh token is kept",
+			access:     user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()},
+			body:       `{"current_password":"old","new_password":"Xk9#mQ2$vL","refresh":"refresh"}`,
+			refresh:    "refresh",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:         "New password breaks the policy",
+			access:       user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()},
+			serviceError: apperrors.NewIncorrectInputError("Password is too weak", "field-password-too-weak"),
+			body:         `{"current_password":"old","new_password":"Password1"}`,
+			want:         `{"slug":"field-password-too-weak"}`,
+			statusCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "Impersonated session is refused",
+			access:     user.Token{Value: "impersonated", UserId: userUUID, ActorId: uuid.New(), Scopes: scope.Impersonation()},
+			body:       `{"current_password":"old","new_password":"Xk9#mQ2$vL"}`,
+			want:       `{"slug":"password-change-forbidden"}`,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "Current password is required",
+			access:     user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()},
+			body:       `{"new_password":"Xk9#mQ2$vL"}`,
+			want:       `{"slug":"field-current-password-required"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/api/v1/password", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer "+tc.access.Value)
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, tc.access.Value).Return(tc.access, nil)
+			authMock.On("ChangePassword", mock.Anything, mock.MatchedBy(func(r *user.ChangePasswordRequest) bool {
+				return r.UserUUID == userUUID && r.RefreshToken == tc.refresh
+			})).Return(tc.serviceError)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			handler := server.changePassword
+			handler(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
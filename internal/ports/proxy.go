@@ -0,0 +1,78 @@
+package ports
+
+import (
+	"net"
+	"strings"
+
+	"github.com/ibgl/microservice-users/internal/app"
+	"github.com/sirupsen/logrus"
+)
+
+// parseTrustedProxies reads the comma separated addresses and CIDR ranges, invalid entries are logged and skipped
+func parseTrustedProxies(value string, logger *logrus.Logger) []*net.IPNet {
+	var proxies []*net.IPNet
+	for _, item := range app.SplitList(value) {
+		cidr := item
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Warnf("Invalid trusted proxy %q skipped", item)
+			continue
+		}
+
+		proxies = append(proxies, network)
+	}
+
+	return proxies
+}
+
+func isTrustedProxy(address string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardedClientIP is the peer address unless the peer is a trusted proxy, any caller could send the forwarding
+// headers. Forwarded addresses are read from the right and the proxies skipped, the entries left of the first
+// client are not checked.
+func forwardedClientIP(peerIP string, header func(name string) string, trustedProxies []*net.IPNet) string {
+	if !isTrustedProxy(peerIP, trustedProxies) {
+		return peerIP
+	}
+
+	if forwarded := header("True-Client-IP"); forwarded != "" {
+		return forwarded
+	}
+
+	if forwarded := header("X-Real-IP"); forwarded != "" {
+		return forwarded
+	}
+
+	if forwarded := header("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && !isTrustedProxy(hop, trustedProxies) {
+				return hop
+			}
+		}
+	}
+
+	return peerIP
+}
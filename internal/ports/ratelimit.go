@@ -0,0 +1,70 @@
+package ports
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
+)
+
+// rateLimit applies the route policy, requests are keyed by the client IP (realIP must run before it)
+// or by the token owner for user keyed policies, anonymous requests fall back to the IP
+func (h *HttpServer) rateLimit(route string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter := h.app.GetRateLimiter()
+			if limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			policy, ok := limiter.Policy(route)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := limiter.Allow(r.Context(), route, h.rateLimitSubject(policy, r))
+			if err != nil {
+				// an unavailable store must not take the service down
+				h.app.GetLogger().Errorf("Rate limit store error %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+
+			if !result.Allowed {
+				h.TooManyRequests(
+					"rate-limit-exceeded",
+					result.RetryAfter,
+					apperrors.NewTooManyRequestsError("Rate limit exceeded for "+route, "rate-limit-exceeded", result.RetryAfter),
+					w, r,
+				)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *HttpServer) rateLimitSubject(policy ratelimit.Policy, r *http.Request) string {
+	if policy.KeyBy == ratelimit.USER && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		access, err := h.app.GetAuthService().ValidateToken(r.Context(), strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err == nil {
+			if access.IsService() {
+				return "client:" + access.ClientId
+			}
+
+			return "user:" + access.UserId.String()
+		}
+	}
+
+	return "ip:" + clientIP(r)
+}
@@ -0,0 +1,85 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ibgl/microservice-users/internal/adapters"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/ratelimit"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_rateLimit(t *testing.T) {
+	userUUID := uuid.New()
+	tt := []struct {
+		name       string
+		route      string
+		remoteAddr string
+		token      string
+		want       string
+		statusCode int
+		remaining  string
+		retryAfter string
+	}{
+		{name: "First request of an IP", route: "signUp", remoteAddr: "10.0.0.1:1000", want: `{"status":"ok"}`, statusCode: http.StatusOK, remaining: "1"},
+		{name: "Second request of an IP", route: "signUp", remoteAddr: "10.0.0.1:1001", want: `{"status":"ok"}`, statusCode: http.StatusOK, remaining: "0"},
+		{name: "Exhausted IP is refused", route: "signUp", remoteAddr: "10.0.0.1:1002", want: `{"slug":"rate-limit-exceeded"}`, statusCode: http.StatusTooManyRequests, remaining: "0", retryAfter: "30"},
+		{name: "Other IP has an own bucket", route: "signUp", remoteAddr: "10.0.0.2:1000", want: `{"status":"ok"}`, statusCode: http.StatusOK, remaining: "1"},
+		{name: "User is keyed by the token", route: "tokens", remoteAddr: "10.0.0.3:1000", token: "access", want: `{"status":"ok"}`, statusCode: http.StatusOK, remaining: "1"},
+		{name: "User keeps the bucket on another IP", route: "tokens", remoteAddr: "10.0.0.4:1000", token: "access", want: `{"status":"ok"}`, statusCode: http.StatusOK, remaining: "0"},
+		{name: "Anonymous request falls back to the IP", route: "tokens", remoteAddr: "10.0.0.4:1000", want: `{"status":"ok"}`, statusCode: http.StatusOK, remaining: "1"},
+		{name: "Route without a policy is not limited", route: "me", remoteAddr: "10.0.0.1:1003", want: `{"status":"ok"}`, statusCode: http.StatusOK},
+	}
+
+	authMock := new(mocks.AuthServiceMock)
+	authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: userUUID, Scopes: scope.Full()}, nil)
+
+	app := mocks.NewAppMock(nil).SetAuthService(authMock).SetRateLimiter(ratelimit.NewLimiter(
+		adapters.NewRateLimitMemoryStore(),
+		map[string]ratelimit.Policy{
+			"signUp": {Limit: 2, Period: time.Minute, KeyBy: ratelimit.IP},
+			"tokens": {Limit: 2, Period: time.Minute, KeyBy: ratelimit.USER},
+		},
+	))
+	server := NewHttpServer(app)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/"+tc.route, strings.NewReader(""))
+			request.RemoteAddr = tc.remoteAddr
+			if tc.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			server.rateLimit(tc.route)(ok).ServeHTTP(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if responseRecorder.Header().Get("X-RateLimit-Remaining") != tc.remaining {
+				t.Errorf("Want X-RateLimit-Remaining '%s', got '%s'", tc.remaining, responseRecorder.Header().Get("X-RateLimit-Remaining"))
+			}
+
+			if responseRecorder.Header().Get("Retry-After") != tc.retryAfter {
+				t.Errorf("Want Retry-After '%s', got '%s'", tc.retryAfter, responseRecorder.Header().Get("Retry-After"))
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package ports
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/ibgl/microservice-users/internal/app"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	auth "github.com/ibgl/microservice-users/internal/app/user"
+)
+
+const (
+	RefreshCookieName = "refresh_token"
+	CSRFCookieName    = "csrf_token"
+	CSRFHeaderName    = "X-CSRF-Token"
+)
+
+func (h *HttpServer) cookieMode() bool {
+	return h.app.GetConfig().SessionMode == app.SessionModeCookie
+}
+
+func (h *HttpServer) cookieSameSite() http.SameSite {
+	switch strings.ToLower(h.app.GetConfig().SessionCookieSameSite) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+
+	return http.SameSiteStrictMode
+}
+
+// respondTokens answers with the token pair, in cookie mode the refresh token only travels in an HttpOnly cookie
+// scoped to the refresh path and a readable CSRF cookie is issued for the double submit check
+func (h *HttpServer) respondTokens(tokens *auth.LoginResponse, w http.ResponseWriter, r *http.Request) {
+	if !h.cookieMode() {
+		render.Render(w, r, &TokenPairResponse{
+			Access:  tokens.Access.Value,
+			Refresh: tokens.Refresh.Value,
+		})
+		return
+	}
+
+	csrf := make([]byte, 32)
+	if _, err := rand.Read(csrf); err != nil {
+		h.InternalError("csrf-token-error", err, w, r)
+		return
+	}
+
+	maxAge := h.app.GetConfig().JwtRefreshTTL
+	http.SetCookie(w, h.sessionCookie(RefreshCookieName, tokens.Refresh.Value, maxAge))
+	http.SetCookie(w, h.sessionCookie(CSRFCookieName, base64.RawURLEncoding.EncodeToString(csrf), maxAge))
+
+	render.Render(w, r, &TokenPairResponse{
+		Access: tokens.Access.Value,
+	})
+}
+
+// clearSessionCookies expires both session cookies
+func (h *HttpServer) clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, h.sessionCookie(RefreshCookieName, "", -1))
+	http.SetCookie(w, h.sessionCookie(CSRFCookieName, "", -1))
+}
+
+func (h *HttpServer) sessionCookie(name, value string, maxAge int) *http.Cookie {
+	config := h.app.GetConfig()
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   config.SessionCookieDomain,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   !config.SessionCookieInsecure,
+		SameSite: h.cookieSameSite(),
+	}
+
+	// the CSRF cookie has to be readable by the client to be sent back in the header
+	if name == RefreshCookieName {
+		cookie.Path = config.SessionCookiePath
+		cookie.HttpOnly = true
+	}
+
+	return cookie
+}
+
+// refreshToken takes the refresh token from the body or, in cookie mode, from the cookie
+func (h *HttpServer) refreshToken(fromBody string, r *http.Request) string {
+	if fromBody != "" || !h.cookieMode() {
+		return fromBody
+	}
+
+	cookie, err := r.Cookie(RefreshCookieName)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// csrf requires the X-CSRF-Token header to match the CSRF cookie on state changing requests carrying
+// the refresh cookie, a cross site page can make the browser send the cookies but can not read them
+func (h *HttpServer) csrf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if _, err := r.Cookie(RefreshCookieName); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(CSRFCookieName)
+		header := r.Header.Get(CSRFHeaderName)
+		if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			h.Forbidden("csrf-token-invalid", apperrors.NewForbiddenError("CSRF token does not match", "csrf-token-invalid"), w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,159 @@
+package ports
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ibgl/microservice-users/internal/app"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_refresh(t *testing.T) {
+	tokens := &user.LoginResponse{
+		Access:  user.Token{Value: "access"},
+		Refresh: user.Token{Value: "new-refresh"},
+	}
+
+	tt := []struct {
+		name          string
+		sessionMode   string
+		body          string
+		refreshCookie string
+		csrfCookie    string
+		csrfHeader    string
+		serviceError  error
+		want          string
+		statusCode    int
+		setCookies    []string
+	}{
+		{
+			name:       "Token mode takes the body",
+			body:       `{"refresh":"refresh"}`,
+			want:       `{"access":"access","refresh":"new-refresh"}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Token mode without a token",
+			body:       `{}`,
+			want:       `{"slug":"invalid-token"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:          "Cookie mode takes the cookie",
+			sessionMode:   app.SessionModeCookie,
+			refreshCookie: "refresh",
+			csrfCookie:    "csrf",
+			csrfHeader:    "csrf",
+			want:          `{"access":"access"}`,
+			statusCode:    http.StatusOK,
+			setCookies:    []string{"refresh_token=new-refresh; Path=/users/api/v1/refresh; Max-Age=10; HttpOnly; Secure; SameSite=Strict", "csrf_token="},
+		},
+		{
+			name:          "Cookie without the CSRF header",
+			sessionMode:   app.SessionModeCookie,
+			refreshCookie: "refresh",
+			csrfCookie:    "csrf",
+			want:          `{"slug":"csrf-token-invalid"}`,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "Cookie with a wrong CSRF header",
+			sessionMode:   app.SessionModeCookie,
+			refreshCookie: "refresh",
+			csrfCookie:    "csrf",
+			csrfHeader:    "other",
+			want:          `{"slug":"csrf-token-invalid"}`,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "Revoked cookie is cleared",
+			sessionMode:   app.SessionModeCookie,
+			refreshCookie: "refresh",
+			csrfCookie:    "csrf",
+			csrfHeader:    "csrf",
+			serviceError:  apperrors.NewAuthorizationError("Refresh not found", "invalid-token"),
+			want:          `{"slug":"invalid-token"}`,
+			statusCode:    http.StatusUnauthorized,
+			setCookies:    []string{"refresh_token=; Path=/users/api/v1/refresh; Max-Age=0; HttpOnly; Secure; SameSite=Strict", "csrf_token=; Path=/; Max-Age=0; Secure; SameSite=Strict"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/refresh", strings.NewReader(tc.body))
+			if tc.refreshCookie != "" {
+				request.AddCookie(&http.Cookie{Name: RefreshCookieName, Value: tc.refreshCookie})
+			}
+			if tc.csrfCookie != "" {
+				request.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: tc.csrfCookie})
+			}
+			if tc.csrfHeader != "" {
+				request.Header.Set(CSRFHeaderName, tc.csrfHeader)
+			}
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("Refresh", mock.Anything, "refresh").Return(tokens, tc.serviceError)
+
+			app := mocks.NewAppMock(&app.Config{
+				JwtRefreshTTL:         10,
+				SessionMode:           tc.sessionMode,
+				SessionCookiePath:     "/users/api/v1/refresh",
+				SessionCookieSameSite: "strict",
+			}).SetAuthService(authMock)
+			server := NewHttpServer(app)
+
+			server.csrf(http.HandlerFunc(server.refresh)).ServeHTTP(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+
+			setCookies := responseRecorder.Header().Values("Set-Cookie")
+			if len(setCookies) != len(tc.setCookies) {
+				t.Fatalf("Want cookies %v, got %v", tc.setCookies, setCookies)
+			}
+
+			for i, want := range tc.setCookies {
+				if !strings.HasPrefix(setCookies[i], want) {
+					t.Errorf("Want cookie '%s', got '%s'", want, setCookies[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_signOut(t *testing.T) {
+	request := httptest.NewRequest(http.MethodDelete, "/api/v1/refresh", strings.NewReader(""))
+	request.AddCookie(&http.Cookie{Name: RefreshCookieName, Value: "refresh"})
+	request.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: "csrf"})
+	request.Header.Set(CSRFHeaderName, "csrf")
+	responseRecorder := httptest.NewRecorder()
+
+	authMock := new(mocks.AuthServiceMock)
+	authMock.On("SignOut", mock.Anything, "refresh").Return(nil)
+
+	app := mocks.NewAppMock(&app.Config{SessionMode: app.SessionModeCookie, SessionCookiePath: "/users/api/v1/refresh"}).SetAuthService(authMock)
+	server := NewHttpServer(app)
+
+	server.csrf(http.HandlerFunc(server.signOut)).ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusNoContent {
+		t.Errorf("Want status '%d', got '%d'", http.StatusNoContent, responseRecorder.Code)
+	}
+
+	authMock.AssertExpectations(t)
+
+	if cookies := responseRecorder.Result().Cookies(); len(cookies) != 2 || cookies[0].MaxAge >= 0 || cookies[1].MaxAge >= 0 {
+		t.Errorf("Want both session cookies cleared, got %v", cookies)
+	}
+}
@@ -0,0 +1,322 @@
+package ports
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/webhook"
+)
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,lte=2000"`
+	Events []string `json:"events"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,lte=2000"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active" validate:"required"`
+}
+
+type WebhookResponse struct {
+	UUID       uuid.UUID `json:"uuid"`
+	URL        string    `json:"url"`
+	Events     []string  `json:"events"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Secret     string    `json:"secret,omitempty"`
+	httpStatus int
+}
+
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+type WebhookDeliveryResponse struct {
+	UUID          uuid.UUID  `json:"uuid"`
+	EventUUID     uuid.UUID  `json:"event_uuid"`
+	EventType     string     `json:"event_type"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	ResponseCode  int        `json:"response_code"`
+	ResponseBody  string     `json:"response_body"`
+	LastError     string     `json:"last_error"`
+	RedeliveryOf  *uuid.UUID `json:"redelivery_of"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
+
+func (e *WebhookResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, e.httpStatus)
+	return nil
+}
+
+func (e *WebhookListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+func (e *WebhookDeliveryResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 202)
+	return nil
+}
+
+func (e *WebhookDeliveryListResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	render.Status(r, 200)
+	return nil
+}
+
+func newWebhookResponse(s *webhook.Subscription, status int) WebhookResponse {
+	events := make([]string, 0, len(s.Events))
+	for _, t := range s.Events {
+		events = append(events, t.String())
+	}
+
+	return WebhookResponse{
+		UUID:       s.UUID,
+		URL:        s.URL,
+		Events:     events,
+		Active:     s.Active,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+		httpStatus: status,
+	}
+}
+
+func newWebhookDeliveryResponse(d *webhook.Delivery) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		UUID:          d.UUID,
+		EventUUID:     d.EventUUID,
+		EventType:     d.EventType.String(),
+		Status:        d.Status.String(),
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		ResponseCode:  d.ResponseCode,
+		ResponseBody:  d.ResponseBody,
+		LastError:     d.LastError,
+		CreatedAt:     d.CreatedAt,
+		DeliveredAt:   d.DeliveredAt,
+	}
+
+	if d.RedeliveryOf != uuid.Nil {
+		response.RedeliveryOf = &d.RedeliveryOf
+	}
+
+	return response
+}
+
+func (h *HttpServer) registerWebhookRoutes(r chi.Router) {
+	r.Get("/webhooks", h.listWebhooks)
+	r.Post("/webhooks", h.createWebhook)
+	r.Put("/webhooks/{uuid}", h.updateWebhook)
+	r.Delete("/webhooks/{uuid}", h.deleteWebhook)
+	r.Get("/webhooks/{uuid}/deliveries", h.listWebhookDeliveries)
+	r.Post("/webhooks/{uuid}/deliveries/{deliveryUuid}/redeliver", h.redeliverWebhook)
+}
+
+func (h *HttpServer) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := h.app.GetWebhookService().ListSubscriptions(r.Context(), access.UserId)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := &WebhookListResponse{Webhooks: make([]WebhookResponse, 0, len(subscriptions))}
+	for _, s := range subscriptions {
+		response.Webhooks = append(response.Webhooks, newWebhookResponse(s, http.StatusOK))
+	}
+
+	render.Render(w, r, response)
+}
+
+func (h *HttpServer) createWebhook(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request CreateWebhookRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	s, err := h.app.GetWebhookService().CreateSubscription(r.Context(), &webhook.CreateSubscriptionRequest{
+		ActorUUID: access.UserId,
+		URL:       request.URL,
+		Events:    request.Events,
+	})
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := newWebhookResponse(s, http.StatusCreated)
+	response.Secret = s.Secret
+
+	render.Render(w, r, &response)
+}
+
+func (h *HttpServer) updateWebhook(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	subscriptionUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("webhook-not-found", err, w, r)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body) // response body is []byte
+	if err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	var request UpdateWebhookRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		h.BadRequest("invalid-input", err, w, r)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		h.RespondValidationError(err.(validator.ValidationErrors), w, r)
+		return
+	}
+
+	s, err := h.app.GetWebhookService().UpdateSubscription(r.Context(), &webhook.UpdateSubscriptionRequest{
+		ActorUUID:        access.UserId,
+		SubscriptionUUID: subscriptionUUID,
+		URL:              request.URL,
+		Events:           request.Events,
+		Active:           *request.Active,
+	})
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := newWebhookResponse(s, http.StatusOK)
+	render.Render(w, r, &response)
+}
+
+func (h *HttpServer) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	subscriptionUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("webhook-not-found", err, w, r)
+		return
+	}
+
+	err = h.app.GetWebhookService().DeleteSubscription(r.Context(), access.UserId, subscriptionUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HttpServer) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	subscriptionUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("webhook-not-found", err, w, r)
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > webhook.MaxDeliveriesLimit {
+			h.RespondWithAppError(apperrors.NewIncorrectInputError("Invalid limit", "field-limit-invalid"), w, r)
+			return
+		}
+	}
+
+	deliveries, err := h.app.GetWebhookService().ListDeliveries(r.Context(), access.UserId, subscriptionUUID, limit)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := &WebhookDeliveryListResponse{Deliveries: make([]WebhookDeliveryResponse, 0, len(deliveries))}
+	for _, d := range deliveries {
+		response.Deliveries = append(response.Deliveries, newWebhookDeliveryResponse(d))
+	}
+
+	render.Render(w, r, response)
+}
+
+func (h *HttpServer) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	access, ok := h.authorize(scope.ADMIN, w, r)
+	if !ok {
+		return
+	}
+
+	subscriptionUUID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		h.NotFound("webhook-not-found", err, w, r)
+		return
+	}
+
+	deliveryUUID, err := uuid.Parse(chi.URLParam(r, "deliveryUuid"))
+	if err != nil {
+		h.NotFound("webhook-delivery-not-found", err, w, r)
+		return
+	}
+
+	d, err := h.app.GetWebhookService().Redeliver(r.Context(), access.UserId, subscriptionUUID, deliveryUUID)
+	if err != nil {
+		h.RespondWithAppError(err, w, r)
+		return
+	}
+
+	response := newWebhookDeliveryResponse(d)
+	render.Render(w, r, &response)
+}
@@ -0,0 +1,204 @@
+package ports
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	apperrors "github.com/ibgl/microservice-users/internal/app/errors"
+	"github.com/ibgl/microservice-users/internal/app/event"
+	"github.com/ibgl/microservice-users/internal/app/mocks"
+	"github.com/ibgl/microservice-users/internal/app/scope"
+	"github.com/ibgl/microservice-users/internal/app/user"
+	"github.com/ibgl/microservice-users/internal/app/webhook"
+	"github.com/stretchr/testify/mock"
+)
+
+func withURLParams(r *http.Request, params map[string]string) *http.Request {
+	routeContext := chi.NewRouteContext()
+	for key, value := range params {
+		routeContext.URLParams.Add(key, value)
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+}
+
+func Test_createWebhook(t *testing.T) {
+	adminUUID := uuid.New()
+	subscriptionUUID := uuid.New()
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name            string
+		body            string
+		serviceRequest  *webhook.CreateSubscriptionRequest
+		serviceResponse *webhook.Subscription
+		serviceError    error
+		want            string
+		statusCode      int
+	}{
+		{
+			name:           "Secret is returned on creation",
+			body:           `{"url":"https://partner.example.com/hooks","events":["user.created"]}`,
+			serviceRequest: &webhook.CreateSubscriptionRequest{ActorUUID: adminUUID, URL: "https://partner.example.com/hooks", Events: []string{"user.created"}},
+			serviceResponse: &webhook.Subscription{
+				UUID:      subscriptionUUID,
+				URL:       "https://partner.example.com/hooks",
+				Secret:    "whsec_secret",
+				Events:    []event.Type{event.USER_CREATED},
+				Active:    true,
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+			},
+			want:       `{"uuid":"` + subscriptionUUID.String() + `","url":"https://partner.example.com/hooks","events":["user.created"],"active":true,"created_at":"2026-10-18T12:00:00Z","updated_at":"2026-10-18T12:00:00Z","secret":"whsec_secret"}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:            "Unknown event",
+			body:            `{"url":"https://partner.example.com/hooks","events":["user.deleted"]}`,
+			serviceRequest:  &webhook.CreateSubscriptionRequest{ActorUUID: adminUUID, URL: "https://partner.example.com/hooks", Events: []string{"user.deleted"}},
+			serviceResponse: &webhook.Subscription{},
+			serviceError:    apperrors.NewIncorrectInputError("Invalid event type", "field-events-invalid"),
+			want:            `{"slug":"field-events-invalid"}`,
+			statusCode:      http.StatusBadRequest,
+		},
+		{
+			name:       "Url is required",
+			body:       `{"events":[]}`,
+			want:       `{"slug":"invalid-input"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks", strings.NewReader(tc.body))
+			request.Header.Set("Authorization", "Bearer access")
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: adminUUID, Scopes: scope.Full()}, nil)
+
+			webhookMock := new(mocks.WebhookServiceMock)
+			webhookMock.On("CreateSubscription", mock.Anything, tc.serviceRequest).Return(tc.serviceResponse, tc.serviceError)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock).SetWebhookService(webhookMock)
+			server := NewHttpServer(app)
+
+			server.createWebhook(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_listWebhookDeliveries(t *testing.T) {
+	adminUUID := uuid.New()
+	subscriptionUUID := uuid.New()
+	deliveryUUID, eventUUID, originalUUID := uuid.New(), uuid.New(), uuid.New()
+	createdAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	deliveries := []*webhook.Delivery{{
+		UUID:          deliveryUUID,
+		EventUUID:     eventUUID,
+		EventType:     event.USER_UPDATED,
+		Status:        webhook.PENDING,
+		Attempts:      2,
+		NextAttemptAt: createdAt,
+		ResponseCode:  503,
+		ResponseBody:  "unavailable",
+		LastError:     "unexpected status 503",
+		RedeliveryOf:  originalUUID,
+		CreatedAt:     createdAt,
+	}}
+
+	tt := []struct {
+		name       string
+		query      string
+		limit      int
+		want       string
+		statusCode int
+	}{
+		{
+			name:       "Delivery log",
+			query:      "?limit=10",
+			limit:      10,
+			want:       `{"deliveries":[{"uuid":"` + deliveryUUID.String() + `","event_uuid":"` + eventUUID.String() + `","event_type":"user.updated","status":"pending","attempts":2,"next_attempt_at":"2026-10-18T12:00:00Z","response_code":503,"response_body":"unavailable","last_error":"unexpected status 503","redelivery_of":"` + originalUUID.String() + `","created_at":"2026-10-18T12:00:00Z","delivered_at":null}]}`,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Invalid limit",
+			query:      "?limit=1000",
+			want:       `{"slug":"field-limit-invalid"}`,
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhooks/"+subscriptionUUID.String()+"/deliveries"+tc.query, nil)
+			request.Header.Set("Authorization", "Bearer access")
+			request = withURLParams(request, map[string]string{"uuid": subscriptionUUID.String()})
+			responseRecorder := httptest.NewRecorder()
+
+			authMock := new(mocks.AuthServiceMock)
+			authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: adminUUID, Scopes: scope.Full()}, nil)
+
+			webhookMock := new(mocks.WebhookServiceMock)
+			webhookMock.On("ListDeliveries", mock.Anything, adminUUID, subscriptionUUID, tc.limit).Return(deliveries, nil)
+
+			app := mocks.NewAppMock(nil).SetAuthService(authMock).SetWebhookService(webhookMock)
+			server := NewHttpServer(app)
+
+			server.listWebhookDeliveries(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+
+			if strings.TrimSpace(responseRecorder.Body.String()) != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, responseRecorder.Body)
+			}
+		})
+	}
+}
+
+func Test_redeliverWebhook(t *testing.T) {
+	adminUUID := uuid.New()
+	subscriptionUUID, deliveryUUID := uuid.New(), uuid.New()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks/x/deliveries/y/redeliver", nil)
+	request.Header.Set("Authorization", "Bearer access")
+	request = withURLParams(request, map[string]string{"uuid": subscriptionUUID.String(), "deliveryUuid": deliveryUUID.String()})
+	responseRecorder := httptest.NewRecorder()
+
+	authMock := new(mocks.AuthServiceMock)
+	authMock.On("ValidateToken", mock.Anything, "access").Return(user.Token{Value: "access", UserId: adminUUID, Scopes: scope.Full()}, nil)
+
+	webhookMock := new(mocks.WebhookServiceMock)
+	webhookMock.On("Redeliver", mock.Anything, adminUUID, subscriptionUUID, deliveryUUID).
+		Return(&webhook.Delivery{UUID: uuid.New(), EventType: event.USER_CREATED, Status: webhook.PENDING, RedeliveryOf: deliveryUUID}, nil)
+
+	app := mocks.NewAppMock(nil).SetAuthService(authMock).SetWebhookService(webhookMock)
+	server := NewHttpServer(app)
+
+	server.redeliverWebhook(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusAccepted {
+		t.Errorf("Want status '%d', got '%d'", http.StatusAccepted, responseRecorder.Code)
+	}
+
+	if !strings.Contains(responseRecorder.Body.String(), `"redelivery_of":"`+deliveryUUID.String()+`"`) {
+		t.Errorf("Want the redelivery pointing to the delivery, got '%s'", responseRecorder.Body)
+	}
+}